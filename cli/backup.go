@@ -0,0 +1,70 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  backup.go - Subcomandaments per a fer i restaurar còpies de
+ *              seguretat de la biblioteca.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func cmdBackup( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  with_files:= fs.Bool ( "files", false, "Inclou el contingut dels"+
+    " fitxers en un tar" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  return m.Backup ( fs.Arg ( 0 ), *with_files, func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+
+} // end cmdBackup
+
+
+func cmdRestore( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  return m.Restore ( fs.Arg ( 0 ), func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+
+} // end cmdRestore
@@ -0,0 +1,317 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  dats.go - Subcomandaments per a gestionar els DATs i verificar els
+ *            fitxers.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/model/dat"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _DatJSON struct {
+  ID          int64  `json:"id"`
+  Name        string `json:"name"`
+  Description string `json:"description"`
+  Version     string `json:"version"`
+  Author      string `json:"author"`
+  Format      string `json:"format"`
+  Header      string `json:"header,omitempty"`
+  Platform    string `json:"platform,omitempty"`
+  FileName    string `json:"file_name"`
+  ImportDate  int64  `json:"import_date"`
+  NumGames    int64  `json:"num_games"`
+  NumRoms     int64  `json:"num_roms"`
+}
+
+
+type _VerifyJSON struct {
+  ID      int64  `json:"id"`
+  Name    string `json:"name"`
+  EntryID int64  `json:"entry_id"`
+  Status  string `json:"status"`
+  DatName string `json:"dat_name"`
+}
+
+
+func newDatJSON( m *model.Model, d *model.Dat ) _DatJSON {
+
+  ret:= _DatJSON{
+    ID          : d.GetID (),
+    Name        : d.GetName (),
+    Description : d.GetDescription (),
+    Version     : d.GetVersion (),
+    Author      : d.GetAuthor (),
+    Format      : d.GetFormat (),
+    Header      : d.GetHeader (),
+    FileName    : d.GetFileName (),
+    ImportDate  : d.GetImportDate (),
+    NumGames    : d.GetNumGames (),
+    NumRoms     : d.GetNumRoms (),
+  }
+  ret.Platform= getDatPlatformName ( m, d )
+
+  return ret
+
+} // end newDatJSON
+
+
+// Nom curt de la plataforma associada o cadena buida.
+func getDatPlatformName( m *model.Model, d *model.Dat ) string {
+
+  if d.GetPlatformID () == -1 { return "" }
+
+  return m.GetPlatforms ().GetPlatform ( d.GetPlatformID () ).GetShortName ()
+
+} // end getDatPlatformName
+
+
+func parseDatID( m *model.Model, text string ) (int64,error) {
+
+  id,err:= strconv.ParseInt ( text, 10, 64 )
+  if err != nil {
+    return -1,fmt.Errorf ( "Identificador de DAT no vàlid: '%s'", text )
+  }
+  if m.GetDats ().Get ( id ) == nil {
+    return -1,fmt.Errorf ( "El DAT indicat (%d) no existeix", id )
+  }
+
+  return id,nil
+
+} // end parseDatID
+
+
+func cmdDatExport( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  name:= fs.String ( "name", "imgteka", "Nom del DAT" )
+  description:= fs.String ( "description", "", "Descripció del DAT"+
+    " (per defecte el nom)" )
+  version:= fs.String ( "version", time.Now ().Format ( "20060102" ),
+    "Versió del DAT" )
+  author:= fs.String ( "author", "", "Autor del DAT" )
+  out:= fs.String ( "o", "", "Fitxer d'eixida (per defecte l'eixida"+
+    " estàndard)" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if *description == "" {
+    *description= *name
+  }
+
+  // Filtra
+  if fs.NArg () > 0 {
+    m.FilterEntries ( strings.Join ( fs.Args (), " " ) )
+  }
+
+  // Eixida
+  w:= os.Stdout
+  if *out != "" {
+    f,err:= os.Create ( *out )
+    if err != nil {
+      return fmt.Errorf ( "No s'ha pogut crear el fitxer '%s': %s", *out, err )
+    }
+    defer f.Close ()
+    w= f
+  }
+
+  // Escriu
+  header:= dat.Dat{
+    Name        : *name,
+    Description : *description,
+    Version     : *version,
+    Author      : *author,
+  }
+
+  return m.GetDats ().Export ( w, &header, m.GetEntries (),
+    func() view.ProgressBar {
+      return newTextProgressBar ()
+    })
+
+} // end cmdDatExport
+
+
+func cmdDatImport( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () == 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  for _,file_name:= range fs.Args () {
+    if err:= m.GetDats ().Import ( file_name ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end cmdDatImport
+
+
+func cmdDatList( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  dats:= m.GetDats ()
+  ids:= dats.GetIDs ()
+
+  // JSON
+  if *as_json {
+    v:= make([]_DatJSON,0,len(ids))
+    for _,id:= range ids {
+      v= append(v,newDatJSON ( m, dats.Get ( id ) ))
+    }
+    return printJSON ( v )
+  }
+
+  // Text
+  for _,id:= range ids {
+    d:= dats.Get ( id )
+    fmt.Printf ( "%d\t%s\t%s\t%s\t%d\t%d\n", id, getDatPlatformName ( m, d ),
+      d.GetName (), d.GetVersion (), d.GetNumGames (), d.GetNumRoms () )
+  }
+
+  return nil
+
+} // end cmdDatList
+
+
+func cmdDatPlatform( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 1 && fs.NArg () != 2 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Prepara
+  id,err:= parseDatID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  plat_id:= -1
+  if fs.NArg () == 2 {
+    plat_id,err= findPlatform ( m, fs.Arg ( 1 ) )
+    if err != nil { return err }
+  }
+
+  return m.GetDats ().SetPlatform ( id, plat_id )
+
+} // end cmdDatPlatform
+
+
+func cmdDatReport( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_csv:= fs.Bool ( "csv", false, "Eixida en format CSV" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Crea informe
+  plat_id,err:= findPlatform ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  report,err:= model.NewDatReport ( m.GetDats (), m.GetFiles (),
+    m.GetPlatforms (), plat_id )
+  if err != nil { return err }
+
+  // Mostra
+  if *as_csv {
+    return report.WriteCSV ( os.Stdout )
+  }
+
+  return report.WriteText ( os.Stdout )
+
+} // end cmdDatReport
+
+
+func cmdDatRm( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  id,err:= parseDatID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+
+  return m.GetDats ().Remove ( id )
+
+} // end cmdDatRm
+
+
+func cmdVerify( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Verifica
+  files:= m.GetFiles ()
+  ids:= files.GetIDs ()
+  v:= make([]_VerifyJSON,0,len(ids))
+  for _,id:= range ids {
+    f:= files.Get ( id )
+    status,dat_name:= f.GetDatStatus ()
+    v= append(v,_VerifyJSON{
+      ID      : id,
+      Name    : f.GetName (),
+      EntryID : f.GetEntryID (),
+      Status  : status,
+      DatName : dat_name,
+    })
+  }
+
+  // Mostra
+  if *as_json {
+    return printJSON ( v )
+  }
+  for _,r:= range v {
+    fmt.Printf ( "%d\t%s\t%s\t%s\n", r.ID, r.Status, r.Name, r.DatName )
+  }
+
+  return nil
+
+} // end cmdVerify
@@ -0,0 +1,385 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  entries.go - Subcomandaments per a consultar i modificar les
+ *               entrades.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "strings"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/model/file_type"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _KeyValueJSON struct {
+  Key   string `json:"key"`
+  Value string `json:"value"`
+}
+
+
+type _FileJSON struct {
+  ID        int64           `json:"id"`
+  Name      string          `json:"name"`
+  EntryID   int64           `json:"entry_id"`
+  TypeID    int             `json:"type_id"`
+  Type      string          `json:"type"`
+  Size      int64           `json:"size"`
+  MD5       string          `json:"md5"`
+  SHA1      string          `json:"sha1"`
+  CRC32     string          `json:"crc32,omitempty"`
+  SHA256    string          `json:"sha256,omitempty"`
+  RealMD5   string          `json:"real_md5,omitempty"`
+  RealSHA1  string          `json:"real_sha1,omitempty"`
+  RealCRC32 string          `json:"real_crc32,omitempty"`
+  DatStatus string          `json:"dat_status"`
+  DatName   string          `json:"dat_name,omitempty"`
+  Metadata  []_KeyValueJSON `json:"metadata,omitempty"`
+}
+
+
+type _EntryJSON struct {
+  ID       int64       `json:"id"`
+  Name     string      `json:"name"`
+  Platform string      `json:"platform"`
+  Labels   []string    `json:"labels"`
+  Files    []_FileJSON `json:"files"`
+}
+
+
+func newFileJSON( f *model.File, with_md bool ) _FileJSON {
+
+  ret:= _FileJSON{
+    ID      : f.GetID (),
+    Name    : f.GetName (),
+    EntryID : f.GetEntryID (),
+    TypeID  : f.GetTypeID (),
+    Size    : f.GetSize (),
+    MD5     : f.GetMD5 (),
+    SHA1    : f.GetSHA1 (),
+    CRC32   : f.GetCRC32 (),
+    SHA256  : f.GetSHA256 (),
+  }
+  if f.GetRealSHA1 () != f.GetSHA1 () {
+    ret.RealMD5= f.GetRealMD5 ()
+    ret.RealSHA1= f.GetRealSHA1 ()
+    ret.RealCRC32= f.GetRealCRC32 ()
+  }
+  if ft,err:= file_type.Get ( f.GetTypeID () ); err == nil {
+    ret.Type= ft.GetShortName ()
+  }
+  ret.DatStatus,ret.DatName= f.GetDatStatus ()
+  if with_md {
+    for _,kv:= range f.GetMetadata () {
+      ret.Metadata= append(ret.Metadata,_KeyValueJSON{
+        Key   : kv.GetKey (),
+        Value : kv.GetValue (),
+      })
+    }
+  }
+
+  return ret
+
+} // end newFileJSON
+
+
+func newEntryJSON( m *model.Model, e *model.Entry ) _EntryJSON {
+
+  ret:= _EntryJSON{
+    ID       : e.GetID (),
+    Name     : e.GetName (),
+    Platform : m.GetPlatforms ().GetPlatform (
+      e.GetPlatformID () ).GetShortName (),
+    Labels   : []string{},
+    Files    : []_FileJSON{},
+  }
+  for _,id:= range e.GetLabelIDs () {
+    ret.Labels= append(ret.Labels,m.GetLabels ().Get ( id ).GetName ())
+  }
+  for _,id:= range e.GetFileIDs () {
+    ret.Files= append(ret.Files,newFileJSON ( m.GetFiles ().Get ( id ), false ))
+  }
+
+  return ret
+
+} // end newEntryJSON
+
+
+// Mostra les entrades actuals (d'acord amb el filtre aplicat).
+func printEntries( m *model.Model, as_json bool ) error {
+
+  entries:= m.GetEntries ()
+  ids:= entries.GetIDs ()
+
+  // JSON
+  if as_json {
+    v:= make([]_EntryJSON,0,len(ids))
+    for _,id:= range ids {
+      v= append(v,newEntryJSON ( m, entries.Get ( id ) ))
+    }
+    return printJSON ( v )
+  }
+
+  // Text
+  for _,id:= range ids {
+    e:= entries.Get ( id )
+    plat:= m.GetPlatforms ().GetPlatform ( e.GetPlatformID () )
+    fmt.Printf ( "%d\t%s\t%s\n", id, plat.GetShortName (), e.GetName () )
+  }
+
+  return nil
+
+} // end printEntries
+
+
+func printEntryInfo( m *model.Model, e *model.Entry ) {
+
+  plat:= m.GetPlatforms ().GetPlatform ( e.GetPlatformID () )
+  labels:= make([]string,0)
+  for _,id:= range e.GetLabelIDs () {
+    labels= append(labels,m.GetLabels ().Get ( id ).GetName ())
+  }
+  fmt.Printf ( "Entrada:    %d\n", e.GetID () )
+  fmt.Printf ( "Nom:        %s\n", e.GetName () )
+  fmt.Printf ( "Plataforma: %s - %s\n", plat.GetShortName (), plat.GetName () )
+  fmt.Printf ( "Etiquetes:  %s\n", strings.Join ( labels, ", " ) )
+  fmt.Printf ( "Fitxers:\n" )
+  for _,id:= range e.GetFileIDs () {
+    f:= m.GetFiles ().Get ( id )
+    fmt.Printf ( "  %d\t%s\t%s\n", id,
+      m.GetFileTypeName ( f.GetTypeID () ), f.GetName () )
+  }
+
+} // end printEntryInfo
+
+
+func printFileInfo( m *model.Model, f *model.File ) {
+
+  e:= m.GetEntries ().Get ( f.GetEntryID () )
+  fmt.Printf ( "Fitxer:  %d\n", f.GetID () )
+  fmt.Printf ( "Nom:     %s\n", f.GetName () )
+  if e != nil {
+    fmt.Printf ( "Entrada: %d (%s)\n", e.GetID (), e.GetName () )
+  }
+  fmt.Printf ( "Tipus:   %s\n", m.GetFileTypeName ( f.GetTypeID () ) )
+  fmt.Printf ( "Ruta:    %s\n", f.GetStoredPath () )
+  status,dat_name:= f.GetDatStatus ()
+  fmt.Printf ( "DAT:     %s", status )
+  if dat_name != "" {
+    fmt.Printf ( " (%s)", dat_name )
+  }
+  fmt.Printf ( "\n" )
+  fmt.Printf ( "Metadades:\n" )
+  for _,kv:= range f.GetMetadata () {
+    fmt.Printf ( "  %s: %s\n", kv.GetKey (), kv.GetValue () )
+  }
+
+} // end printFileInfo
+
+
+func cmdList( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  return printEntries ( m, *as_json )
+
+} // end cmdList
+
+
+func cmdSearch( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () == 0 {
+    fs.Usage ()
+    return errors.New ( "No s'ha especificat cap consulta" )
+  }
+
+  // Filtra
+  m.FilterEntries ( strings.Join ( fs.Args (), " " ) )
+
+  return printEntries ( m, *as_json )
+
+} // end cmdSearch
+
+
+func cmdAddEntry( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  plat_name:= fs.String ( "p", "", "Nom curt de la plataforma" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () == 0 || *plat_name == "" {
+    fs.Usage ()
+    return errors.New ( "Cal indicar la plataforma i el nom" )
+  }
+
+  // Crea
+  plat_id,err:= findPlatform ( m, *plat_name )
+  if err != nil { return err }
+  name:= strings.Join ( fs.Args (), " " )
+  if err:= m.AddEntry ( name, plat_id ); err != nil {
+    return err
+  }
+
+  // Mostra l'identificador de la nova entrada.
+  name= strings.TrimSpace ( name )
+  entries:= m.GetEntries ()
+  for _,id:= range entries.GetIDs () {
+    e:= entries.Get ( id )
+    if e.GetPlatformID () == plat_id && e.GetName () == name {
+      fmt.Println ( id )
+      break
+    }
+  }
+
+  return nil
+
+} // end cmdAddEntry
+
+
+func cmdRm( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  recursive:= fs.Bool ( "r", false, "Elimina també els fitxers de l'entrada" )
+  file_id:= fs.String ( "file", "", "Identificador del fitxer a eliminar" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+
+  // Elimina fitxer
+  if *file_id != "" {
+    if fs.NArg () != 0 {
+      fs.Usage ()
+      return errors.New ( "Nombre d'arguments incorrecte" )
+    }
+    f,err:= parseFileID ( m, *file_id )
+    if err != nil { return err }
+    e:= m.GetEntries ().Get ( f.GetEntryID () )
+    if e == nil {
+      return fmt.Errorf ( "La entrada indicada (%d) no existeix",
+        f.GetEntryID () )
+    }
+    return e.RemoveFile ( f.GetID () )
+  }
+
+  // Elimina entrada
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  if *recursive {
+    ids:= append([]int64{},e.GetFileIDs ()...)
+    for _,id:= range ids {
+      if err:= e.RemoveFile ( id ); err != nil {
+        return err
+      }
+    }
+  }
+
+  return m.RemoveEntry ( e.GetID () )
+
+} // end cmdRm
+
+
+func cmdLabel( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () < 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+
+  // Aplica canvis
+  for _,arg:= range fs.Args ()[1:] {
+    if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+      return fmt.Errorf ( "Argument no vàlid (cal '+' o '-'): '%s'", arg )
+    }
+    label_id,err:= findLabel ( m, arg[1:] )
+    if err != nil { return err }
+    if arg[0] == '+' {
+      err= e.AddLabel ( label_id )
+    } else {
+      err= e.RemoveLabel ( label_id )
+    }
+    if err != nil { return err }
+  }
+
+  // Mostra etiquetes
+  for _,id:= range e.GetLabelIDs () {
+    fmt.Println ( m.GetLabels ().Get ( id ).GetName () )
+  }
+
+  return nil
+
+} // end cmdLabel
+
+
+func cmdInfo( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  file_id:= fs.String ( "file", "", "Identificador del fitxer" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+
+  // Fitxer
+  if *file_id != "" {
+    if fs.NArg () != 0 {
+      fs.Usage ()
+      return errors.New ( "Nombre d'arguments incorrecte" )
+    }
+    f,err:= parseFileID ( m, *file_id )
+    if err != nil { return err }
+    if *as_json {
+      return printJSON ( newFileJSON ( f, true ) )
+    }
+    printFileInfo ( m, f )
+    return nil
+  }
+
+  // Entrada
+  if fs.NArg () != 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  if *as_json {
+    return printJSON ( newEntryJSON ( m, e ) )
+  }
+  printEntryInfo ( m, e )
+
+  return nil
+
+} // end cmdInfo
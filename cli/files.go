@@ -0,0 +1,629 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  files.go - Subcomandaments per a afegir fitxers i mantindre'ls.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Accepta l'identificador numèric (p.e. 0x203) o el nom curt (p.e. NES).
+func parseFileType( text string ) (int,error) {
+
+  // Identificador
+  if id,err:= strconv.ParseInt ( text, 0, 32 ); err == nil {
+    if _,err:= file_type.Get ( int(id) ); err != nil {
+      return -1,fmt.Errorf ( "Tipus de fitxer desconegut: '%s'", text )
+    }
+    return int(id),nil
+  }
+
+  // Nom curt
+  text= strings.ToUpper ( strings.TrimSpace ( text ) )
+  cands:= make([]int,0,1)
+  for _,id:= range file_type.GetIDs () {
+    ft,err:= file_type.Get ( id )
+    if err != nil { return -1,err }
+    if ft.GetShortName () == text {
+      cands= append(cands,id)
+    }
+  }
+  switch len(cands) {
+  case 0:
+    return -1,fmt.Errorf ( "Tipus de fitxer desconegut: '%s'", text )
+  case 1:
+    return cands[0],nil
+  default:
+    msg:= ""
+    for _,id:= range cands {
+      ft,_:= file_type.Get ( id )
+      msg+= fmt.Sprintf ( "\n  0x%03x  %s", id, ft.GetName () )
+    }
+    return -1,fmt.Errorf ( "El tipus '%s' és ambigu, indique"+
+      " l'identificador:%s", text, msg )
+  }
+
+} // end parseFileType
+
+
+func printFileTypes( fs *flag.FlagSet ) {
+
+  w:= fs.Output ()
+  fmt.Fprintf ( w, "Tipus disponibles:\n" )
+  for _,id:= range file_type.GetIDs () {
+    ft,err:= file_type.Get ( id )
+    if err != nil { continue }
+    fmt.Fprintf ( w, "  0x%03x  %-7s %s\n", id, ft.GetShortName (),
+      ft.GetName () )
+  }
+
+} // end printFileTypes
+
+
+func cmdAddFile( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  type_text:= fs.String ( "t", "", "Tipus del fitxer (nom curt o"+
+    " identificador). Per defecte es detecta automàticament" )
+  name:= fs.String ( "name", "", "Nom amb el que es registra el fitxer"+
+    " (per defecte el nom del fitxer)" )
+  usage:= fs.Usage
+  fs.Usage= func() {
+    usage ()
+    printFileTypes ( fs )
+  }
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 2 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Prepara
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  path,err:= filepath.Abs ( fs.Arg ( 1 ) )
+  if err != nil { return err }
+  var ftype int
+  if *type_text == "" {
+    cands,err:= m.DetectFileType ( path )
+    if err != nil { return err }
+    ftype= cands[0]
+    fmt.Fprintf ( os.Stderr, "Tipus detectat: %s\n",
+      m.GetFileTypeName ( ftype ) )
+  } else if ftype,err= parseFileType ( *type_text ); err != nil {
+    return err
+  }
+  if *name == "" {
+    *name= filepath.Base ( path )
+  }
+
+  // Afegeix
+  dups,err:= e.AddFile ( path, *name, ftype, func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+  if err != nil { return err }
+  for _,d:= range dups {
+    fmt.Fprintf ( os.Stderr, "Avís: és un duplicat del %s\n", d )
+  }
+
+  // Mostra l'identificador del nou fitxer.
+  printNewFileID ( m, e, *name )
+
+  return nil
+
+} // end cmdAddFile
+
+
+// Mostra l'identificador del fitxer de l'entrada amb el nom indicat.
+func printNewFileID( m *model.Model, e *model.Entry, name string ) {
+
+  name= strings.TrimSpace ( name )
+  for _,id:= range e.GetFileIDs () {
+    if m.GetFiles ().Get ( id ).GetName () == name {
+      fmt.Println ( id )
+      break
+    }
+  }
+  
+} // end printNewFileID
+
+
+func cmdFloppyNew( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  usage:= fs.Usage
+  fs.Usage= func() {
+    usage ()
+    fmt.Fprintf ( fs.Output (), "Formats: %s\n",
+      strings.Join ( m.GetFloppyFormats (), ", " ) )
+  }
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 3 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Crea
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  name:= fs.Arg ( 1 )
+  if err:= e.CreateFloppy ( name, fs.Arg ( 2 ), func() view.ProgressBar {
+    return newTextProgressBar ()
+  }); err != nil {
+    return err
+  }
+  printNewFileID ( m, e, name )
+
+  return nil
+  
+} // end cmdFloppyNew
+
+
+func cmdFloppyPut( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  dir:= fs.String ( "dir", "", "Directori del disquet on s'escriuen els"+
+    " fitxers (per defecte l'arrel)" )
+  name:= fs.String ( "name", "", "Nom del nou disquet (per defecte el"+
+    " nom de l'original acabat en _mod)" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () < 2 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Prepara
+  f,err:= parseFileID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  e:= m.GetEntries ().Get ( f.GetEntryID () )
+  if e == nil {
+    return fmt.Errorf ( "No s'ha trobat l'entrada del fitxer %d", f.GetID () )
+  }
+  paths:= make([]string,0,fs.NArg ()-1)
+  for _,arg:= range fs.Args ()[1:] {
+    path,err:= filepath.Abs ( arg )
+    if err != nil { return err }
+    paths= append(paths,path)
+  }
+  if *name == "" {
+    ext:= filepath.Ext ( f.GetName () )
+    *name= strings.TrimSuffix ( f.GetName (), ext )+"_mod"+ext
+  }
+
+  // Escriu
+  if err:= e.AddFilesToFloppy ( f.GetID (), *name, paths, *dir,
+    func() view.ProgressBar {
+      return newTextProgressBar ()
+    }); err != nil {
+    return err
+  }
+  printNewFileID ( m, e, *name )
+
+  return nil
+  
+} // end cmdFloppyPut
+
+
+func cmdBackfillHashes(
+  m    *model.Model,
+  fs   *flag.FlagSet,
+  args []string,
+) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  n,err:= m.GetFiles ().Backfill ( nil, func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+  if err != nil { return err }
+  fmt.Println ( n )
+  n,err= m.GetFiles ().RefreshMetadata ( nil, func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+  if err != nil { return err }
+  if n > 0 {
+    fmt.Fprintf ( os.Stderr, "S'han actualitzat les metadades de %d"+
+      " fitxers\n", n )
+  }
+
+  return nil
+
+} // end cmdBackfillHashes
+
+
+type _IntegrityJSON struct {
+  ID        int64  `json:"id"`
+  Name      string `json:"name"`
+  Status    string `json:"status"`
+  LastCheck int64  `json:"last_check"`
+  Error     string `json:"error,omitempty"`
+}
+
+
+func cmdCheckIntegrity(
+  m    *model.Model,
+  fs   *flag.FlagSet,
+  args []string,
+) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  age:= fs.Int ( "age", 90, "Sols comprova els fitxers que fa més d'aquest"+
+    " nombre de dies que no es comproven" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 || *age < 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Comprova
+  report,err:= m.CheckIntegrity ( time.Duration(*age)*24*time.Hour,
+    func() view.ProgressBar {
+      return newTextProgressBar ()
+    })
+  if err != nil { return err }
+  v:= make([]_IntegrityJSON,0,report.GetNumFiles ())
+  for i:= 0; i < report.GetNumFiles (); i++ {
+    r:= report.GetResult ( i )
+    tmp:= _IntegrityJSON{
+      ID        : r.GetID (),
+      Name      : r.GetName (),
+      Status    : model.IntegrityStatusToText ( r.GetStatus () ),
+      LastCheck : r.GetLastCheck (),
+    }
+    if err:= r.GetError (); err != nil {
+      tmp.Error= err.Error ()
+    }
+    v= append(v,tmp)
+  }
+
+  // Mostra
+  if *as_json {
+    if err:= printJSON ( v ); err != nil { return err }
+  } else {
+    for _,r:= range v {
+      fmt.Printf ( "%d\t%s\t%s", r.ID, r.Status, r.Name )
+      if r.Error != "" {
+        fmt.Printf ( " (%s)", r.Error )
+      }
+      fmt.Printf ( "\n" )
+    }
+  }
+  if failed:= report.GetNumFiles ()-report.GetNumOK (); failed > 0 {
+    return fmt.Errorf ( "Hi ha %d fitxers modificats o corruptes", failed )
+  }
+
+  return nil
+
+} // end cmdCheckIntegrity
+
+
+type _DuplicateJSON struct {
+  ID      int64  `json:"id"`
+  Name    string `json:"name"`
+  EntryID int64  `json:"entry_id"`
+}
+
+
+type _DuplicateGroupJSON struct {
+  Hash  string           `json:"hash"`
+  Files []_DuplicateJSON `json:"files"`
+}
+
+
+func cmdDuplicates( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  rm_id:= fs.String ( "rm", "", "Elimina aquesta còpia del fitxer" )
+  keep_id:= fs.String ( "keep", "", "Conserva aquest fitxer i elimina la"+
+    " resta de còpies (les entrades buides es fusionen amb la seua)" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 || (*rm_id != "" && *keep_id != "") {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Accions
+  if *rm_id != "" {
+    f,err:= parseFileID ( m, *rm_id )
+    if err != nil { return err }
+    return m.RemoveDuplicate ( f.GetID () )
+  } else if *keep_id != "" {
+    f,err:= parseFileID ( m, *keep_id )
+    if err != nil { return err }
+    return m.MergeDuplicates ( f.GetID () )
+  }
+
+  // Informe
+  report,err:= model.NewDuplicatesReport ( m.GetFiles () )
+  if err != nil { return err }
+  v:= make([]_DuplicateGroupJSON,report.GetNumGroups ())
+  for g:= range v {
+    v[g].Hash= report.GetHash ( g )
+    v[g].Files= make([]_DuplicateJSON,report.GetNumFiles ( g ))
+    for i:= range v[g].Files {
+      v[g].Files[i]= _DuplicateJSON{
+        ID      : report.GetFileID ( g, i ),
+        Name    : report.GetFileName ( g, i ),
+        EntryID : report.GetEntryID ( g, i ),
+      }
+    }
+  }
+  if *as_json {
+    return printJSON ( v )
+  }
+  for g,group:= range v {
+    fmt.Println ( group.Hash )
+    for i,f:= range group.Files {
+      fmt.Printf ( "  %d\t%s\n", f.ID, report.GetFileText ( g, i ) )
+    }
+  }
+
+  return nil
+
+} // end cmdDuplicates
+
+
+func cmdImportMode( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () > 1 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Consulta
+  if fs.NArg () == 0 {
+    mode:= m.GetImportMode ()
+    fmt.Printf ( "%s\t%s\n", model.GetImportModeName ( mode ),
+      model.ImportModeToText ( mode ) )
+    return nil
+  }
+
+  // Canvia
+  mode,err:= model.ParseImportMode ( fs.Arg ( 0 ) )
+  if err != nil { return err }
+
+  return m.SetImportMode ( mode )
+
+} // end cmdImportMode
+
+
+type _CompressionJSON struct {
+  Type        string `json:"type"`
+  Compression string `json:"compression"`
+  Files       int    `json:"files"`
+  Compressed  int    `json:"compressed"`
+  Size        int64  `json:"size"`
+  StoredSize  int64  `json:"stored_size"`
+  Saved       int64  `json:"saved"`
+}
+
+
+func cmdAddArchive( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  list:= fs.Bool ( "list", false, "Mostra els fitxers que conté el fitxer"+
+    " ZIP o TAR sense afegir-los" )
+  keep:= fs.Bool ( "keep", false, "Afegeix també el fitxer original" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+
+  // Llista
+  if *list {
+    if fs.NArg () != 1 {
+      fs.Usage ()
+      return errors.New ( "Nombre d'arguments incorrecte" )
+    }
+    members,err:= m.GetArchiveMembers ( fs.Arg ( 0 ) )
+    if err != nil { return err }
+    for _,mem:= range members {
+      fmt.Printf ( "%d\t%s\n", mem.GetSize (), mem.GetName () )
+    }
+    return nil
+  }
+  if fs.NArg () < 2 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Prepara. Sense fitxers s'afegeixen tots.
+  e,err:= parseEntryID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  path,err:= filepath.Abs ( fs.Arg ( 1 ) )
+  if err != nil { return err }
+  members:= fs.Args ()[2:]
+  if len(members) == 0 {
+    all,err:= m.GetArchiveMembers ( path )
+    if err != nil { return err }
+    for _,mem:= range all {
+      members= append(members,mem.GetName ())
+    }
+  }
+
+  // Afegeix
+  n,err:= e.AddFilesFromArchive ( path, members, *keep,
+    func() view.ProgressBar {
+      return newTextProgressBar ()
+    })
+  fmt.Printf ( "S'han afegit %d fitxers\n", n )
+  
+  return err
+  
+} // end cmdAddArchive
+
+
+type _MemberJSON struct {
+  Name string `json:"name"`
+  Size int64  `json:"size"`
+  Date string `json:"date,omitempty"`
+}
+
+
+func cmdContents( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  dir:= fs.String ( "x", "", "Extrau els fitxers indicats (tots si no"+
+    " s'indica cap) en el directori" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () < 1 || (*dir == "" && fs.NArg () != 1) {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Llig contingut
+  f,err:= parseFileID ( m, fs.Arg ( 0 ) )
+  if err != nil { return err }
+  members,err:= f.GetMembers ()
+  if err != nil { return err }
+
+  // Extrau
+  if *dir != "" {
+    names:= fs.Args ()[1:]
+    if len(names) == 0 {
+      for _,mem:= range members {
+        names= append(names,mem.GetName ())
+      }
+    }
+    if err:= f.ExtractMembers ( names, *dir, func() view.ProgressBar {
+      return newTextProgressBar ()
+    }); err != nil {
+      return err
+    }
+    fmt.Printf ( "S'han extret %d fitxers\n", len(names) )
+    return nil
+  }
+
+  // Llista
+  if *as_json {
+    v:= make([]_MemberJSON,len(members))
+    for i,mem:= range members {
+      v[i]= _MemberJSON{Name:mem.GetName (),Size:mem.GetSize ()}
+      if !mem.GetDate ().IsZero () {
+        v[i].Date= mem.GetDate ().Format ( time.RFC3339 )
+      }
+    }
+    return printJSON ( v )
+  }
+  for _,mem:= range members {
+    date:= ""
+    if !mem.GetDate ().IsZero () {
+      date= mem.GetDate ().Format ( "2006-01-02 15:04" )
+    }
+    fmt.Printf ( "%10d\t%16s\t%s\n", mem.GetSize (), date, mem.GetName () )
+  }
+  
+  return nil
+  
+} // end cmdContents
+
+
+func cmdCompression( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  apply:= fs.Bool ( "apply", false, "Comprimeix o descomprimeix els"+
+    " fitxers que ja estan en la biblioteca segons la configuració" )
+  fs.Usage= func() {
+    fmt.Fprintf ( fs.Output (), "Compressions: none %s\n",
+      strings.Join ( model.GetCompressions (), " " ) )
+    fs.PrintDefaults ()
+    printFileTypes ( fs )
+  }
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if (fs.NArg () != 0 && fs.NArg () != 2) || (*apply && fs.NArg () != 0) {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Canvia la configuració d'un tipus
+  if fs.NArg () == 2 {
+    ftype,err:= parseFileType ( fs.Arg ( 0 ) )
+    if err != nil { return err }
+    compression:= fs.Arg ( 1 )
+    if compression == "none" { compression= "" }
+    return m.SetCompression ( ftype, compression )
+  }
+
+  // Aplica
+  if *apply {
+    n,err:= m.ApplyCompression ( func() view.ProgressBar {
+      return newTextProgressBar ()
+    })
+    if n > 0 {
+      fmt.Printf ( "S'ha canviat la compressió de %d fitxers\n", n )
+    }
+    if err != nil { return err }
+  }
+
+  // Informe
+  stats,err:= m.GetCompressionReport ()
+  if err != nil { return err }
+  v:= make([]_CompressionJSON,0,len(stats))
+  var total _CompressionJSON
+  for _,s:= range stats {
+    r:= _CompressionJSON{
+      Type        : m.GetFileTypeName ( s.GetTypeID () ),
+      Compression : s.GetCompression (),
+      Files       : s.GetNumFiles (),
+      Compressed  : s.GetNumCompressed (),
+      Size        : s.GetSize (),
+      StoredSize  : s.GetStoredSize (),
+      Saved       : s.GetSaved (),
+    }
+    if r.Compression == "" { r.Compression= "none" }
+    total.Files+= r.Files
+    total.Compressed+= r.Compressed
+    total.Size+= r.Size
+    total.StoredSize+= r.StoredSize
+    total.Saved+= r.Saved
+    v= append(v,r)
+  }
+  if *as_json {
+    return printJSON ( v )
+  }
+  for _,r:= range v {
+    fmt.Printf ( "%s\t%s\t%d/%d comprimits\t%d B -> %d B\t(%d B estalviats)\n",
+      r.Type, r.Compression, r.Compressed, r.Files, r.Size, r.StoredSize,
+      r.Saved )
+  }
+  fmt.Printf ( "Total: %d B -> %d B (%d B estalviats)\n", total.Size,
+    total.StoredSize, total.Saved )
+
+  return nil
+
+} // end cmdCompression
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  fsck.go - Subcomandaments per a comprovar i reparar el directori de
+ *            dades de la biblioteca i per a canviar l'organització dels
+ *            fitxers.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "os"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _FsckJSON struct {
+  Type        string `json:"type"`
+  Path        string `json:"path"`
+  Description string `json:"description,omitempty"`
+  Fixed       bool   `json:"fixed"`
+  Error       string `json:"error,omitempty"`
+}
+
+
+func cmdFsck( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  repair:= fs.Bool ( "repair", false, "Repara els problemes trobats" )
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Comprova
+  report,err:= m.Fsck ( *repair, func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+  if err != nil { return err }
+  v:= make([]_FsckJSON,0,report.GetNumProblems ())
+  pending:= 0
+  for i:= 0; i < report.GetNumProblems (); i++ {
+    p:= report.GetProblem ( i )
+    r:= _FsckJSON{
+      Type        : model.FsckProblemToText ( p.GetType () ),
+      Path        : p.GetPath (),
+      Description : p.GetDescription (),
+      Fixed       : p.IsFixed (),
+    }
+    if err:= p.GetError (); err != nil {
+      r.Error= err.Error ()
+    }
+    if !r.Fixed { pending++ }
+    v= append(v,r)
+  }
+
+  // Mostra
+  if *as_json {
+    if err:= printJSON ( v ); err != nil { return err }
+  } else {
+    for _,r:= range v {
+      status:= "Pendent"
+      if r.Fixed {
+        status= "Reparat"
+      } else if r.Error != "" {
+        status= "Error: "+r.Error
+      }
+      fmt.Printf ( "%s\t%s\t%s", status, r.Type, r.Path )
+      if r.Description != "" {
+        fmt.Printf ( " (%s)", r.Description )
+      }
+      fmt.Printf ( "\n" )
+    }
+  }
+  if dir:= report.GetQuarantineDir (); dir != "" {
+    fmt.Fprintf ( os.Stderr, "Fitxers moguts a quarantena: %s\n", dir )
+  }
+  if pending > 0 {
+    return fmt.Errorf ( "Hi ha %d problemes sense reparar", pending )
+  }
+
+  return nil
+
+} // end cmdFsck
+
+
+func cmdMigrateStore( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  n,ndups,err:= m.MigrateStore ( func() view.ProgressBar {
+    return newTextProgressBar ()
+  })
+  if n > 0 {
+    fmt.Printf ( "S'han migrat %d fitxers (%d amb contingut repetit)\n",
+      n, ndups )
+  }
+
+  return err
+
+} // end cmdMigrateStore
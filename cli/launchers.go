@@ -0,0 +1,137 @@
+/*
+ * Copyright 2023 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  launchers.go - Subcomandament per a gestionar els llançadors de
+ *                 cada tipus de fitxer.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+  "strings"
+
+  "github.com/adriagipas/imgteka/model"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _LauncherJSON struct {
+  Type    string `json:"type"`
+  Name    string `json:"name"`
+  Command string `json:"command"`
+}
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func cmdLaunchers( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  as_json:= fs.Bool ( "json", false, "Eixida en format JSON" )
+  plat_name:= fs.String ( "p", "", "Llançadors propis de la plataforma"+
+    " (nom curt)" )
+  entry_text:= fs.String ( "e", "", "Llançadors propis de l'entrada" )
+  clear:= fs.Bool ( "clear", false, "Elimina els llançadors del tipus" )
+  usage:= fs.Usage
+  fs.Usage= func() {
+    usage ()
+    printFileTypes ( fs )
+  }
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if (*plat_name != "" && *entry_text != "") ||
+    (*clear && fs.NArg () != 1) {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Àmbit
+  get:= m.GetFileTypeLaunchers
+  set:= m.SetFileTypeLaunchers
+  if *plat_name != "" {
+    plat_id,err:= findPlatform ( m, *plat_name )
+    if err != nil { return err }
+    get= func(tid int) []view.Launcher {
+      return m.GetPlatformLaunchers ( plat_id, tid )
+    }
+    set= func(tid int,ls []view.Launcher) error {
+      return m.SetPlatformLaunchers ( plat_id, tid, ls )
+    }
+  } else if *entry_text != "" {
+    e,err:= parseEntryID ( m, *entry_text )
+    if err != nil { return err }
+    get= func(tid int) []view.Launcher {
+      return m.GetEntryLaunchers ( e.GetID (), tid )
+    }
+    set= func(tid int,ls []view.Launcher) error {
+      return m.SetEntryLaunchers ( e.GetID (), tid, ls )
+    }
+  }
+
+  // Canvia els llançadors d'un tipus
+  tids:= m.GetFileTypeIDs ()
+  if fs.NArg () > 0 {
+    tid,err:= parseFileType ( fs.Arg ( 0 ) )
+    if err != nil { return err }
+    if *clear || fs.NArg () > 1 {
+      ls:= make([]view.Launcher,0,fs.NArg ()-1)
+      for _,arg:= range fs.Args ()[1:] {
+        name,command,ok:= strings.Cut ( arg, "=" )
+        if !ok {
+          return fmt.Errorf ( "S'esperava NOM=PLANTILLA: '%s'", arg )
+        }
+        ls= append(ls,view.Launcher{Name:name,Command:command})
+      }
+      return set ( tid, ls )
+    }
+    tids= []int{tid}
+  }
+
+  // Llista
+  v:= make([]_LauncherJSON,0)
+  for _,tid:= range tids {
+    for _,l:= range get ( tid ) {
+      v= append(v,_LauncherJSON{
+        Type    : m.GetFileTypeName ( tid ),
+        Name    : l.Name,
+        Command : l.Command,
+      })
+    }
+  }
+  if *as_json {
+    return printJSON ( v )
+  }
+  for _,l:= range v {
+    fmt.Printf ( "%s\t%s\t%s\n", l.Type, l.Name, l.Command )
+  }
+  
+  return nil
+  
+} // end cmdLaunchers
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  library.go - Subcomandament per a consultar els perfils de
+ *               biblioteca.
+ */
+
+package cli
+
+import (
+  "errors"
+  "flag"
+  "fmt"
+
+  "github.com/adriagipas/imgteka/model"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func cmdProfiles( m *model.Model, fs *flag.FlagSet, args []string ) error {
+
+  if err:= fs.Parse ( args ); err != nil { return err }
+  if fs.NArg () != 0 {
+    fs.Usage ()
+    return errors.New ( "Nombre d'arguments incorrecte" )
+  }
+
+  // Mostra. Marca el perfil obert.
+  profiles,err:= model.GetProfiles ()
+  if err != nil { return err }
+  current:= m.GetLibraryName ()
+  for _,name:= range profiles {
+    mark:= " "
+    if name == current { mark= "*" }
+    fmt.Printf ( "%s %s\n", mark, name )
+  }
+
+  return nil
+
+} // end cmdProfiles
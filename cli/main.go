@@ -0,0 +1,398 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  main.go - Interfície de línia de comandaments. Permet manipular la
+ *            biblioteca sense la interfície gràfica.
+ */
+
+package cli
+
+import (
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+
+  "github.com/adriagipas/imgteka/model"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _Command struct {
+  args string // Descripció dels arguments
+  help string
+  run  func(m *model.Model,fs *flag.FlagSet,args []string) error
+}
+
+
+// S'ompli en init per evitar cicles d'inicialització amb printUsage.
+var _CMDS map[string]*_Command
+
+
+func init() {
+
+  _CMDS= map[string]*_Command{
+    "list" : &_Command{
+      args : "[-json]",
+      help : "Llista totes les entrades",
+      run  : cmdList,
+    },
+    "search" : &_Command{
+      args : "[-json] CONSULTA",
+      help : "Llista les entrades que cumpleixen la consulta"+
+        " (p.e.: 'mario + p:NES + l:Verificat')",
+      run  : cmdSearch,
+    },
+    "add-entry" : &_Command{
+      args : "-p PLATAFORMA NOM",
+      help : "Crea una entrada nova",
+      run  : cmdAddEntry,
+    },
+    "add-file" : &_Command{
+      args : "[-t TIPUS] [-name NOM] ENTRADA FITXER",
+      help : "Afegeix un fitxer a una entrada",
+      run  : cmdAddFile,
+    },
+    "add-archive" : &_Command{
+      args : "[-keep] ENTRADA FITXER [MEMBRE...] | -list FITXER",
+      help : "Afegeix a una entrada els fitxers (tots si no s'indica"+
+        " cap) continguts en un fitxer ZIP o TAR, detectant el tipus de"+
+        " cadascun",
+      run  : cmdAddArchive,
+    },
+    "rm" : &_Command{
+      args : "[-r] ENTRADA | -file FITXER",
+      help : "Elimina una entrada (amb -r també els seus fitxers) o un fitxer",
+      run  : cmdRm,
+    },
+    "label" : &_Command{
+      args : "ENTRADA [+ETIQUETA|-ETIQUETA]...",
+      help : "Mostra, afegeix o elimina les etiquetes d'una entrada",
+      run  : cmdLabel,
+    },
+    "info" : &_Command{
+      args : "[-json] ENTRADA | -file FITXER",
+      help : "Mostra els detalls d'una entrada o d'un fitxer",
+      run  : cmdInfo,
+    },
+    "contents" : &_Command{
+      args : "[-json] FITXER | -x DIRECTORI FITXER [MEMBRE...]",
+      help : "Mostra els fitxers continguts en un fitxer ZIP, TAR o"+
+        " imatge de disc (ISO, FAT12) o els extrau (-x)",
+      run  : cmdContents,
+    },
+    "floppy-new" : &_Command{
+      args : "ENTRADA NOM FORMAT",
+      help : "Crea un disquet FAT12 buit (360K, 720K o 1.44M) i"+
+        " l'afegeix a una entrada",
+      run  : cmdFloppyNew,
+    },
+    "floppy-put" : &_Command{
+      args : "[-dir DIR] [-name NOM] FITXER LOCAL...",
+      help : "Escriu fitxers locals en una còpia d'un disquet FAT12, que"+
+        " s'afegeix com a fitxer nou de la mateixa entrada. L'original"+
+        " no es modifica",
+      run  : cmdFloppyPut,
+    },
+    "launchers" : &_Command{
+      args : "[-json] [-p PLATAFORMA|-e ENTRADA] [TIPUS [NOM=PLANTILLA...]]"+
+        " | [-p PLATAFORMA|-e ENTRADA] -clear TIPUS",
+      help : "Mostra o canvia els llançadors d'un tipus de fitxer, o els"+
+        " que el substitueixen en una plataforma o una entrada",
+      run  : cmdLaunchers,
+    },
+    "import-mode" : &_Command{
+      args : "[hardlink|reflink|copy|move]",
+      help : "Mostra o canvia com es desen els fitxers afegits: enllaç"+
+        " dur, reflink, còpia o moure. Si no es pot fer un enllaç dur"+
+        " o un reflink es copia",
+      run  : cmdImportMode,
+    },
+    "compression" : &_Command{
+      args : "[-json] [-apply] | TIPUS none|gzip",
+      help : "Mostra l'espai estalviat per la compressió de cada tipus de"+
+        " fitxer, canvia la compressió dels fitxers nous d'un tipus o"+
+        " l'aplica als que ja estan en la biblioteca (-apply)",
+      run  : cmdCompression,
+    },
+    "backfill-hashes" : &_Command{
+      args : "",
+      help : "Calcula el CRC32, el SHA-256 i els hashes sense capçalera"+
+        " dels fitxers registrats abans que es guardaren i actualitza"+
+        " les metadades antigues (p.e. el contingut dels disquets FAT12)",
+      run  : cmdBackfillHashes,
+    },
+    "check-integrity" : &_Command{
+      args : "[-json] [-age DIES]",
+      help : "Torna a calcular els hashes dels fitxers que no s'han"+
+        " comprovat en els últims dies (90 per defecte) per a detectar"+
+        " fitxers modificats o corruptes",
+      run  : cmdCheckIntegrity,
+    },
+    "backup" : &_Command{
+      args : "[-files] DIRECTORI",
+      help : "Fa una còpia de seguretat de la biblioteca (amb -files"+
+        " també del contingut dels fitxers)",
+      run  : cmdBackup,
+    },
+    "profiles" : &_Command{
+      args : "",
+      help : "Llista els perfils de biblioteca (el marcat amb * és l'obert)",
+      run  : cmdProfiles,
+    },
+    "restore" : &_Command{
+      args : "DIRECTORI",
+      help : "Comprova i restaura una còpia de seguretat",
+      run  : cmdRestore,
+    },
+    "duplicates" : &_Command{
+      args : "[-json] | -rm FITXER | -keep FITXER",
+      help : "Mostra els fitxers repetits, elimina una còpia o conserva"+
+        " un fitxer i elimina la resta de còpies",
+      run  : cmdDuplicates,
+    },
+    "fsck" : &_Command{
+      args : "[-json] [-repair]",
+      help : "Comprova que els fitxers de la biblioteca coincideixen amb"+
+        " la base de dades (amb -repair també els repara)",
+      run  : cmdFsck,
+    },
+    "migrate-store" : &_Command{
+      args : "",
+      help : "Passa a desar els fitxers pel seu SHA1 (files/ab/cdef...)."+
+        " Els fitxers idèntics es desen una sola vegada",
+      run  : cmdMigrateStore,
+    },
+    "dat-export" : &_Command{
+      args : "[-name NOM] [-version VERSIÓ] [-author AUTOR] [-o FITXER]"+
+        " [CONSULTA]",
+      help : "Genera un DAT en format Logiqx XML amb les entrades que"+
+        " cumpleixen la consulta (totes si no s'indica)",
+      run  : cmdDatExport,
+    },
+    "dat-import" : &_Command{
+      args : "FITXER...",
+      help : "Importa fitxers DAT de referència (Logiqx XML o"+
+        " ClrMamePro)",
+      run  : cmdDatImport,
+    },
+    "dat-list" : &_Command{
+      args : "[-json]",
+      help : "Llista els DATs importats",
+      run  : cmdDatList,
+    },
+    "dat-platform" : &_Command{
+      args : "DAT [PLATAFORMA]",
+      help : "Associa un DAT a una plataforma (sense plataforma el"+
+        " desassocia)",
+      run  : cmdDatPlatform,
+    },
+    "dat-report" : &_Command{
+      args : "[-csv] PLATAFORMA",
+      help : "Mostra els jocs dels DATs de la plataforma que tenim i"+
+        " els que falten",
+      run  : cmdDatReport,
+    },
+    "dat-rm" : &_Command{
+      args : "DAT",
+      help : "Elimina un DAT importat",
+      run  : cmdDatRm,
+    },
+    "verify" : &_Command{
+      args : "[-json]",
+      help : "Mostra l'estat de tots els fitxers respecte als DATs importats",
+      run  : cmdVerify,
+    },
+  }
+
+} // end init
+
+
+func printUsage( w io.Writer ) {
+
+  fmt.Fprintf ( w, "Ús: imgteka [-root DIRECTORI | -profile PERFIL]"+
+    " [SUBCOMANDAMENT [OPCIONS] [ARGUMENTS]]\n\n" )
+  fmt.Fprintf ( w, "Sense subcomandament s'obri la interfície gràfica.\n" )
+  fmt.Fprintf ( w, "Amb -root (o la variable IMGTEKA_ROOT) s'empra la"+
+    " biblioteca del directori indicat\ni amb -profile (o la variable"+
+    " IMGTEKA_PROFILE) la del perfil indicat.\n\n" )
+  fmt.Fprintf ( w, "Subcomandaments:\n" )
+  names:= make([]string,0,len(_CMDS))
+  for name:= range _CMDS {
+    names= append(names,name)
+  }
+  sort.Strings ( names )
+  for _,name:= range names {
+    cmd:= _CMDS[name]
+    fmt.Fprintf ( w, "  %s %s\n        %s\n", name, cmd.args, cmd.help )
+  }
+
+} // end printUsage
+
+
+// Escriu 'v' en format JSON en l'eixida estàndard.
+func printJSON( v any ) error {
+
+  enc:= json.NewEncoder ( os.Stdout )
+  enc.SetIndent ( "", "  " )
+
+  return enc.Encode ( v )
+
+} // end printJSON
+
+
+func parseEntryID( m *model.Model, text string ) (*model.Entry,error) {
+
+  id,err:= strconv.ParseInt ( text, 10, 64 )
+  if err != nil {
+    return nil,fmt.Errorf ( "Identificador d'entrada no vàlid: '%s'", text )
+  }
+  e:= m.GetEntries ().Get ( id )
+  if e == nil {
+    return nil,fmt.Errorf ( "La entrada indicada (%d) no existeix", id )
+  }
+
+  return e,nil
+
+} // end parseEntryID
+
+
+func parseFileID( m *model.Model, text string ) (*model.File,error) {
+
+  id,err:= strconv.ParseInt ( text, 10, 64 )
+  if err != nil {
+    return nil,fmt.Errorf ( "Identificador de fitxer no vàlid: '%s'", text )
+  }
+  if !m.GetFiles ().Exists ( id ) {
+    return nil,fmt.Errorf ( "El fitxer indicat (%d) no existeix", id )
+  }
+
+  return m.GetFiles ().Get ( id ),nil
+
+} // end parseFileID
+
+
+// Busca una plataforma pel nom curt (no distingeix majúscules).
+func findPlatform( m *model.Model, short_name string ) (int,error) {
+
+  short_name= strings.ToUpper ( strings.TrimSpace ( short_name ) )
+  plats:= m.GetPlatforms ()
+  for _,id:= range plats.GetIDs () {
+    if plats.GetPlatform ( id ).GetShortName () == short_name {
+      return id,nil
+    }
+  }
+
+  return -1,fmt.Errorf ( "La plataforma indicada ('%s') no existeix",
+    short_name )
+
+} // end findPlatform
+
+
+// Busca una etiqueta pel nom (no distingeix majúscules).
+func findLabel( m *model.Model, name string ) (int,error) {
+
+  name= strings.TrimSpace ( name )
+  labels:= m.GetLabels ()
+  for _,id:= range labels.GetIDs () {
+    if strings.EqualFold ( labels.Get ( id ).GetName (), name ) {
+      return id,nil
+    }
+  }
+
+  return -1,fmt.Errorf ( "L'etiqueta indicada ('%s') no existeix", name )
+
+} // end findLabel
+
+
+
+
+// PROGRESS BAR ////////////////////////////////////////////////////////////////
+
+// Barra de progrés que escriu en l'eixida d'errors.
+type _TextProgressBar struct {
+  w io.Writer
+}
+
+
+func newTextProgressBar() *_TextProgressBar {
+  return &_TextProgressBar{os.Stderr}
+} // end newTextProgressBar
+
+
+func (self *_TextProgressBar) Close() {
+  fmt.Fprintf ( self.w, "\n" )
+} // end Close
+
+
+func (self *_TextProgressBar) Set( msg string, f float32 ) {
+  fmt.Fprintf ( self.w, "\r\033[K[%3d%%] %s", int(f*100+0.5), msg )
+} // end Set
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Executa el subcomandament indicat en 'args' (sense el nom del
+// programa).
+func Run( m *model.Model, args []string ) error {
+
+  // Ajuda
+  if len(args) == 0 || args[0] == "-h" || args[0] == "-help" ||
+    args[0] == "--help" || args[0] == "help" {
+    printUsage ( os.Stdout )
+    return nil
+  }
+
+  // Busca subcomandament
+  cmd,ok:= _CMDS[args[0]]
+  if !ok {
+    printUsage ( os.Stderr )
+    return fmt.Errorf ( "Subcomandament desconegut: '%s'", args[0] )
+  }
+
+  // Executa
+  fs:= flag.NewFlagSet ( args[0], flag.ContinueOnError )
+  fs.Usage= func() {
+    fmt.Fprintf ( fs.Output (), "Ús: imgteka %s %s\n  %s\n",
+      args[0], cmd.args, cmd.help )
+    fs.PrintDefaults ()
+  }
+
+  err:= cmd.run ( m, fs, args[1:] )
+  if errors.Is ( err, flag.ErrHelp ) {
+    err= nil
+  }
+
+  return err
+
+} // end Run
@@ -24,6 +24,8 @@
 package lock
 
 import (
+  "fmt"
+  "hash/fnv"
   "log"
   
   "github.com/godbus/dbus/v5"
@@ -39,18 +41,26 @@ import (
 var _con *dbus.Conn
 
 const _LOCK_NAME= "org.github.adriagipas.imgteka.lock"
-const _LOCK_INT= _LOCK_NAME+".interface"
 const _LOCK_OBJECT= "/org/github/adriagipas/imgteka/lock"
 
+// Nom i interfície de la biblioteca actual.
+var _name string
+var _int string
 
 
+// Si no s'obté el bloqueig i 'show_win' és cert demana al procés que
+// el té que mostre la finestra.
+func initLock( lib string, show_win bool ) bool {
 
-/****************/
-/* PART PÚBLICA */
-/****************/
-
-func Init () bool {
-
+  // Nom
+  _name= _LOCK_NAME
+  if lib != "" {
+    h:= fnv.New64a ()
+    h.Write ( []byte(lib) )
+    _name+= fmt.Sprintf ( ".r%016x", h.Sum64 () )
+  }
+  _int= _name+".interface"
+  
   var err error
   // Connecta
   _con,err= dbus.ConnectSessionBus ()
@@ -60,7 +70,7 @@ func Init () bool {
   }
 
   // Fica nom
-  res,err:= _con.RequestName ( _LOCK_NAME, dbus.NameFlagDoNotQueue )
+  res,err:= _con.RequestName ( _name, dbus.NameFlagDoNotQueue )
   if err != nil {
     log.Printf ( "error a l'obtindre el nom de D-BUS: %s", err )
     return true // En aquest cas permet que continue
@@ -71,13 +81,13 @@ func Init () bool {
   // Senyals
   if ret {
     if err:= _con.AddMatchSignal (
-      dbus.WithMatchInterface ( _LOCK_INT ),
+      dbus.WithMatchInterface ( _int ),
     ); err != nil {
       log.Printf ( "no s'ha pogut registrar la regla de la interfície"+
         " en D-BUS: %s", err )
     }
-  } else {
-    if err:= _con.Emit ( _LOCK_OBJECT, _LOCK_INT+".ShowWin" ); err != nil {
+  } else if show_win {
+    if err:= _con.Emit ( _LOCK_OBJECT, _int+".ShowWin" ); err != nil {
       log.Printf ( "no s'ha pogut emetre la senyal: %s", err )
     }
     
@@ -85,11 +95,34 @@ func Init () bool {
   
   return ret
   
+} // end initLock
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// 'lib' identifica la biblioteca (buit per a la biblioteca per
+// defecte). Sols es permet un procés per biblioteca.
+func Init ( lib string ) bool {
+  return initLock ( lib, true )
 } // end Init
 
 
+// Com Init però, si la biblioteca ja està oberta, no mostra la
+// finestra del procés que la té. Pensat per a la línia de
+// comandaments.
+func TryInit ( lib string ) bool {
+  return initLock ( lib, false )
+} // end TryInit
+
+
 func Close () {
-  _con.Close ()
+  if _con != nil {
+    _con.Close ()
+  }
 } // end Close
 
 
@@ -99,7 +132,7 @@ func CheckSignals () bool {
   c:= make ( chan *dbus.Signal, 100 )
 	_con.Signal ( c )
   for v:= range c {
-    if v.Name == _LOCK_INT+".ShowWin" {
+    if v.Name == _int+".ShowWin" {
       return true
     }
   }
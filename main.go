@@ -24,25 +24,90 @@
 package main
 
 import (
+  "fmt"
   "log"
+  "os"
+  "strings"
 
+  "github.com/adriagipas/imgteka/cli"
   "github.com/adriagipas/imgteka/lock"
   "github.com/adriagipas/imgteka/model"
   "github.com/adriagipas/imgteka/view"
 )
 
+
+// Processa les opcions globals (-root DIRECTORI i -profile PERFIL)
+// que van abans del subcomandament. Torna el directori arrel, el perfil
+// i la resta d'arguments.
+func parseGlobalArgs( args []string ) (string,string,[]string,error) {
+
+  opts:= map[string]string{"root":"","profile":""}
+  for len(args) > 0 {
+    name,value,has_value:= strings.Cut (
+      strings.TrimLeft ( args[0], "-" ), "=" )
+    if _,ok:= opts[name]; !ok || !strings.HasPrefix ( args[0], "-" ) {
+      break
+    }
+    if has_value {
+      args= args[1:]
+    } else if len(args) < 2 {
+      return "","",nil,fmt.Errorf ( "L'opció %s necessita un valor",
+        args[0] )
+    } else {
+      value,args= args[1],args[2:]
+    }
+    opts[name]= value
+  }
+
+  return opts["root"],opts["profile"],args,nil
+  
+} // end parseGlobalArgs
+
+
 func main() {
 
   // Inicialitza log.
   log.SetPrefix ( "[imgteka]" )
   log.SetFlags ( 0 )
 
+  // Biblioteca
+  root,profile,args,err:= parseGlobalArgs ( os.Args[1:] )
+  if err != nil {
+    log.Fatal ( err )
+  }
+  lib,err:= model.ResolveLibrary ( root, profile )
+  if err != nil {
+    log.Fatal ( err )
+  }
+  
+  // Mode línia de comandaments. També bloqueja la biblioteca: la
+  // interfície gràfica no veuria els canvis i podria desfer-los.
+  if len(args) > 0 {
+    if !lock.TryInit ( lib.GetID () ) {
+      log.Fatal ( "La biblioteca està oberta en un altre procés (p.e. la"+
+        " interfície gràfica). Tanca'l abans d'executar subcomandaments" )
+    }
+    model,err:= model.New ( lib )
+    if err != nil {
+      lock.Close ()
+      log.Fatal ( err )
+    }
+    err= cli.Run ( model, args )
+    model.Close ()
+    lock.Close ()
+    if err != nil {
+      log.Fatal ( err )
+    }
+    return
+  }
+  
   // Executa
-  if lock.Init () {
-    model,err:= model.New ()
+  if lock.Init ( lib.GetID () ) {
+    model,err:= model.New ( lib )
     if err != nil {
       log.Fatal ( err )
     }
+    model.GetFiles ().StartBackfill ()
     if err:= view.Run ( model ); err != nil {
       log.Fatal ( err )
     }
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  archive.go - Importació dels fitxers continguts en fitxers
+ *               d'emmagatzemament (ZIP, TAR).
+ */
+
+package model
+
+import (
+  "fmt"
+  "io"
+  "log"
+  "os"
+  "path"
+  "path/filepath"
+
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Directori, dins del de dades, on es creen temporalment els fitxers
+// que s'han d'importar. Està en el mateix sistema de fitxers que
+// 'files' perquè es puguen importar amb enllaços durs.
+const _ROOT_TMP= "tmp"
+
+
+// Detecta el tipus del fitxer d'emmagatzemament. Les imatges de disc
+// no es consideren fitxers d'emmagatzemament.
+func openArchive( file_name string ) (file_type.Archive,int,error) {
+
+  cands,err:= file_type.Detect ( file_name )
+  if err != nil { return nil,-1,err }
+  for _,c:= range cands {
+    reg,err:= file_type.GetRegistration ( c.GetID () )
+    if err != nil || (reg.Caps&file_type.CAP_CONTAINER) == 0 { continue }
+    if arch,err:= file_type.GetArchive ( c.GetID () ); err == nil {
+      return arch,c.GetID (),nil
+    }
+  }
+  
+  return nil,-1,fmt.Errorf ( "'%s' no és un fitxer ZIP o TAR", file_name )
+  
+} // end openArchive
+
+
+// Crea un directori temporal dins de _ROOT_TMP. La funció que torna
+// l'elimina.
+func (self *Files) newTempDir( pattern string ) (string,func(),error) {
+
+  tmp_root,err:= self.dirs.GetDataFileName ( _ROOT_TMP )
+  if err != nil { return "",nil,err }
+  if err:= os.MkdirAll ( tmp_root, 0755 ); err != nil { return "",nil,err }
+  tmp_dir,err:= os.MkdirTemp ( tmp_root, pattern )
+  if err != nil { return "",nil,err }
+  clean:= func() {
+    if err:= os.RemoveAll ( tmp_dir ); err != nil {
+      log.Printf ( "No s'ha pogut eliminar '%s': %s", tmp_dir, err )
+    }
+    os.Remove ( tmp_root )
+  }
+  
+  return tmp_dir,clean,nil
+  
+} // end newTempDir
+
+
+func extractArchiveMember(
+
+  arch      file_type.Archive,
+  file_name string,
+  member    string,
+  dst       string,
+  
+) error {
+
+  r,err:= arch.OpenMember ( file_name, member )
+  if err != nil { return err }
+  defer r.Close ()
+  f,err:= os.Create ( dst )
+  if err != nil { return err }
+  if _,err:= io.Copy ( f, r ); err != nil {
+    f.Close ()
+    return fmt.Errorf ( "No s'ha pogut extraure '%s': %s", member, err )
+  }
+  
+  return f.Close ()
+  
+} // end extractArchiveMember
+
+
+// Afegeix a l'entrada els fitxers 'members' continguts en
+// 'file_name'. Cada fitxer s'extrau, es detecta el seu tipus i
+// s'afegeix com qualsevol altre fitxer. Torna el nombre de fitxers
+// afegits.
+func (self *Files) AddFromArchive(
+
+  e         *Entry,
+  file_name string,
+  members   []string,
+  keep      bool,
+  create_pb func() view.ProgressBar,
+  
+) (int,error) {
+
+  // Obri
+  file_name,err:= readLink ( file_name )
+  if err != nil {
+    return 0,fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s': %s",
+      file_name, err )
+  }
+  arch,arch_type,err:= openArchive ( file_name )
+  if err != nil { return 0,err }
+
+  // Directori temporal
+  tmp_dir,clean,err:= self.newTempDir ( "archive" )
+  if err != nil { return 0,err }
+  defer clean ()
+
+  // Afegeix fitxers
+  n:= 0
+  for i,member:= range members {
+
+    // Extrau
+    name:= path.Base ( member )
+    tmp_fn:= filepath.Join ( tmp_dir, fmt.Sprint ( i ) )
+    pb:= create_pb ()
+    pb.Set ( fmt.Sprintf ( "Extrau '%s'...", member ), 0 )
+    err:= extractArchiveMember ( arch, file_name, member, tmp_fn )
+    pb.Close ()
+    if err != nil { return n,err }
+
+    // Detecta tipus i afegeix
+    cands,err:= file_type.Detect ( tmp_fn )
+    if err != nil { return n,err }
+    dups,err:= self.Add ( e, tmp_fn, name, cands[0].GetID (), create_pb )
+    if err != nil {
+      return n,fmt.Errorf ( "No s'ha pogut afegir '%s': %s", member, err )
+    }
+    logDuplicates ( name, dups )
+    n++
+    
+  }
+
+  // Conserva l'original
+  if keep {
+    dups,err:= self.Add ( e, file_name, filepath.Base ( file_name ),
+      arch_type, create_pb )
+    if err != nil { return n,err }
+    logDuplicates ( filepath.Base ( file_name ), dups )
+    n++
+  }
+  
+  return n,nil
+  
+} // end AddFromArchive
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func (self *Model) GetArchiveMembers(
+  file_name string,
+) ([]view.ArchiveMember,error) {
+
+  arch,_,err:= openArchive ( file_name )
+  if err != nil { return nil,err }
+  members,err:= arch.GetMembers ( file_name )
+  if err != nil { return nil,err }
+  ret:= make([]view.ArchiveMember,len(members))
+  for i,m:= range members {
+    ret[i]= m
+  }
+
+  return ret,nil
+  
+} // end GetArchiveMembers
+
+
+// Indica si es pot explorar el contingut del fitxer (ZIP, TAR,
+// imatges de disc).
+func (self *File) IsContainer() bool {
+  _,err:= file_type.GetArchive ( self.file_type_id )
+  return err == nil
+} // end IsContainer
+
+
+func (self *File) GetMembers() ([]view.ArchiveMember,error) {
+
+  arch,err:= file_type.GetArchive ( self.file_type_id )
+  if err != nil { return nil,err }
+  fn:= self.GetPath ()
+  if fn == "" {
+    return nil,fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s'",
+      self.name )
+  }
+  members,err:= arch.GetMembers ( fn )
+  if err != nil { return nil,err }
+  ret:= make([]view.ArchiveMember,len(members))
+  for i,m:= range members {
+    ret[i]= m
+  }
+
+  return ret,nil
+  
+} // end GetMembers
+
+
+func (self *File) OpenMember( member string ) (io.ReadCloser,error) {
+
+  arch,err:= file_type.GetArchive ( self.file_type_id )
+  if err != nil { return nil,err }
+  fn:= self.GetPath ()
+  if fn == "" {
+    return nil,fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s'",
+      self.name )
+  }
+  
+  return arch.OpenMember ( fn, member )
+  
+} // end OpenMember
+
+
+// Extrau els fitxers indicats en el directori 'dir' mantenint la
+// ruta que tenen dins del fitxer. No sobreescriu fitxers existents.
+func (self *File) ExtractMembers(
+
+  members   []string,
+  dir       string,
+  create_pb func() view.ProgressBar,
+  
+) error {
+
+  arch,err:= file_type.GetArchive ( self.file_type_id )
+  if err != nil { return err }
+  fn:= self.GetPath ()
+  if fn == "" {
+    return fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s'", self.name )
+  }
+
+  // Extrau
+  pb:= create_pb ()
+  defer pb.Close ()
+  for i,member:= range members {
+    pb.Set ( member, float32(i)/float32(len(members)) )
+    rel:= filepath.FromSlash ( member )
+    if !filepath.IsLocal ( rel ) {
+      return fmt.Errorf ( "Ruta no vàlida dins del fitxer: '%s'", member )
+    }
+    dst:= filepath.Join ( dir, rel )
+    if _,err:= os.Stat ( dst ); err == nil {
+      return fmt.Errorf ( "El fitxer '%s' ja existeix", dst )
+    }
+    if err:= os.MkdirAll ( filepath.Dir ( dst ), 0755 ); err != nil {
+      return err
+    }
+    if err:= extractArchiveMember ( arch, fn, member, dst ); err != nil {
+      os.Remove ( dst )
+      return err
+    }
+  }
+  pb.Set ( "", 1 )
+  
+  return nil
+  
+} // end ExtractMembers
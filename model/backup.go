@@ -0,0 +1,523 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  backup.go - Còpies de seguretat de tota la biblioteca. Una còpia és
+ *              un directori amb la base de dades, els comandaments, un
+ *              manifest amb els hashes de tots els fitxers i,
+ *              opcionalment, un tar amb el contingut dels fitxers.
+ */
+
+package model
+
+import (
+  "archive/tar"
+  "crypto/md5"
+  "crypto/sha1"
+  "database/sql"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path"
+  "path/filepath"
+  "time"
+
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+const _BACKUP_DB= "database.db"
+const _BACKUP_CMDS= "commands.json"
+const _BACKUP_MANIFEST= "manifest.json"
+const _BACKUP_TAR= "files.tar"
+
+const _BACKUP_FORMAT= 1
+
+
+// Fitxer de la biblioteca en el manifest. Els noms són relatius al
+// directori de dades.
+type _BackupFile struct {
+  ID    int64    `json:"id"`
+  Path  string   `json:"path"`
+  Links []string `json:"links"`
+  Size  int64    `json:"size"`
+  MD5   string   `json:"md5"`
+  SHA1  string   `json:"sha1"`
+
+  // Compressió del fitxer en 'files'. La grandària i els hashes són
+  // del contingut sense comprimir.
+  Compression string `json:"compression,omitempty"`
+
+  // Sols per a construir els noms
+  name      string
+  file_type int
+  entry     string
+  platform  string
+}
+
+
+type _BackupManifest struct {
+  Format        int           `json:"format"`
+  SchemaVersion int           `json:"schema_version"`
+  Date          int64         `json:"date"`
+  WithFiles     bool          `json:"with_files"`
+  Files         []_BackupFile `json:"files"`
+}
+
+
+// Completa els noms relatius dels fitxers segons l'organització del
+// directori 'files'.
+func setBackupPaths( files []_BackupFile, store int ) error {
+
+  for i:= range files {
+    f:= &files[i]
+    ft,err:= file_type.Get ( f.file_type )
+    if err != nil { return err }
+    f.Path= storeRelName ( store, ft.GetShortName (), f.name, f.SHA1,
+      f.Compression )
+    f.Links= []string{path.Join ( _ROOT_ENTRIES, f.platform, f.entry,
+      compressedName ( f.name, f.Compression ) )}
+  }
+
+  return nil
+
+} // end setBackupPaths
+
+
+// Copia 'r' en 'w' i comprova que la grandària i els hashes
+// coincideixen amb els del manifest. Si el fitxer està comprimit es
+// copia tal qual però es comprova el contingut sense comprimir.
+func copyAndCheck( w io.Writer, r io.Reader, f *_BackupFile ) error {
+
+  h_md5,h_sha1:= md5.New (),sha1.New ()
+  var n int64
+  if f.Compression == "" {
+    var err error
+    n,err= io.Copy ( io.MultiWriter ( w, h_md5, h_sha1 ), r )
+    if err != nil { return err }
+  } else {
+    codec,err:= getCodec ( f.Compression )
+    if err != nil { return err }
+    cr,err:= codec.new_reader ( io.TeeReader ( r, w ) )
+    if err != nil { return err }
+    n,err= io.Copy ( io.MultiWriter ( h_md5, h_sha1 ), cr )
+    cr.Close ()
+    if err != nil { return err }
+    if _,err:= io.Copy ( w, r ); err != nil { return err }
+  }
+  if n != f.Size ||
+    fmt.Sprintf ( "%x", h_md5.Sum ( nil ) ) != f.MD5 ||
+    fmt.Sprintf ( "%x", h_sha1.Sum ( nil ) ) != f.SHA1 {
+    return fmt.Errorf ( "El contingut de '%s' no coincideix amb els"+
+      " hashes registrats", f.Path )
+  }
+
+  return nil
+
+} // end copyAndCheck
+
+
+func readBackupManifest( dir string ) (*_BackupManifest,error) {
+
+  f,err:= os.Open ( filepath.Join ( dir, _BACKUP_MANIFEST ) )
+  if err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut obrir el manifest de la còpia"+
+      " de seguretat: %s", err )
+  }
+  defer f.Close ()
+  var ret _BackupManifest
+  if err:= json.NewDecoder ( f ).Decode ( &ret ); err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut llegir el manifest de la"+
+      " còpia de seguretat: %s", err )
+  }
+  if ret.Format != _BACKUP_FORMAT {
+    return nil,fmt.Errorf ( "Format de còpia de seguretat desconegut: %d",
+      ret.Format )
+  }
+
+  return &ret,nil
+
+} // end readBackupManifest
+
+
+func writeBackupManifest( dir string, manifest *_BackupManifest ) error {
+
+  fn:= filepath.Join ( dir, _BACKUP_MANIFEST )
+  f,err:= os.Create ( fn )
+  if err != nil {
+    return fmt.Errorf ( "No s'ha pogut crear '%s': %s", fn, err )
+  }
+  defer f.Close ()
+  enc:= json.NewEncoder ( f )
+  enc.SetIndent ( "", "  " )
+  if err:= enc.Encode ( manifest ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut escriure '%s': %s", fn, err )
+  }
+
+  return f.Close ()
+
+} // end writeBackupManifest
+
+
+// Comprova que la base de dades de la còpia és íntegra, que no és
+// d'una versió més nova i que els seus fitxers són els del manifest.
+func checkBackupDatabase( fn string, manifest *_BackupManifest ) error {
+
+  conn,err:= sql.Open ( "sqlite3", fn )
+  if err != nil { return err }
+  defer conn.Close ()
+
+  // Integritat i versió
+  var check string
+  if err:= conn.QueryRow ( "PRAGMA integrity_check;" ).Scan (
+    &check ); err != nil {
+    return err
+  }
+  if check != "ok" {
+    return fmt.Errorf ( "La base de dades de la còpia està corrupta: %s",
+      check )
+  }
+  version,err:= getSchemaVersion ( conn )
+  if err != nil { return err }
+  if version > len(_MIGRATIONS) {
+    return fmt.Errorf ( "La còpia de seguretat és d'una versió més nova"+
+      " (%d) que la suportada per aquesta versió del programa (%d)",
+      version, len(_MIGRATIONS) )
+  }
+
+  // Fitxers
+  files,err:= loadBackupFiles ( conn )
+  if err != nil { return err }
+  store,err:= loadStore ( conn )
+  if err != nil { return err }
+  if err:= setBackupPaths ( files, store ); err != nil { return err }
+  if len(files) != len(manifest.Files) {
+    return errors.New ( "La base de dades de la còpia no coincideix amb"+
+      " el manifest" )
+  }
+  for i,f:= range files {
+    m:= &manifest.Files[i]
+    if f.ID != m.ID || f.Path != m.Path || f.Size != m.Size ||
+      f.MD5 != m.MD5 || f.SHA1 != m.SHA1 {
+      return fmt.Errorf ( "El fitxer '%s' de la base de dades de la còpia"+
+        " no coincideix amb el manifest", f.Path )
+    }
+  }
+
+  return nil
+
+} // end checkBackupDatabase
+
+
+// Recorre el tar de la còpia comprovant cada fitxer amb el
+// manifest. Si 'dirs' no és nil, a més, desa els fitxers en el
+// directori de dades.
+func readBackupTar(
+
+  fn       string,
+  manifest *_BackupManifest,
+  dirs     *Dirs,
+  pb       view.ProgressBar,
+
+) error {
+
+  // Fitxers esperats
+  files:= make(map[string]*_BackupFile)
+  for i:= range manifest.Files {
+    files[manifest.Files[i].Path]= &manifest.Files[i]
+  }
+
+  // Obri
+  fd,err:= os.Open ( fn )
+  if err != nil { return err }
+  defer fd.Close ()
+  tr:= tar.NewReader ( fd )
+
+  // Recorre
+  n:= 0
+  for hdr,err:= tr.Next (); err != io.EOF; hdr,err= tr.Next () {
+    if err != nil { return err }
+    f,ok:= files[hdr.Name]
+    if !ok {
+      return fmt.Errorf ( "El fitxer '%s' del tar no està en el manifest",
+        hdr.Name )
+    }
+    delete(files,hdr.Name)
+    pb.Set ( f.Path, float32(n)/float32(len(manifest.Files)) )
+    if dirs == nil {
+      err= copyAndCheck ( io.Discard, tr, f )
+    } else {
+      err= restoreFile ( dirs, tr, f )
+    }
+    if err != nil { return err }
+    n++
+  }
+  for name:= range files {
+    return fmt.Errorf ( "El fitxer '%s' no està en el tar", name )
+  }
+
+  return nil
+
+} // end readBackupTar
+
+
+// Desa en el directori de dades un fitxer del tar. S'escriu en un
+// fitxer temporal i no es reemplaça l'original fins que s'ha
+// comprovat.
+func restoreFile( dirs *Dirs, r io.Reader, f *_BackupFile ) error {
+
+  fname,err:= dirs.GetDataFileName ( f.Path )
+  if err != nil { return err }
+  tmp,err:= os.CreateTemp ( filepath.Dir ( fname ), ".restore-*" )
+  if err != nil { return err }
+  defer os.Remove ( tmp.Name () )
+  if err:= copyAndCheck ( tmp, r, f ); err != nil {
+    tmp.Close ()
+    return err
+  }
+  if err:= tmp.Close (); err != nil { return err }
+  if err:= os.Chmod ( tmp.Name (), 0400 ); err != nil { return err }
+
+  return os.Rename ( tmp.Name (), fname )
+
+} // end restoreFile
+
+
+// Comprova els fitxers que ja hi ha en el directori de dades.
+func checkLibraryFiles(
+
+  dirs     *Dirs,
+  manifest *_BackupManifest,
+  pb       view.ProgressBar,
+
+) error {
+
+  for i:= range manifest.Files {
+    f:= &manifest.Files[i]
+    pb.Set ( f.Path, float32(i)/float32(len(manifest.Files)) )
+    fname,err:= dirs.GetDataFileName ( f.Path )
+    if err != nil { return err }
+    fd,err:= os.Open ( fname )
+    if err != nil {
+      return fmt.Errorf ( "No s'ha pogut obrir '%s': %s", fname, err )
+    }
+    err= copyAndCheck ( io.Discard, fd, f )
+    fd.Close ()
+    if err != nil { return err }
+  }
+
+  return nil
+
+} // end checkLibraryFiles
+
+
+// Torna a crear els enllaços de les entrades cap als fitxers.
+func restoreLinks( dirs *Dirs, manifest *_BackupManifest ) error {
+
+  for _,f:= range manifest.Files {
+    fname,err:= dirs.GetDataFileName ( f.Path )
+    if err != nil { return err }
+    finfo,err:= os.Stat ( fname )
+    if err != nil { return err }
+    for _,link:= range f.Links {
+      lname,err:= dirs.GetDataFileName ( link )
+      if err != nil { return err }
+      if linfo,err:= os.Stat ( lname ); err == nil {
+        if os.SameFile ( finfo, linfo ) { continue }
+        if err:= os.Remove ( lname ); err != nil { return err }
+      }
+      if err:= linkFile ( fname, lname ); err != nil { return err }
+    }
+  }
+
+  return nil
+
+} // end restoreLinks
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Crea una còpia de seguretat de la biblioteca en el directori
+// 'dir'. Si 'with_files' és cert també es copia el contingut dels
+// fitxers.
+func (self *Model) Backup(
+
+  dir        string,
+  with_files bool,
+  create_pb  func() view.ProgressBar,
+
+) error {
+
+  // Prepara directori
+  if _,err:= os.Stat ( filepath.Join ( dir, _BACKUP_MANIFEST ) );
+  err == nil {
+    return fmt.Errorf ( "El directori '%s' ja conté una còpia de"+
+      " seguretat", dir )
+  }
+  if err:= os.MkdirAll ( dir, 0755 ); err != nil { return err }
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Base de dades i comandaments
+  pb.Set ( "Copia la base de dades...", 0 )
+  db_fn:= filepath.Join ( dir, _BACKUP_DB )
+  if err:= self.db.BackupTo ( db_fn ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut copiar la base de dades: %s", err )
+  }
+  if err:= self.cmds.save ( filepath.Join ( dir, _BACKUP_CMDS ) );
+  err != nil {
+    return err
+  }
+
+  // Manifest a partir de la còpia de la base de dades
+  conn,err:= sql.Open ( "sqlite3", db_fn )
+  if err != nil { return err }
+  defer conn.Close ()
+  version,err:= getSchemaVersion ( conn )
+  if err != nil { return err }
+  files,err:= loadBackupFiles ( conn )
+  if err != nil { return err }
+  store,err:= loadStore ( conn )
+  if err != nil { return err }
+  if err:= setBackupPaths ( files, store ); err != nil { return err }
+  manifest:= _BackupManifest{
+    Format        : _BACKUP_FORMAT,
+    SchemaVersion : version,
+    Date          : time.Now ().Unix (),
+    WithFiles     : with_files,
+    Files         : files,
+  }
+
+  // Contingut dels fitxers. Si es desen per SHA1 diversos fitxers
+  // poden compartir el mateix contingut, que sols es copia una vegada.
+  if with_files {
+    copied:= make(map[string]bool)
+    fd,err:= os.Create ( filepath.Join ( dir, _BACKUP_TAR ) )
+    if err != nil { return err }
+    defer fd.Close ()
+    tw:= tar.NewWriter ( fd )
+    for i:= range files {
+      f:= &files[i]
+      if copied[f.Path] { continue }
+      copied[f.Path]= true
+      pb.Set ( f.Path, float32(i)/float32(len(files)) )
+      fname,err:= self.dirs.GetDataFileName ( f.Path )
+      if err != nil { return err }
+      src,err:= os.Open ( fname )
+      if err != nil {
+        return fmt.Errorf ( "No s'ha pogut obrir '%s': %s", fname, err )
+      }
+      info,err:= src.Stat ()
+      if err != nil { src.Close (); return err }
+      err= tw.WriteHeader ( &tar.Header{
+        Typeflag : tar.TypeReg,
+        Name     : f.Path,
+        Size     : info.Size (),
+        Mode     : 0400,
+        ModTime  : time.Now (),
+      })
+      if err == nil {
+        err= copyAndCheck ( tw, src, f )
+      }
+      src.Close ()
+      if err != nil { return err }
+    }
+    if err:= tw.Close (); err != nil { return err }
+    if err:= fd.Close (); err != nil { return err }
+  }
+
+  // El manifest s'escriu l'últim: indica que la còpia està completa
+  pb.Set ( "Escriu el manifest...", 1 )
+
+  return writeBackupManifest ( dir, &manifest )
+
+} // end Backup
+
+
+// Restaura la còpia de seguretat del directori 'dir'. Abans de
+// modificar res es comprova la còpia amb el manifest: la base de
+// dades i, si la còpia inclou el contingut dels fitxers, el tar. Si no
+// l'inclou, es comprova que els fitxers de la biblioteca coincideixen
+// amb el manifest.
+func (self *Model) Restore(
+
+  dir       string,
+  create_pb func() view.ProgressBar,
+
+) error {
+
+  // Comprova
+  manifest,err:= readBackupManifest ( dir )
+  if err != nil { return err }
+  db_fn:= filepath.Join ( dir, _BACKUP_DB )
+  if err:= checkBackupDatabase ( db_fn, manifest ); err != nil {
+    return err
+  }
+  tar_fn:= filepath.Join ( dir, _BACKUP_TAR )
+  if manifest.WithFiles {
+    pb:= create_pb ()
+    err= readBackupTar ( tar_fn, manifest, nil, pb )
+    pb.Close ()
+  } else {
+    pb:= create_pb ()
+    err= checkLibraryFiles ( self.dirs, manifest, pb )
+    pb.Close ()
+  }
+  if err != nil { return err }
+
+  // Restaura base de dades i comandaments
+  if self.files.StopBackfill () {
+    defer self.files.StartBackfill ()
+  }
+  if err:= self.db.RestoreFrom ( db_fn, self.dirs ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut restaurar la base de dades: %s", err )
+  }
+  store,err:= self.db.GetStore ()
+  if err != nil { return err }
+  self.dirs.SetStore ( store )
+  self.cmds.load ( filepath.Join ( dir, _BACKUP_CMDS ) )
+  self.plats.reset ()
+  self.labels.reset ()
+  self.dats.reset ()
+  self.files.reset ()
+  self.entries.reset ()
+
+  // Restaura fitxers
+  if manifest.WithFiles {
+    pb:= create_pb ()
+    err:= readBackupTar ( tar_fn, manifest, self.dirs, pb )
+    pb.Close ()
+    if err != nil { return err }
+  }
+
+  return restoreLinks ( self.dirs, manifest )
+
+} // end Restore
@@ -24,30 +24,422 @@
 package model
 
 import (
+  "database/sql"
   "encoding/json"
+  "errors"
   "fmt"
   "log"
   "os"
   "os/exec"
+  "path/filepath"
+  "regexp"
   "strings"
   "sync"
 
   "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
 )
 
 
 
 
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Versió 1: objecte que mapeja directament tipus a comandament (el
+// fitxer s'afegia com a únic argument). Versió 2: plantilles. Versió
+// 3: llistes de llançadors per tipus, plataforma i entrada.
+const _COMMANDS_VERSION= 3
+
+const _CMD_FILES_OF_ENTRY= "files_of_entry"
+
+var _CMD_PLACEHOLDER= regexp.MustCompile ( `\{([a-z_]+)\}` )
+
+var _CMD_PLACEHOLDERS= map[string]bool{
+  "file"              : true,
+  "dir"               : true,
+  "entry"             : true,
+  "platform"          : true,
+  "name"              : true,
+  _CMD_FILES_OF_ENTRY : true,
+}
+
+
+type _Launcher struct {
+  Name    string `json:"name"`
+  Command string `json:"command"`
+}
+
+
+// Mapeja identificador de tipus a llançadors.
+type _Launchers map[int][]_Launcher
+
+
+type _CommandsConf struct {
+  Version   int                  `json:"version"`
+  Commands  map[int]string       `json:"commands,omitempty"` // Versió 2
+  Launchers _Launchers           `json:"launchers,omitempty"`
+  Platforms map[int]_Launchers   `json:"platforms,omitempty"`
+  Entries   map[int64]_Launchers `json:"entries,omitempty"`
+  Last      map[int64]string     `json:"last,omitempty"`
+}
+
+
+// Valors per a substituir en una plantilla.
+type _CommandVars struct {
+  vars           map[string]string
+  files_of_entry []string
+}
+
+
+// Separa una plantilla en arguments seguint les regles de la
+// shell: cometes simples (tot literal), cometes dobles (sols '\'
+// escapa '"', '\\', '$' i '`') i '\' fora de cometes.
+func splitCommandTemplate( text string ) ([]string,error) {
+
+  var ret []string
+  var arg strings.Builder
+  in_arg:= false
+  quote:= rune(0)
+  escape:= false
+  for _,c:= range text {
+    if escape {
+      if quote == '"' && !strings.ContainsRune ( "\"\\$`", c ) {
+        arg.WriteRune ( '\\' )
+      }
+      arg.WriteRune ( c )
+      escape= false
+    } else if quote == '\'' {
+      if c == '\'' {
+        quote= 0
+      } else {
+        arg.WriteRune ( c )
+      }
+    } else if c == '\\' {
+      escape,in_arg= true,true
+    } else if quote == '"' {
+      if c == '"' {
+        quote= 0
+      } else {
+        arg.WriteRune ( c )
+      }
+    } else if c == '\'' || c == '"' {
+      quote,in_arg= c,true
+    } else if c == ' ' || c == '\t' || c == '\n' {
+      if in_arg {
+        ret= append(ret,arg.String ())
+        arg.Reset ()
+        in_arg= false
+      }
+    } else {
+      arg.WriteRune ( c )
+      in_arg= true
+    }
+  }
+  if escape {
+    return nil,errors.New ( "'\\' al final del comandament" )
+  } else if quote != 0 {
+    return nil,fmt.Errorf ( "Falta tancar les cometes (%c)", quote )
+  }
+  if in_arg {
+    ret= append(ret,arg.String ())
+  }
+  
+  return ret,nil
+  
+} // end splitCommandTemplate
+
+
+// Comprova la plantilla i torna els arguments i si conté alguna
+// variable.
+func parseCommandTemplate( text string ) ([]string,bool,error) {
+
+  args,err:= splitCommandTemplate ( text )
+  if err != nil { return nil,false,err }
+  if len(args) == 0 {
+    return nil,false,errors.New ( "El comandament està buit" )
+  }
+  has_vars:= false
+  for i,arg:= range args {
+    for _,m:= range _CMD_PLACEHOLDER.FindAllStringSubmatch ( arg, -1 ) {
+      if !_CMD_PLACEHOLDERS[m[1]] {
+        return nil,false,fmt.Errorf ( "Variable desconeguda: %s", m[0] )
+      }
+      if m[1] == _CMD_FILES_OF_ENTRY && (i == 0 || arg != m[0]) {
+        return nil,false,fmt.Errorf ( "%s ha de ser un argument sencer",
+          m[0] )
+      }
+      has_vars= true
+    }
+  }
+
+  return args,has_vars,nil
+  
+} // end parseCommandTemplate
+
+
+// Indica si la plantilla usa {files_of_entry}.
+func usesFilesOfEntry( text string ) (bool,error) {
+
+  args,_,err:= parseCommandTemplate ( text )
+  if err != nil { return false,err }
+  for _,arg:= range args {
+    if arg == "{"+_CMD_FILES_OF_ENTRY+"}" {
+      return true,nil
+    }
+  }
+
+  return false,nil
+  
+} // end usesFilesOfEntry
+
+
+// Construeix els arguments del comandament substituint les
+// variables. Si la plantilla no en té el fitxer s'afegeix com a últim
+// argument.
+func expandCommandTemplate(
+  
+  text string,
+  vars *_CommandVars,
+  
+) ([]string,error) {
+
+  args,has_vars,err:= parseCommandTemplate ( text )
+  if err != nil { return nil,err }
+  if !has_vars {
+    return append(args,vars.vars["file"]),nil
+  }
+  ret:= make([]string,0,len(args))
+  for _,arg:= range args {
+    if arg == "{"+_CMD_FILES_OF_ENTRY+"}" {
+      ret= append(ret,vars.files_of_entry...)
+    } else {
+      ret= append(ret,_CMD_PLACEHOLDER.ReplaceAllStringFunc ( arg,
+        func(m string) string {
+          return vars.vars[m[1:len(m)-1]]
+        }))
+    }
+  }
+
+  return ret,nil
+  
+} // end expandCommandTemplate
+
+
+// Nom per defecte d'un llançador: el nom del programa.
+func getLauncherName( command string ) string {
+
+  args,err:= splitCommandTemplate ( command )
+  if err != nil || len(args) == 0 { return command }
+  
+  return filepath.Base ( args[0] )
+  
+} // end getLauncherName
+
+
+// Comprova els llançadors i els converteix al format intern.
+func newLaunchers( launchers []view.Launcher ) ([]_Launcher,error) {
+
+  ret:= make([]_Launcher,0,len(launchers))
+  names:= make(map[string]bool)
+  for _,l:= range launchers {
+    name:= strings.TrimSpace ( l.Name )
+    command:= strings.TrimSpace ( l.Command )
+    if command == "" {
+      return nil,fmt.Errorf ( "El llançador '%s' no té comandament", name )
+    }
+    if name == "" {
+      name= getLauncherName ( command )
+    }
+    if names[name] {
+      return nil,fmt.Errorf ( "Llançador repetit: '%s'", name )
+    }
+    if _,_,err:= parseCommandTemplate ( command ); err != nil {
+      return nil,fmt.Errorf ( "%s: %s", name, err )
+    }
+    names[name]= true
+    ret= append(ret,_Launcher{name,command})
+  }
+
+  return ret,nil
+  
+} // end newLaunchers
+
+
+// Cita un argument perquè es llija com un únic argument en una
+// plantilla.
+func quoteCommandArg( arg string ) string {
+
+  if arg != "" && !strings.ContainsAny ( arg, " \t\n'\"\\{}" ) {
+    return arg
+  }
+
+  return "'"+strings.ReplaceAll ( arg, "'", `'\''` )+"'"
+  
+} // end quoteCommandArg
+
+
+// Descarta els llançadors amb plantilles no vàlides.
+func checkLaunchers( launchers _Launchers ) _Launchers {
+
+  ret:= make(_Launchers)
+  for tid,ls:= range launchers {
+    for _,l:= range ls {
+      if _,_,err:= parseCommandTemplate ( l.Command ); err != nil {
+        log.Printf ( "S'ha descartat el comandament '%s': %s\n",
+          l.Command, err )
+      } else {
+        ret[tid]= append(ret[tid],l)
+      }
+    }
+  }
+
+  return ret
+  
+} // end checkLaunchers
+
+
+func (self *Commands) reset() {
+
+  self.v= make(_Launchers)
+  self.by_plat= make(map[int]_Launchers)
+  self.by_entry= make(map[int64]_Launchers)
+  self.last= make(map[int64]string)
+  
+} // end reset
+
+
+// Llig els comandaments del fitxer indicat. Si el fitxer no existeix
+// o no es pot decodificar es queda sense comandaments. Els fitxers de
+// versions anteriors es convertixen: cada comandament passa a ser
+// l'únic llançador del tipus.
+func (self *Commands) load( fn string ) {
+
+  self.reset ()
+  data,err:= os.ReadFile ( fn )
+  if err != nil { return }
+  var conf _CommandsConf
+  if err:= json.Unmarshal ( data, &conf ); err != nil {
+    log.Printf ( "S'ha produit un error al decodificar '%s': %s\n",
+      fn, err )
+    return
+  }
+  
+  // Versions 1 i 2
+  if conf.Version == 0 {
+    var old map[int]string
+    if err:= json.Unmarshal ( data, &old ); err != nil {
+      log.Printf ( "S'ha produit un error al decodificar '%s': %s\n",
+        fn, err )
+      return
+    }
+    conf.Commands= make(map[int]string)
+    for tid,cmd:= range old {
+      conf.Commands[tid]= quoteCommandArg ( cmd )
+    }
+  } else if conf.Version > _COMMANDS_VERSION {
+    log.Printf ( "Versió de '%s' no suportada: %d\n", fn, conf.Version )
+    return
+  }
+  if conf.Version < 3 {
+    conf.Launchers= make(_Launchers)
+    for tid,cmd:= range conf.Commands {
+      conf.Launchers[tid]= []_Launcher{{getLauncherName ( cmd ),cmd}}
+    }
+  }
+
+  // Versió actual
+  self.v= checkLaunchers ( conf.Launchers )
+  for id,ls:= range conf.Platforms {
+    self.by_plat[id]= checkLaunchers ( ls )
+  }
+  for id,ls:= range conf.Entries {
+    self.by_entry[id]= checkLaunchers ( ls )
+  }
+  for id,name:= range conf.Last {
+    self.last[id]= name
+  }
+  
+} // end load
+
+
+// Elimina la configuració d'una entrada esborrada perquè no l'herete
+// una entrada nova amb el mateix identificador.
+func (self *Commands) removeEntry( id int64 ) {
+  delete(self.by_entry,id)
+  delete(self.last,id)
+} // end removeEntry
+
+
+// Elimina la configuració de plataformes i entrades que ja no
+// existeixen. Cal cridar-la amb la base de dades oberta.
+func (self *Commands) prune() {
+
+  if self.entries == nil { return }
+  for id:= range self.by_plat {
+    if self.entries.plats.GetPlatform ( id ) == nil {
+      delete(self.by_plat,id)
+    }
+  }
+  exists:= func(id int64) bool {
+    _,_,_,err:= self.entries.db.GetEntry ( id )
+    return !errors.Is ( err, sql.ErrNoRows )
+  }
+  for id:= range self.by_entry {
+    if !exists ( id ) { delete(self.by_entry,id) }
+  }
+  for id:= range self.last {
+    if !exists ( id ) { delete(self.last,id) }
+  }
+  
+} // end prune
+
+
+func (self *Commands) save( fn string ) error {
+
+  // Obri fitxer
+  f,err:= os.Create ( fn )
+  if err != nil {
+    return fmt.Errorf ( "No s'ha pogut crear '%s': %s", fn, err )
+  }
+  defer f.Close ()
+  
+  // Serialitza
+  json_enc:= json.NewEncoder ( f )
+  conf:= _CommandsConf{
+    Version   : _COMMANDS_VERSION,
+    Launchers : self.v,
+    Platforms : self.by_plat,
+    Entries   : self.by_entry,
+    Last      : self.last,
+  }
+  if err:= json_enc.Encode ( &conf ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut desar el contingut en '%s': %s",
+      fn, err )
+  }
+
+  return nil
+  
+} // end save
+
+
+
+
 /****************/
 /* PART PÚBLICA */
 /****************/
 
 type Commands struct {
   
-  dirs    *Dirs
-  v       map[int]string // Mapeja identificador tipus a commandament.
-  running map[string]bool // Controla fitxers en execució
-  mu      sync.Mutex
+  dirs     *Dirs
+  entries  *Entries
+  v        _Launchers           // Llançadors de cada tipus
+  by_plat  map[int]_Launchers   // Substitueixen els del tipus
+  by_entry map[int64]_Launchers // Substitueixen els de la plataforma
+  last     map[int64]string     // Últim llançador usat en cada entrada
+  running  map[string]bool      // Controla fitxers en execució
+  mu       sync.Mutex
   
 }
 
@@ -61,17 +453,7 @@ func NewCommands( dirs *Dirs ) (*Commands,error) {
   // Intenta deserialitzar
   fn,err:= dirs.GetCommandsConfName()
   if err != nil { return nil,err }
-  f,err:= os.Open ( fn )
-  ret.v= make(map[int]string)
-  if err == nil {
-    defer f.Close ()
-    json_dec:= json.NewDecoder ( f )
-    if err:= json_dec.Decode ( &ret.v ); err != nil {
-      log.Printf ( "S'ha produit un error al decodificar '%s': %s\n",
-        fn, err )
-      ret.v= make(map[int]string) // Reset
-    }
-  }
+  ret.load ( fn )
 
   // Altres
   ret.running= make(map[string]bool)
@@ -83,59 +465,286 @@ func NewCommands( dirs *Dirs ) (*Commands,error) {
 
 func (self *Commands) Close() {
 
-  // Obri fitxer
+  self.prune ()
   fn,err:= self.dirs.GetCommandsConfName()
   if err != nil {
     log.Printf ( "Error inesperat en 'Commands': %s", err )
     return
   }
-  f,err:= os.Create ( fn )
-  if err != nil {
-    log.Printf ( "No s'ha pogut crear '%s': %s", fn, err )
-    return
+  if err:= self.save ( fn ); err != nil {
+    log.Print ( err )
   }
-  defer f.Close ()
   
-  // Serialitza
-  json_enc:= json.NewEncoder ( f )
-  if err:= json_enc.Encode ( self.v ); err != nil {
-    log.Printf ( "No s'ha pogut desar el contingut en '%s': %s", fn, err )
-    return
+} // end Close
+
+
+// Nombre de fitxers oberts amb un comandament que encara no ha acabat.
+func (self *Commands) getNumRunning() int {
+
+  self.mu.Lock ()
+  defer self.mu.Unlock ()
+
+  return len(self.running)
+  
+} // end getNumRunning
+
+
+// Enllaç del fitxer en el directori de l'entrada.
+func (self *Commands) getLinkPath( f *File ) (string,error) {
+
+  ret,err:= self.entries.getFileLinkName ( f )
+  if err != nil { return "",err }
+  if _,err:= os.Stat ( ret ); err != nil {
+    return "",fmt.Errorf ( "No s'ha trobat '%s' (es pot reparar amb fsck):"+
+      " %s", ret, err )
   }
+
+  return ret,nil
   
-} // end Close
+} // end getLinkPath
+
+
+// Camins amb què es llancen el fitxer i tots els de la seua entrada:
+// els enllaços del directori de l'entrada, que conserven el nom
+// original. Si algun està comprimit tots es preparen en el directori
+// de l'entrada de la memòria cau, perquè els fitxers que es
+// referencien entre ells (p.e. CUE/BIN) estiguen junts. El camí de
+// 'files' és intern. Els camins de tots els fitxers sols es tornen
+// amb 'all'.
+func (self *Commands) getLaunchPaths(
+  
+  f   *File,
+  all bool,
+  
+) (string,[]string,error) {
 
+  // Fitxers de l'entrada
+  e,err:= self.entries.getEntry ( f.GetEntryID () )
+  if err != nil { return "",nil,err }
+  ids:= e.GetFileIDs ()
+  files:= make([]*File,0,len(ids))
+  compressed:= false
+  for _,id:= range ids {
+    ef:= self.entries.files.Get ( id )
+    files= append(files,ef)
+    if ef.GetCompression () != "" {
+      compressed= true
+    }
+  }
 
-// Cadena buida indica que no hi ha
-func (self *Commands) GetCommand( type_id int ) string {
+  // Camins
+  ret:= ""
+  var all_paths []string
+  for _,ef:= range files {
+    if !all && !compressed && ef.GetID () != f.GetID () { continue }
+    var fn string
+    if compressed {
+      fn,err= ef.getCachedPath ()
+    } else {
+      fn,err= self.getLinkPath ( ef )
+    }
+    if err != nil { return "",nil,err }
+    if ef.GetID () == f.GetID () {
+      ret= fn
+    }
+    if all {
+      all_paths= append(all_paths,fn)
+    }
+  }
+  if ret == "" {
+    return "",nil,fmt.Errorf ( "El fitxer '%s' no està en l'entrada '%s'",
+      f.GetName (), e.GetName () )
+  }
+  
+  return ret,all_paths,nil
+  
+} // end getLaunchPaths
+
+
+// Valors de les variables per a obrir el fitxer.
+func (self *Commands) getVars(
+  
+  f              *File,
+  file_path      string,
+  files_of_entry []string,
+  
+) *_CommandVars {
+
+  ret:= _CommandVars{
+    vars : map[string]string{
+      "file" : file_path,
+      "dir"  : filepath.Dir ( file_path ),
+      "name" : f.GetName (),
+    },
+    files_of_entry : files_of_entry,
+  }
+  e,err:= self.entries.getEntry ( f.GetEntryID () )
+  if err != nil { return &ret }
+  ret.vars["entry"]= e.GetName ()
+  if plat:= self.entries.plats.GetPlatform ( e.GetPlatformID () );
+  plat != nil {
+    ret.vars["platform"]= plat.GetName ()
+  }
+  
+  return &ret
+  
+} // end getVars
+
+
+// Cal cridar-la abans d'executar cap comandament.
+func (self *Commands) setEntries( entries *Entries ) {
+  self.entries= entries
+} // end setEntries
+
+
+// Llançadors que s'apliquen al fitxer: els de l'entrada, si no en té
+// els de la plataforma i si tampoc els del tipus.
+func (self *Commands) resolve( f *File ) []_Launcher {
+
+  type_id:= f.GetTypeID ()
+  if ls:= self.by_entry[f.GetEntryID ()][type_id]; len(ls) > 0 {
+    return ls
+  }
+  if e,err:= self.entries.getEntry ( f.GetEntryID () ); err == nil {
+    if ls:= self.by_plat[e.GetPlatformID ()][type_id]; len(ls) > 0 {
+      return ls
+    }
+  }
+  
   return self.v[type_id]
-} // end GetCommand
+  
+} // end resolve
+
+
+func getLaunchers( launchers _Launchers, type_id int ) []view.Launcher {
+
+  ret:= make([]view.Launcher,0,len(launchers[type_id]))
+  for _,l:= range launchers[type_id] {
+    ret= append(ret,view.Launcher{Name:l.Name,Command:l.Command})
+  }
+
+  return ret
+  
+} // end getLaunchers
 
 
-func (self *Commands) Run( type_id int, file_path string ) error {
+// Una llista buida elimina els llançadors.
+func setLaunchers(
+
+  launchers _Launchers,
+  type_id   int,
+  ls        []view.Launcher,
+  
+) error {
+
+  tmp,err:= newLaunchers ( ls )
+  if err != nil { return err }
+  if len(tmp) == 0 {
+    delete(launchers,type_id)
+  } else {
+    launchers[type_id]= tmp
+  }
+
+  return nil
+  
+} // end setLaunchers
+
+
+// Llançadors propis d'una entrada (sense els heretats).
+func (self *Commands) GetEntryLaunchers(
+  
+  entry_id int64,
+  type_id  int,
+  
+) []view.Launcher {
+  return getLaunchers ( self.by_entry[entry_id], type_id )
+} // end GetEntryLaunchers
+
+
+// Noms dels llançadors que es poden usar per a obrir el fitxer.
+func (self *Commands) GetFileLaunchers( f *File ) []string {
+
+  ls:= self.resolve ( f )
+  ret:= make([]string,len(ls))
+  for i,l:= range ls {
+    ret[i]= l.Name
+  }
+
+  return ret
+  
+} // end GetFileLaunchers
+
+
+func (self *Commands) GetLastLauncher( entry_id int64 ) string {
+  return self.last[entry_id]
+} // end GetLastLauncher
+
+
+// Llançadors propis d'una plataforma (sense els del tipus).
+func (self *Commands) GetPlatformLaunchers(
+  
+  platform_id int,
+  type_id     int,
+  
+) []view.Launcher {
+  return getLaunchers ( self.by_plat[platform_id], type_id )
+} // end GetPlatformLaunchers
+
+
+func (self *Commands) GetTypeLaunchers( type_id int ) []view.Launcher {
+  return getLaunchers ( self.v, type_id )
+} // end GetTypeLaunchers
+
+
+// Obri el fitxer amb el llançador indicat. Amb un nom buit s'usa
+// l'últim usat en l'entrada o, si no n'hi ha, el primer.
+func (self *Commands) Run( f *File, name string ) error {
 
   // Selecciona tipus
+  type_id:= f.GetTypeID ()
   ft,err:= file_type.Get ( type_id )
   if err != nil { return err }
   
   // Obté commandament
-  cmd_name,ok:= self.v[type_id]
-  if !ok {
+  ls:= self.resolve ( f )
+  if len(ls) == 0 {
     return fmt.Errorf ( "No s'ha especificat ningun comandament" +
       " per al tipus '%s'", ft.GetName () )
   }
+  if name == "" {
+    name= self.last[f.GetEntryID ()]
+  }
+  launcher:= &ls[0]
+  for i:= range ls {
+    if ls[i].Name == name {
+      launcher= &ls[i]
+      break
+    }
+  }
+  template:= launcher.Command
+  all,err:= usesFilesOfEntry ( template )
+  if err != nil { return err }
+  file_path,files_of_entry,err:= self.getLaunchPaths ( f, all )
+  if err != nil { return err }
 
   // Comprova que no estiga ja en execució. Si ho està torna sense
   // error.
-  if _,ok:= self.running[file_path]; ok {
-    return nil
-  }
+  self.mu.Lock ()
+  _,running:= self.running[file_path]
+  self.mu.Unlock ()
+  if running { return nil }
   
   // Crea commandament
-  cmd:= exec.Command ( cmd_name, file_path )
+  args,err:= expandCommandTemplate ( template,
+    self.getVars ( f, file_path, files_of_entry ) )
+  if err != nil { return err }
+  cmd:= exec.Command ( args[0], args[1:]... )
   err= cmd.Start ()
   if err == nil {
 
+    // Recorda el llançador
+    self.last[f.GetEntryID ()]= launcher.Name
+
     // Marca com en execució
     self.mu.Lock ()
     self.running[file_path]= true
@@ -156,14 +765,79 @@ func (self *Commands) Run( type_id int, file_path string ) error {
 } // end Run
 
 
-// Una cadena buida indica que no hi ha commandament
-func (self *Commands) SetCommand( type_id int, command string ) {
+// Una llista buida fa que s'usen els de la plataforma o el tipus.
+func (self *Commands) SetEntryLaunchers(
 
-  command= strings.TrimSpace ( command )
-  if command == "" {
-    delete(self.v,type_id)
-  } else {
-    self.v[type_id]= command
+  entry_id  int64,
+  type_id   int,
+  launchers []view.Launcher,
+  
+) error {
+
+  ls,ok:= self.by_entry[entry_id]
+  if !ok {
+    ls= make(_Launchers)
+    self.by_entry[entry_id]= ls
+  }
+  if err:= setLaunchers ( ls, type_id, launchers ); err != nil {
+    return err
+  }
+  if len(ls) == 0 {
+    delete(self.by_entry,entry_id)
+  }
+
+  return nil
+  
+} // end SetEntryLaunchers
+
+
+// Una llista buida fa que s'usen els del tipus.
+func (self *Commands) SetPlatformLaunchers(
+
+  platform_id int,
+  type_id     int,
+  launchers   []view.Launcher,
+  
+) error {
+
+  ls,ok:= self.by_plat[platform_id]
+  if !ok {
+    ls= make(_Launchers)
+    self.by_plat[platform_id]= ls
+  }
+  if err:= setLaunchers ( ls, type_id, launchers ); err != nil {
+    return err
   }
+  if len(ls) == 0 {
+    delete(self.by_plat,platform_id)
+  }
+
+  return nil
+  
+} // end SetPlatformLaunchers
+
+
+// Una llista buida elimina els llançadors del tipus.
+func (self *Commands) SetTypeLaunchers(
+
+  type_id   int,
+  launchers []view.Launcher,
+  
+) error {
+  return setLaunchers ( self.v, type_id, launchers )
+} // end SetTypeLaunchers
+
+
+// Comprova una plantilla de comandament. Els arguments se separen com
+// en la shell i poden contindre les variables {file}, {dir}, {entry},
+// {platform}, {name} i {files_of_entry} (tots els fitxers de
+// l'entrada, com a arguments separats). Si no en té cap el fitxer
+// s'afegeix al final. Una cadena buida és vàlida (sense comandament).
+func CheckCommand( command string ) error {
+
+  if strings.TrimSpace ( command ) == "" { return nil }
+  _,_,err:= parseCommandTemplate ( command )
+  
+  return err
   
-} // end SetCommand
+} // end CheckCommand
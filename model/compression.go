@@ -0,0 +1,445 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  compression.go - Compressió dels fitxers desats en 'files'. Els
+ *                   hashes i les metadades sempre es calculen sobre
+ *                   el contingut sense comprimir.
+ */
+
+package model
+
+import (
+  "compress/gzip"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "sort"
+
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Directori de la memòria cau amb les còpies sense comprimir dels
+// fitxers que s'obrin.
+const _CACHE_UNCOMPRESSED= "uncompressed"
+
+
+type _Codec struct {
+  ext        string // S'afegeix al nom del fitxer
+  new_reader func(r io.Reader) (io.ReadCloser,error)
+  new_writer func(w io.Writer) (io.WriteCloser,error)
+}
+
+
+var _CODECS= map[string]*_Codec{
+  "gzip" : &_Codec{
+    ext        : ".gz",
+    new_reader : func(r io.Reader) (io.ReadCloser,error) {
+      return gzip.NewReader ( r )
+    },
+    new_writer : func(w io.Writer) (io.WriteCloser,error) {
+      return gzip.NewWriterLevel ( w, gzip.BestCompression )
+    },
+  },
+}
+
+
+func getCodec( compression string ) (*_Codec,error) {
+
+  ret,ok:= _CODECS[compression]
+  if !ok {
+    return nil,fmt.Errorf ( "Compressió desconeguda: '%s'", compression )
+  }
+
+  return ret,nil
+  
+} // end getCodec
+
+
+// Nom del fitxer comprimit (p.e. "smb.nes.gz").
+func compressedName( name string, compression string ) string {
+
+  if c,ok:= _CODECS[compression]; ok { return name+c.ext }
+
+  return name
+  
+} // end compressedName
+
+
+// Lector del contingut sense comprimir d'un fitxer de 'files'.
+type _StoreReader struct {
+  io.Reader
+  fd *os.File
+  cr io.ReadCloser
+}
+
+
+func (self *_StoreReader) Close() error {
+
+  if self.cr != nil { self.cr.Close () }
+
+  return self.fd.Close ()
+  
+} // end Close
+
+
+func openStoreFile( fn string, compression string ) (io.ReadCloser,error) {
+
+  fd,err:= os.Open ( fn )
+  if err != nil { return nil,err }
+  if compression == "" {
+    return &_StoreReader{Reader : fd, fd : fd},nil
+  }
+  codec,err:= getCodec ( compression )
+  if err == nil {
+    var cr io.ReadCloser
+    if cr,err= codec.new_reader ( fd ); err == nil {
+      return &_StoreReader{Reader : cr, fd : fd, cr : cr},nil
+    }
+  }
+  fd.Close ()
+
+  return nil,err
+  
+} // end openStoreFile
+
+
+// Escriu en 'dst' el contingut sense comprimir de 'src'.
+func uncompressFile( src string, dst string, compression string ) error {
+
+  r,err:= openStoreFile ( src, compression )
+  if err != nil { return err }
+  defer r.Close ()
+  tmp,err:= os.CreateTemp ( filepath.Dir ( dst ), ".uncompress-*" )
+  if err != nil { return err }
+  defer os.Remove ( tmp.Name () )
+  if _,err:= io.Copy ( tmp, r ); err != nil {
+    tmp.Close ()
+    return err
+  }
+  if err:= tmp.Close (); err != nil { return err }
+  if err:= os.Chmod ( tmp.Name (), 0400 ); err != nil { return err }
+
+  return os.Rename ( tmp.Name (), dst )
+  
+} // end uncompressFile
+
+
+// Escriu en 'dst' el contingut de 'src' amb la compressió
+// 'f.Compression' i comprova que coincideix amb els hashes.
+func recodeFile(
+
+  src             string,
+  src_compression string,
+  dst             string,
+  f               *_BackupFile,
+
+) (err error) {
+
+  // Obri
+  r,err:= openStoreFile ( src, src_compression )
+  if err != nil { return err }
+  defer r.Close ()
+  fd,err:= os.OpenFile ( dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600 )
+  if err != nil { return err }
+  defer func() {
+    if err != nil { os.Remove ( dst ) }
+  }()
+
+  // Escriu
+  var w io.WriteCloser= fd
+  if f.Compression != "" {
+    codec,err:= getCodec ( f.Compression )
+    if err != nil { fd.Close (); return err }
+    if w,err= codec.new_writer ( fd ); err != nil { fd.Close (); return err }
+  }
+  _,err= io.Copy ( w, r )
+  if w != fd {
+    if err2:= w.Close (); err == nil { err= err2 }
+  }
+  if err == nil { err= fd.Sync () }
+  if err2:= fd.Close (); err == nil { err= err2 }
+  if err != nil { return err }
+
+  // Comprova
+  fd,err= os.Open ( dst )
+  if err != nil { return err }
+  err= copyAndCheck ( io.Discard, fd, f )
+  fd.Close ()
+  if err != nil { return err }
+
+  return os.Chmod ( dst, 0400 )
+  
+} // end recodeFile
+
+
+// Canvia la compressió d'un fitxer. Primer es crea el fitxer nou i
+// després s'actualitza la base de dades i l'enllaç de l'entrada.
+func (self *Files) changeCompression(
+
+  f           *_BackupFile,
+  compression string,
+
+) error {
+
+  // Noms
+  ft,err:= file_type.Get ( f.file_type )
+  if err != nil { return err }
+  old_fname,err:= self.dirs.GetDataFileName ( f.Path )
+  if err != nil { return err }
+  old_ename,err:= self.dirs.GetDataFileName ( f.Links[0] )
+  if err != nil { return err }
+  new_fname,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), f.name,
+    f.SHA1, compression )
+  if err != nil { return err }
+  new_ename,err:= self.dirs.GetFileNameEntries ( f.platform, f.entry,
+    compressedName ( f.name, compression ) )
+  if err != nil { return err }
+
+  // Quan es desen per SHA1 el fitxer antic pot estar compartit i el
+  // nou pot existir ja.
+  shared,exists:= false,false
+  if self.dirs.GetStore () == STORE_SHA1 {
+    n,err:= self.db.GetNumFilesWithSHA1 ( f.SHA1, f.Compression )
+    if err != nil { return err }
+    shared= n > 1
+    _,err= os.Stat ( new_fname )
+    exists= err == nil
+  }
+
+  // Crea fitxer nou
+  nf:= *f
+  nf.Compression= compression
+  if !exists {
+    if err:= recodeFile ( old_fname, f.Compression, new_fname, &nf );
+    err != nil {
+      return fmt.Errorf ( "No s'ha pogut canviar la compressió de '%s': %s",
+        f.Path, err )
+    }
+  }
+  undo:= func() {
+    if !exists { os.Remove ( new_fname ) }
+  }
+
+  // Base de dades i enllaç
+  if err:= self.db.UpdateFileCompressionWithoutCommit ( f.ID, compression );
+  err != nil {
+    undo ()
+    return err
+  }
+  if err:= linkFile ( new_fname, new_ename ); err != nil {
+    self.db.RollbackLastTransaction ()
+    undo ()
+    return err
+  }
+  if err:= self.db.CommitLastTransaction (); err != nil {
+    os.Remove ( new_ename )
+    undo ()
+    return err
+  }
+  delete(self.v,f.ID)
+
+  // Elimina els antics
+  if err:= os.Remove ( old_ename ); err != nil { return err }
+  if !shared {
+    if err:= os.Remove ( old_fname ); err != nil { return err }
+  }
+
+  return nil
+  
+} // end changeCompression
+
+
+// Elimina les còpies sense comprimir de la memòria cau. Si hi ha
+// comandaments en execució les deixa, poden estar usant-les.
+func (self *Files) cleanUncompressed() {
+
+  if self.cmds.getNumRunning () > 0 { return }
+  dir,err:= self.dirs.GetCachedFileName ( _CACHE_UNCOMPRESSED )
+  if err != nil { return }
+  os.RemoveAll ( dir )
+  
+} // end cleanUncompressed
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Compressions suportades.
+func GetCompressions() []string {
+
+  ret:= make([]string,0,len(_CODECS))
+  for name:= range _CODECS {
+    ret= append(ret,name)
+  }
+  sort.Strings ( ret )
+
+  return ret
+  
+} // end GetCompressions
+
+
+// Compressió dels fitxers nous del tipus indicat. Buit si no es
+// comprimeixen.
+func (self *Model) GetCompression( type_id int ) (string,error) {
+
+  if _,err:= file_type.Get ( type_id ); err != nil { return "",err }
+
+  return self.db.GetCompression ( type_id )
+  
+} // end GetCompression
+
+
+// Canvia la compressió dels fitxers nous del tipus indicat. Els
+// fitxers que ja estan en la biblioteca no canvien fins que es crida
+// a ApplyCompression.
+func (self *Model) SetCompression( type_id int, compression string ) error {
+
+  if _,err:= file_type.Get ( type_id ); err != nil { return err }
+  if compression != "" {
+    if _,err:= getCodec ( compression ); err != nil { return err }
+  }
+
+  return self.db.SetCompression ( type_id, compression )
+  
+} // end SetCompression
+
+
+// Espai ocupat pels fitxers d'un tipus.
+type CompressionStats struct {
+  type_id     int
+  compression string // Política per als fitxers nous
+  num_files   int
+  num_comp    int    // Fitxers comprimits
+  size        int64  // Sense comprimir
+  stored_size int64  // En disc
+}
+
+func (self *CompressionStats) GetTypeID() int { return self.type_id }
+func (self *CompressionStats) GetCompression() string {
+  return self.compression
+} // end GetCompression
+func (self *CompressionStats) GetNumFiles() int { return self.num_files }
+func (self *CompressionStats) GetNumCompressed() int { return self.num_comp }
+func (self *CompressionStats) GetSize() int64 { return self.size }
+func (self *CompressionStats) GetStoredSize() int64 {
+  return self.stored_size
+} // end GetStoredSize
+func (self *CompressionStats) GetSaved() int64 {
+  return self.size-self.stored_size
+} // end GetSaved
+
+
+// Torna l'espai que ocupen els fitxers de cada tipus abans i després
+// de comprimir-los. Els fitxers que comparteixen el mateix fitxer en
+// 'files' sols es compten una vegada.
+func (self *Model) GetCompressionReport() ([]CompressionStats,error) {
+
+  files,err:= loadBackupFiles ( self.db.conn )
+  if err != nil { return nil,err }
+  if err:= setBackupPaths ( files, self.dirs.GetStore () ); err != nil {
+    return nil,err
+  }
+  stats:= make(map[int]*CompressionStats)
+  seen:= make(map[string]bool)
+  for i:= range files {
+    f:= &files[i]
+    s,ok:= stats[f.file_type]
+    if !ok {
+      s= &CompressionStats{type_id : f.file_type}
+      if s.compression,err= self.GetCompression ( f.file_type ); err != nil {
+        return nil,err
+      }
+      stats[f.file_type]= s
+    }
+    s.num_files++
+    if f.Compression != "" { s.num_comp++ }
+    if seen[f.Path] { continue }
+    seen[f.Path]= true
+    fn,err:= self.dirs.GetDataFileName ( f.Path )
+    if err != nil { return nil,err }
+    info,err:= os.Stat ( fn )
+    if errors.Is ( err, os.ErrNotExist ) { continue }
+    if err != nil { return nil,err }
+    s.size+= f.Size
+    s.stored_size+= info.Size ()
+  }
+  ret:= make([]CompressionStats,0,len(stats))
+  for _,s:= range stats {
+    ret= append(ret,*s)
+  }
+  sort.Slice ( ret, func(i,j int) bool {
+    return ret[i].type_id < ret[j].type_id
+  })
+
+  return ret,nil
+  
+} // end GetCompressionReport
+
+
+// Comprimeix o descomprimeix els fitxers de la biblioteca perquè
+// seguisquen la compressió configurada per al seu tipus. Torna el
+// nombre de fitxers canviats.
+func (self *Model) ApplyCompression(
+
+  create_pb func() view.ProgressBar,
+
+) (int,error) {
+
+  // Prepara
+  if self.files.StopBackfill () {
+    defer self.files.StartBackfill ()
+  }
+  files,err:= loadBackupFiles ( self.db.conn )
+  if err != nil { return 0,err }
+  if err:= setBackupPaths ( files, self.dirs.GetStore () ); err != nil {
+    return 0,err
+  }
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Canvia
+  n:= 0
+  for i:= range files {
+    f:= &files[i]
+    pb.Set ( f.Path, float32(i)/float32(len(files)) )
+    compression,err:= self.GetCompression ( f.file_type )
+    if err != nil { return n,err }
+    if compression == f.Compression { continue }
+    if err:= self.files.changeCompression ( f, compression ); err != nil {
+      return n,err
+    }
+    n++
+  }
+
+  return n,nil
+  
+} // end ApplyCompression
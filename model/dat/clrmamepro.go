@@ -0,0 +1,338 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  clrmamepro.go - Format de text de ClrMamePro.
+ *
+ *  Exemple:
+ *
+ *    clrmamepro (
+ *      name "Nintendo - NES"
+ *      header "No-Intro_NES.xml"
+ *    )
+ *    game (
+ *      name "Joc (E)"
+ *      rom ( name "Joc (E).nes" size 40976 crc 3337ec46 md5 ... sha1 ... )
+ *    )
+ */
+
+package dat
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "strings"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+const (
+  _CMP_TOKEN_EOF   = 0
+  _CMP_TOKEN_OPEN  = 1
+  _CMP_TOKEN_CLOSE = 2
+  _CMP_TOKEN_WORD  = 3
+)
+
+
+// Camps que no tenen valor (p.e. 'rom ( name x baddump crc 1234 )').
+var _CMP_FLAGS= map[string]bool{
+  ROM_STATUS_BADDUMP  : true,
+  ROM_STATUS_NODUMP   : true,
+  ROM_STATUS_VERIFIED : true,
+}
+
+
+type _CMP_Lexer struct {
+  r    *bufio.Reader
+  line int
+}
+
+
+// Camp d'un bloc. Si el valor és un bloc 'Block' no és nil.
+type _CMP_Field struct {
+  Key   string
+  Value string
+  Block []_CMP_Field
+}
+
+
+func (self *_CMP_Lexer) error( msg string, args ...any ) error {
+  return fmt.Errorf ( "Línia %d: %s", self.line, fmt.Sprintf ( msg, args... ) )
+} // end error
+
+
+func (self *_CMP_Lexer) next() (int,string,error) {
+
+  // Bota espais
+  var c byte
+  var err error
+  for {
+    c,err= self.r.ReadByte ()
+    if err == io.EOF {
+      return _CMP_TOKEN_EOF,"",nil
+    } else if err != nil {
+      return _CMP_TOKEN_EOF,"",err
+    }
+    if c == '\n' {
+      self.line++
+    } else if c != ' ' && c != '\t' && c != '\r' {
+      break
+    }
+  }
+
+  // Token
+  switch c {
+  case '(':
+    return _CMP_TOKEN_OPEN,"(",nil
+  case ')':
+    return _CMP_TOKEN_CLOSE,")",nil
+  case '"': // Cadena (sense seqüències d'escapament)
+    var b strings.Builder
+    for {
+      c,err= self.r.ReadByte ()
+      if err == io.EOF {
+        return _CMP_TOKEN_EOF,"",self.error ( "cadena sense tancar" )
+      } else if err != nil {
+        return _CMP_TOKEN_EOF,"",err
+      }
+      if c == '"' { break }
+      if c == '\n' { self.line++ }
+      b.WriteByte ( c )
+    }
+    return _CMP_TOKEN_WORD,b.String (),nil
+  default: // Paraula
+    var b strings.Builder
+    b.WriteByte ( c )
+    for {
+      c,err= self.r.ReadByte ()
+      if err == io.EOF {
+        break
+      } else if err != nil {
+        return _CMP_TOKEN_EOF,"",err
+      }
+      if c == ' ' || c == '\t' || c == '\r' || c == '\n' ||
+        c == '(' || c == ')' {
+        self.r.UnreadByte ()
+        break
+      }
+      b.WriteByte ( c )
+    }
+    return _CMP_TOKEN_WORD,b.String (),nil
+  }
+
+} // end next
+
+
+// Llig els camps d'un bloc fins al parèntesi de tancament.
+func (self *_CMP_Lexer) readBlock() ([]_CMP_Field,error) {
+
+  ret:= make([]_CMP_Field,0)
+  for {
+
+    // Clau
+    tok,key,err:= self.next ()
+    if err != nil { return nil,err }
+    switch tok {
+    case _CMP_TOKEN_CLOSE:
+      return ret,nil
+    case _CMP_TOKEN_EOF:
+      return nil,self.error ( "bloc sense tancar" )
+    case _CMP_TOKEN_OPEN:
+      return nil,self.error ( "s'esperava un nom de camp" )
+    }
+    if _CMP_FLAGS[strings.ToLower ( key )] {
+      ret= append(ret,_CMP_Field{Key:key})
+      continue
+    }
+
+    // Valor
+    tok,val,err:= self.next ()
+    if err != nil { return nil,err }
+    switch tok {
+    case _CMP_TOKEN_OPEN:
+      block,err:= self.readBlock ()
+      if err != nil { return nil,err }
+      ret= append(ret,_CMP_Field{Key:key,Block:block})
+    case _CMP_TOKEN_WORD:
+      ret= append(ret,_CMP_Field{Key:key,Value:val})
+    case _CMP_TOKEN_CLOSE: // Camp sense valor (p.e. 'baddump')
+      ret= append(ret,_CMP_Field{Key:key})
+      return ret,nil
+    default:
+      return nil,self.error ( "bloc sense tancar" )
+    }
+
+  }
+
+} // end readBlock
+
+
+func cmpToRom( fields []_CMP_Field ) (Rom,error) {
+
+  ret:= Rom{}
+  for _,f:= range fields {
+    switch strings.ToLower ( f.Key ) {
+    case "name":
+      ret.Name= f.Value
+    case "size":
+      size,err:= parseSize ( f.Value )
+      if err != nil {
+        return ret,fmt.Errorf ( "Grandària no vàlida per a '%s': %s",
+          ret.Name, f.Value )
+      }
+      ret.Size= size
+    case "crc":
+      crc,err:= normCRC ( f.Value )
+      if err != nil {
+        return ret,fmt.Errorf ( "CRC no vàlid per a '%s': %s",
+          ret.Name, f.Value )
+      }
+      ret.CRC= crc
+    case "md5":
+      ret.MD5= normHash ( f.Value )
+    case "sha1":
+      ret.SHA1= normHash ( f.Value )
+    case "merge":
+      ret.Merge= f.Value
+    case "flags","status":
+      ret.Status= strings.ToLower ( f.Value )
+    case ROM_STATUS_BADDUMP,ROM_STATUS_NODUMP,ROM_STATUS_VERIFIED:
+      ret.Status= strings.ToLower ( f.Key )
+    }
+  }
+
+  return ret,nil
+
+} // end cmpToRom
+
+
+func cmpToGame( fields []_CMP_Field, resource bool ) (Game,error) {
+
+  ret:= Game{
+    Roms     : make([]Rom,0),
+    Resource : resource,
+  }
+  for _,f:= range fields {
+    switch strings.ToLower ( f.Key ) {
+    case "name":
+      ret.Name= f.Value
+    case "description":
+      ret.Description= strings.TrimSpace ( f.Value )
+    case "cloneof":
+      ret.CloneOf= f.Value
+    case "romof":
+      ret.RomOf= f.Value
+    case "rom","disk":
+      if f.Block == nil { continue }
+      rom,err:= cmpToRom ( f.Block )
+      if err != nil { return ret,err }
+      ret.Roms= append(ret.Roms,rom)
+    }
+  }
+  if ret.Name == "" {
+    return ret,errors.New ( "Joc del DAT sense nom" )
+  }
+
+  return ret,nil
+
+} // end cmpToGame
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Llig un fitxer en format de text de ClrMamePro. Els blocs
+// 'resource' (BIOS i altres fitxers compartits) es tracten com a jocs
+// marcats com a recurs.
+func ReadClrMamePro( r io.Reader ) (*Dat,error) {
+
+  ret:= Dat{
+    Format : FORMAT_CLRMAMEPRO,
+    Games  : make([]Game,0),
+  }
+  lex:= _CMP_Lexer{
+    r    : bufio.NewReader ( r ),
+    line : 1,
+  }
+  for {
+
+    // Nom del bloc
+    tok,key,err:= lex.next ()
+    if err != nil {
+      return nil,fmt.Errorf ( "Error al llegir el DAT: %s", err )
+    } else if tok == _CMP_TOKEN_EOF {
+      break
+    } else if tok != _CMP_TOKEN_WORD {
+      return nil,lex.error ( "s'esperava un nom de bloc" )
+    }
+    tok,_,err= lex.next ()
+    if err != nil {
+      return nil,fmt.Errorf ( "Error al llegir el DAT: %s", err )
+    } else if tok != _CMP_TOKEN_OPEN {
+      return nil,lex.error ( "s'esperava '(' després de '%s'", key )
+    }
+    fields,err:= lex.readBlock ()
+    if err != nil { return nil,err }
+
+    // Processa
+    switch strings.ToLower ( key ) {
+    case "clrmamepro":
+      for _,f:= range fields {
+        switch strings.ToLower ( f.Key ) {
+        case "name":
+          ret.Name= strings.TrimSpace ( f.Value )
+        case "description":
+          ret.Description= strings.TrimSpace ( f.Value )
+        case "version":
+          ret.Version= strings.TrimSpace ( f.Value )
+        case "author":
+          ret.Author= strings.TrimSpace ( f.Value )
+        case "homepage":
+          ret.Homepage= strings.TrimSpace ( f.Value )
+        case "url":
+          ret.URL= strings.TrimSpace ( f.Value )
+        case "header":
+          ret.Header= strings.TrimSpace ( f.Value )
+        }
+      }
+
+    case "game","machine","resource":
+      game,err:= cmpToGame ( fields, strings.ToLower ( key ) == "resource" )
+      if err != nil { return nil,err }
+      ret.Games= append(ret.Games,game)
+
+    }
+
+  }
+  if ret.Name == "" {
+    return nil,errors.New ( "El DAT no té nom" )
+  }
+
+  return &ret,nil
+
+} // end ReadClrMamePro
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  logiqx.go - Format XML de Logiqx (No-Intro, Redump, MAME, etc.).
+ */
+
+package dat
+
+import (
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "io"
+  "strings"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _Logiqx_Header struct {
+  Name        string `xml:"name"`
+  Description string `xml:"description"`
+  Version     string `xml:"version"`
+  Author      string `xml:"author"`
+  Homepage    string `xml:"homepage"`
+  URL         string `xml:"url"`
+  ClrMamePro  struct {
+    Header string `xml:"header,attr"`
+  } `xml:"clrmamepro"`
+}
+
+
+type _Logiqx_Rom struct {
+  Name   string `xml:"name,attr"`
+  Size   string `xml:"size,attr"`
+  CRC    string `xml:"crc,attr"`
+  MD5    string `xml:"md5,attr"`
+  SHA1   string `xml:"sha1,attr"`
+  Status string `xml:"status,attr"`
+  Merge  string `xml:"merge,attr"`
+}
+
+
+// Estructures per a escriure.
+type _Logiqx_OutHeader struct {
+  Name        string `xml:"name"`
+  Description string `xml:"description"`
+  Version     string `xml:"version,omitempty"`
+  Author      string `xml:"author,omitempty"`
+  Homepage    string `xml:"homepage,omitempty"`
+  URL         string `xml:"url,omitempty"`
+}
+
+
+type _Logiqx_OutRom struct {
+  Name   string `xml:"name,attr"`
+  Size   int64  `xml:"size,attr"`
+  CRC    string `xml:"crc,attr,omitempty"`
+  MD5    string `xml:"md5,attr,omitempty"`
+  SHA1   string `xml:"sha1,attr,omitempty"`
+  Status string `xml:"status,attr,omitempty"`
+}
+
+
+type _Logiqx_OutGame struct {
+  Name        string           `xml:"name,attr"`
+  IsBios      string           `xml:"isbios,attr,omitempty"`
+  CloneOf     string           `xml:"cloneof,attr,omitempty"`
+  RomOf       string           `xml:"romof,attr,omitempty"`
+  Description string           `xml:"description"`
+  Roms        []_Logiqx_OutRom `xml:"rom"`
+}
+
+
+// Serveix tant per a 'game' com per a 'machine' (MAME).
+type _Logiqx_Game struct {
+  Name        string        `xml:"name,attr"`
+  CloneOf     string        `xml:"cloneof,attr"`
+  RomOf       string        `xml:"romof,attr"`
+  IsBios      string        `xml:"isbios,attr"`
+  Description string        `xml:"description"`
+  Roms        []_Logiqx_Rom `xml:"rom"`
+  Disks       []_Logiqx_Rom `xml:"disk"`
+}
+
+
+func (self *_Logiqx_Rom) toRom() (Rom,error) {
+
+  ret:= Rom{
+    Name   : self.Name,
+    MD5    : normHash ( self.MD5 ),
+    SHA1   : normHash ( self.SHA1 ),
+    Status : strings.ToLower ( strings.TrimSpace ( self.Status ) ),
+    Merge  : self.Merge,
+  }
+  crc,err:= normCRC ( self.CRC )
+  if err != nil {
+    return ret,fmt.Errorf ( "CRC no vàlid per a '%s': %s", self.Name,
+      self.CRC )
+  }
+  ret.CRC= crc
+  if self.Size != "" {
+    size,err:= parseSize ( self.Size )
+    if err != nil {
+      return ret,fmt.Errorf ( "Grandària no vàlida per a '%s': %s",
+        self.Name, self.Size )
+    }
+    ret.Size= size
+  }
+
+  return ret,nil
+
+} // end toRom
+
+
+func (self *_Logiqx_Game) toGame() (Game,error) {
+
+  ret:= Game{
+    Name        : self.Name,
+    Description : strings.TrimSpace ( self.Description ),
+    CloneOf     : self.CloneOf,
+    RomOf       : self.RomOf,
+    Resource    : self.IsBios == "yes",
+    Roms        : make([]Rom,0,len(self.Roms)+len(self.Disks)),
+  }
+  for i:= range self.Roms {
+    rom,err:= self.Roms[i].toRom ()
+    if err != nil { return ret,err }
+    ret.Roms= append(ret.Roms,rom)
+  }
+  for i:= range self.Disks {
+    rom,err:= self.Disks[i].toRom ()
+    if err != nil { return ret,err }
+    ret.Roms= append(ret.Roms,rom)
+  }
+
+  return ret,nil
+
+} // end toGame
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+const _LOGIQX_DOCTYPE= `<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/dtds/datafile.dtd">`
+
+// Llig un fitxer en format Logiqx XML. Es processa joc a joc per a no
+// haver de carregar tot l'arbre XML en memòria.
+func ReadLogiqx( r io.Reader ) (*Dat,error) {
+
+  ret:= Dat{
+    Format : FORMAT_LOGIQX,
+    Games  : make([]Game,0),
+  }
+  dec:= xml.NewDecoder ( r )
+  root_found:= false
+  for {
+
+    tok,err:= dec.Token ()
+    if err == io.EOF {
+      break
+    } else if err != nil {
+      return nil,fmt.Errorf ( "Error al llegir el DAT en format XML: %s", err )
+    }
+
+    se,ok:= tok.(xml.StartElement)
+    if !ok { continue }
+    switch se.Name.Local {
+    case "datafile":
+      root_found= true
+
+    case "header":
+      header:= _Logiqx_Header{}
+      if err:= dec.DecodeElement ( &header, &se ); err != nil {
+        return nil,fmt.Errorf ( "Capçalera del DAT no vàlida: %s", err )
+      }
+      ret.Name= strings.TrimSpace ( header.Name )
+      ret.Description= strings.TrimSpace ( header.Description )
+      ret.Version= strings.TrimSpace ( header.Version )
+      ret.Author= strings.TrimSpace ( header.Author )
+      ret.Homepage= strings.TrimSpace ( header.Homepage )
+      ret.URL= strings.TrimSpace ( header.URL )
+      ret.Header= strings.TrimSpace ( header.ClrMamePro.Header )
+
+    case "game","machine":
+      lgame:= _Logiqx_Game{}
+      if err:= dec.DecodeElement ( &lgame, &se ); err != nil {
+        return nil,fmt.Errorf ( "Joc del DAT no vàlid: %s", err )
+      }
+      game,err:= lgame.toGame ()
+      if err != nil { return nil,err }
+      ret.Games= append(ret.Games,game)
+
+    }
+
+  }
+  if !root_found {
+    return nil,errors.New ( "El fitxer no és un DAT en format Logiqx XML" )
+  }
+  if ret.Name == "" {
+    return nil,errors.New ( "El DAT no té nom" )
+  }
+
+  return &ret,nil
+
+} // end ReadLogiqx
+
+
+// Escriu el DAT en format Logiqx XML.
+func WriteLogiqx( w io.Writer, d *Dat ) error {
+
+  // Capçalera
+  if _,err:= io.WriteString ( w, xml.Header+_LOGIQX_DOCTYPE+"\n" );
+  err != nil {
+    return err
+  }
+  enc:= xml.NewEncoder ( w )
+  enc.Indent ( "", "\t" )
+  root:= xml.StartElement{Name:xml.Name{Local:"datafile"}}
+  if err:= enc.EncodeToken ( root ); err != nil { return err }
+  header:= _Logiqx_OutHeader{
+    Name        : d.Name,
+    Description : d.Description,
+    Version     : d.Version,
+    Author      : d.Author,
+    Homepage    : d.Homepage,
+    URL         : d.URL,
+  }
+  if err:= enc.EncodeElement ( &header,
+    xml.StartElement{Name:xml.Name{Local:"header"}} ); err != nil {
+    return err
+  }
+
+  // Jocs
+  for i:= range d.Games {
+    game:= &d.Games[i]
+    out:= _Logiqx_OutGame{
+      Name        : game.Name,
+      CloneOf     : game.CloneOf,
+      RomOf       : game.RomOf,
+      Description : game.Description,
+      Roms        : make([]_Logiqx_OutRom,0,len(game.Roms)),
+    }
+    if game.Resource {
+      out.IsBios= "yes"
+    }
+    for j:= range game.Roms {
+      rom:= &game.Roms[j]
+      out.Roms= append(out.Roms,_Logiqx_OutRom{
+        Name   : rom.Name,
+        Size   : rom.Size,
+        CRC    : rom.CRC,
+        MD5    : rom.MD5,
+        SHA1   : rom.SHA1,
+        Status : rom.Status,
+      })
+    }
+    if err:= enc.EncodeElement ( &out,
+      xml.StartElement{Name:xml.Name{Local:"game"}} ); err != nil {
+      return err
+    }
+  }
+
+  // Tanca
+  if err:= enc.EncodeToken ( root.End () ); err != nil { return err }
+  if err:= enc.Flush (); err != nil { return err }
+  _,err:= io.WriteString ( w, "\n" )
+
+  return err
+
+} // end WriteLogiqx
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  main.go - Fitxers DAT amb la descripció de referència de jocs i
+ *            ROMs (No-Intro, Redump, etc.).
+ */
+
+package dat
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "os"
+  "strconv"
+  "strings"
+)
+
+
+
+
+/*************/
+/* CONSTANTS */
+/*************/
+
+const FORMAT_LOGIQX     = "logiqx"
+const FORMAT_CLRMAMEPRO = "clrmamepro"
+
+// Valors de Rom.Status
+const ROM_STATUS_GOOD     = ""
+const ROM_STATUS_BADDUMP  = "baddump"
+const ROM_STATUS_NODUMP   = "nodump"
+const ROM_STATUS_VERIFIED = "verified"
+
+
+
+
+/*********/
+/* TIPUS */
+/*********/
+
+type Rom struct {
+
+  Name   string
+  Size   int64
+  CRC    string // Sempre en minúscules
+  MD5    string // Sempre en minúscules
+  SHA1   string // Sempre en minúscules
+  Status string
+  Merge  string
+
+}
+
+
+type Game struct {
+
+  Name        string
+  Description string
+  CloneOf     string
+  RomOf       string
+  Resource    bool // BIOS o fitxers compartits, no és un joc
+  Roms        []Rom
+
+}
+
+
+type Dat struct {
+
+  Format      string
+  Name        string
+  Description string
+  Version     string
+  Author      string
+  Homepage    string
+  URL         string
+  Header      string // Fitxer de regles per a botar capçaleres
+  Games       []Game
+
+}
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func normHash( hash string ) string {
+  return strings.ToLower ( strings.TrimSpace ( hash ) )
+} // end normHash
+
+
+// Els CRC són hexadecimals; es tornen amb 8 xifres (alguns DATs
+// ometen els zeros de l'esquerra).
+func normCRC( crc string ) (string,error) {
+
+  crc= strings.TrimSpace ( crc )
+  if crc == "" { return "",nil }
+  v,err:= strconv.ParseUint ( crc, 16, 32 )
+  if err != nil { return "",err }
+
+  return fmt.Sprintf ( "%08x", v ),nil
+  
+} // end normCRC
+
+
+// Les grandàries són sempre decimals (p.e. "0123" és 123).
+func parseSize( size string ) (int64,error) {
+  return strconv.ParseInt ( strings.TrimSpace ( size ), 10, 64 )
+} // end parseSize
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Llig un fitxer DAT detectant el format.
+func Load( file_name string ) (*Dat,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+
+  // Detecta format
+  r:= bufio.NewReader ( fd )
+  for {
+    b,err:= r.Peek ( 1 )
+    if err != nil {
+      return nil,errors.New ( "El fitxer DAT està buit" )
+    }
+    switch b[0] {
+    case ' ','\t','\r','\n',0xef,0xbb,0xbf: // Espais i BOM UTF-8
+      r.ReadByte ()
+    case '<':
+      return ReadLogiqx ( r )
+    default:
+      if (b[0] >= 'a' && b[0] <= 'z') || (b[0] >= 'A' && b[0] <= 'Z') {
+        return ReadClrMamePro ( r )
+      }
+      return nil,errors.New ( "Format de fitxer DAT desconegut" )
+    }
+  }
+
+} // end Load
+
+
+// Nombre total de ROMs
+func (self *Dat) GetNumRoms() int {
+
+  ret:= 0
+  for i:= range self.Games {
+    ret+= len(self.Games[i].Roms)
+  }
+
+  return ret
+
+} // end GetNumRoms
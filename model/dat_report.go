@@ -0,0 +1,251 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  dat_report.go - Informe dels jocs d'un DAT que tenim i dels que
+ *                  falten en una plataforma.
+ */
+
+package model
+
+import (
+  "encoding/csv"
+  "fmt"
+  "io"
+  "log"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _DatReportGame struct {
+  dat_id      int64
+  name        string
+  description string
+  have        bool
+  has_entry   bool
+}
+
+
+func (self *DatReport) add(
+
+  dat_id      int64,
+  name        string,
+  description string,
+  has_entry   bool,
+
+) {
+
+  self.games= append(self.games,_DatReportGame{
+    dat_id      : dat_id,
+    name        : name,
+    description : description,
+    has_entry   : has_entry,
+  })
+
+} // end add
+
+
+func (self *DatReport) getDatName( dat_id int64 ) string {
+
+  d:= self.dats.Get ( dat_id )
+
+  return fmt.Sprintf ( "%s (%s)", d.GetName (), d.GetVersion () )
+
+} // end getDatName
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+type DatReport struct {
+  dats        *Dats
+  plat        *Platform
+  platform_id int
+  dat_ids     []int64
+  games       []_DatReportGame
+  num_have    int
+}
+
+
+// Crea l'informe de la plataforma a partir de tots els DATs
+// associats. Un joc el tenim si algun fitxer de la plataforma
+// coincideix amb alguna de les seues ROMs.
+func NewDatReport(
+
+  dats        *Dats,
+  files       *Files,
+  plats       *Platforms,
+  platform_id int,
+
+) (*DatReport,error) {
+
+  // Prepara
+  plat:= plats.GetPlatform ( platform_id )
+  if plat == nil {
+    return nil,fmt.Errorf ( "La plataforma indicada (%d) no existeix",
+      platform_id )
+  }
+  ret:= DatReport{
+    dats        : dats,
+    plat        : plat,
+    platform_id : platform_id,
+    dat_ids     : make([]int64,0),
+    games       : make([]_DatReportGame,0),
+  }
+
+  // Jocs dels DATs
+  for _,id:= range dats.GetIDs () {
+    if dats.Get ( id ).GetPlatformID () != platform_id { continue }
+    ret.dat_ids= append(ret.dat_ids,id)
+    if err:= dats.db.LoadDatReportGames ( id, &ret ); err != nil {
+      log.Fatal ( err )
+    }
+  }
+  if len(ret.dat_ids) == 0 {
+    return nil,fmt.Errorf ( "La plataforma '%s' no té cap DAT associat",
+      plat.GetShortName () )
+  }
+
+  // Jocs que tenim
+  type key struct {
+    dat_id int64
+    name   string
+  }
+  have:= make(map[key]bool)
+  fids,err:= dats.db.GetPlatformFileIDs ( platform_id )
+  if err != nil { log.Fatal ( err ) }
+  for _,fid:= range fids {
+    f:= files.Get ( fid )
+    _,matches:= dats.Match ( &f.hashes, f.size )
+    for _,m:= range matches {
+      have[key{m.DatID,m.Game}]= true
+    }
+  }
+  for i:= range ret.games {
+    g:= &ret.games[i]
+    if have[key{g.dat_id,g.name}] {
+      g.have= true
+      ret.num_have++
+    }
+  }
+
+  return &ret,nil
+
+} // end NewDatReport
+
+
+func (self *DatReport) GetNumGames() int { return len(self.games) }
+func (self *DatReport) GetNumHave() int { return self.num_have }
+func (self *DatReport) GetNumMiss() int {
+  return len(self.games)-self.num_have
+} // end GetNumMiss
+
+
+// Noms dels jocs que falten.
+func (self *DatReport) GetMissing() []string {
+
+  ret:= make([]string,0,self.GetNumMiss ())
+  for _,g:= range self.games {
+    if !g.have {
+      ret= append(ret,g.name)
+    }
+  }
+
+  return ret
+
+} // end GetMissing
+
+
+// Indica si ja existeix una entrada amb el nom del joc en la
+// plataforma (p.e. una entrada "desitjada" creada anteriorment).
+func (self *DatReport) HasEntry( name string ) bool {
+
+  for _,g:= range self.games {
+    if g.name == name {
+      return g.has_entry
+    }
+  }
+
+  return false
+
+} // end HasEntry
+
+
+// Escriu l'informe en format CSV: estat, DAT, joc i descripció.
+func (self *DatReport) WriteCSV( w io.Writer ) error {
+
+  cw:= csv.NewWriter ( w )
+  if err:= cw.Write ( []string{"estat","dat","joc","descripció"} );
+  err != nil {
+    return err
+  }
+  for _,g:= range self.games {
+    status:= "falta"
+    if g.have { status= "tenim" }
+    if err:= cw.Write ( []string{status,self.getDatName ( g.dat_id ),
+      g.name,g.description} ); err != nil {
+      return err
+    }
+  }
+  cw.Flush ()
+
+  return cw.Error ()
+
+} // end WriteCSV
+
+
+// Escriu l'informe en format text. Els jocs que tenim es marquen amb
+// '+' i els que falten amb '-'.
+func (self *DatReport) WriteText( w io.Writer ) error {
+
+  // Capçalera
+  if _,err:= fmt.Fprintf ( w, "Plataforma: %s - %s\n",
+    self.plat.GetShortName (), self.plat.GetName () ); err != nil {
+    return err
+  }
+  for _,id:= range self.dat_ids {
+    if _,err:= fmt.Fprintf ( w, "DAT:        %s\n",
+      self.getDatName ( id ) ); err != nil {
+      return err
+    }
+  }
+  if _,err:= fmt.Fprintf ( w, "Tenim:      %d/%d\nFalten:     %d\n\n",
+    self.num_have, len(self.games), self.GetNumMiss () ); err != nil {
+    return err
+  }
+
+  // Jocs
+  for _,g:= range self.games {
+    mark:= '-'
+    if g.have { mark= '+' }
+    if _,err:= fmt.Fprintf ( w, "%c %s\n", mark, g.name ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end WriteText
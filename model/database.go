@@ -23,10 +23,15 @@
 package model
 
 import (
+  "context"
   "database/sql"
-  _ "github.com/mattn/go-sqlite3"
+  "github.com/mattn/go-sqlite3"
   "errors"
+  "fmt"
   "log"
+  "strings"
+
+  "github.com/adriagipas/imgteka/model/dat"
 )
 
 
@@ -112,6 +117,98 @@ CREATE TABLE IF NOT EXISTS FILES (
 `
 
 
+// Configuració de la biblioteca guardada amb les dades (p.e.
+// l'organització del directori 'files').
+const _CREATE_SETTINGS= `
+CREATE TABLE IF NOT EXISTS SETTINGS (
+       key TEXT PRIMARY KEY,
+       value TEXT NOT NULL
+);
+`
+
+
+// Versió de FILES sense UNIQUE (type,name), que passa a ser un índex
+// que es pot eliminar quan els fitxers es desen per SHA1.
+const _CREATE_FILES_STORE= `
+CREATE TABLE FILES_STORE (
+       id INTEGER PRIMARY KEY,
+       name TEXT NOT NULL,
+       entry_id INTEGER NOT NULL,
+       type INTEGER NOT NULL,
+       size INTEGER NOT NULL,
+       md5 TEXT NOT NULL,
+       sha1 TEXT NOT NULL,
+       extra_json TEXT NOT NULL,
+       last_check INTEGER NOT NULL,
+       crc32 TEXT NOT NULL DEFAULT '',
+       sha256 TEXT NOT NULL DEFAULT '',
+       real_md5 TEXT NOT NULL DEFAULT '',
+       real_sha1 TEXT NOT NULL DEFAULT '',
+       real_crc32 TEXT NOT NULL DEFAULT '',
+       UNIQUE (entry_id,name),
+       FOREIGN KEY (entry_id)
+               REFERENCES ENTRIES (id)
+               ON DELETE CASCADE
+               ON UPDATE NO ACTION
+);
+`
+
+
+const _CREATE_DATS= `
+CREATE TABLE IF NOT EXISTS DATS (
+       id INTEGER PRIMARY KEY,
+       name TEXT NOT NULL,
+       description TEXT NOT NULL,
+       version TEXT NOT NULL,
+       author TEXT NOT NULL,
+       format TEXT NOT NULL,
+       header TEXT NOT NULL DEFAULT '',
+       platform_id INTEGER NOT NULL DEFAULT -1,
+       file_name TEXT NOT NULL,
+       import_date INTEGER NOT NULL,
+       UNIQUE (name,version)
+);
+`
+
+const _CREATE_DAT_GAMES= `
+CREATE TABLE IF NOT EXISTS DAT_GAMES (
+       id INTEGER PRIMARY KEY,
+       dat_id INTEGER NOT NULL,
+       name TEXT NOT NULL,
+       description TEXT NOT NULL,
+       clone_of TEXT NOT NULL DEFAULT '',
+       rom_of TEXT NOT NULL DEFAULT '',
+       resource INTEGER NOT NULL DEFAULT 0,
+       FOREIGN KEY (dat_id)
+               REFERENCES DATS (id)
+               ON DELETE CASCADE
+               ON UPDATE NO ACTION
+);
+`
+
+const _CREATE_DAT_ROMS= `
+CREATE TABLE IF NOT EXISTS DAT_ROMS (
+       id INTEGER PRIMARY KEY,
+       game_id INTEGER NOT NULL,
+       name TEXT NOT NULL,
+       size INTEGER NOT NULL,
+       crc TEXT NOT NULL,
+       md5 TEXT NOT NULL,
+       sha1 TEXT NOT NULL,
+       status TEXT NOT NULL,
+       merge TEXT NOT NULL DEFAULT '',
+       FOREIGN KEY (game_id)
+               REFERENCES DAT_GAMES (id)
+               ON DELETE CASCADE
+               ON UPDATE NO ACTION
+);
+CREATE INDEX IF NOT EXISTS DAT_ROMS_MD5 ON DAT_ROMS (md5);
+CREATE INDEX IF NOT EXISTS DAT_ROMS_SHA1 ON DAT_ROMS (sha1);
+CREATE INDEX IF NOT EXISTS DAT_GAMES_DAT ON DAT_GAMES (dat_id);
+CREATE INDEX IF NOT EXISTS DAT_ROMS_GAME ON DAT_ROMS (game_id);
+`
+
+
 func initDatabase ( dirs *Dirs ) (*sql.DB,error) {
 
   // Nom
@@ -122,26 +219,147 @@ func initDatabase ( dirs *Dirs ) (*sql.DB,error) {
   db,err:= sql.Open ( "sqlite3", db_fn )
   if err != nil { return nil,err }
   
-  // Crea taules si cal
-  if _,err:= db.Exec ( _CREATE_PLATFORMS ); err != nil {
+  // Crea o actualitza l'esquema
+  if err:= migrateDatabase ( db, db_fn ); err != nil {
+    db.Close ()
     return nil,err
   }
-  if _,err:= db.Exec ( _CREATE_ENTRIES ); err != nil {
+  
+  return db,nil
+  
+} // end initDatabase
+
+
+// Copia tota la base de dades 'src' en 'dst' amb l'API de còpies de
+// seguretat en línia de SQLite.
+func copyDatabase( dst *sql.DB, src *sql.DB ) error {
+
+  ctx:= context.Background ()
+  dst_conn,err:= dst.Conn ( ctx )
+  if err != nil { return err }
+  defer dst_conn.Close ()
+  src_conn,err:= src.Conn ( ctx )
+  if err != nil { return err }
+  defer src_conn.Close ()
+
+  return dst_conn.Raw ( func(dst_raw any) error {
+    return src_conn.Raw ( func(src_raw any) error {
+      dst_sq,ok1:= dst_raw.(*sqlite3.SQLiteConn)
+      src_sq,ok2:= src_raw.(*sqlite3.SQLiteConn)
+      if !ok1 || !ok2 {
+        return errors.New ( "La connexió no és de SQLite" )
+      }
+      b,err:= dst_sq.Backup ( "main", src_sq, "main" )
+      if err != nil { return err }
+      if _,err:= b.Step ( -1 ); err != nil {
+        b.Finish ()
+        return err
+      }
+      return b.Finish ()
+    })
+  })
+  
+} // end copyDatabase
+
+
+// Fitxers registrats en una base de dades (no necessàriament la
+// principal). Sols consulta les columnes que existeixen des de la
+// primera versió de l'esquema, excepte la compressió si existeix.
+func loadBackupFiles( conn *sql.DB ) ([]_BackupFile,error) {
+
+  // Consulta base de dades
+  compression:= "''"
+  var n int
+  if err:= conn.QueryRow ( `
+SELECT COUNT(*) FROM pragma_table_info('FILES') WHERE name = 'compression';
+` ).Scan ( &n ); err != nil {
     return nil,err
   }
-  if _,err:= db.Exec ( _CREATE_LABELS ); err != nil {
-    return nil,err
+  if n > 0 { compression= "f.compression" }
+  rows,err:= conn.Query ( `
+SELECT f.id,f.name,f.type,f.size,f.md5,f.sha1,e.name,p.short_name,
+       `+compression+`
+FROM FILES f
+INNER JOIN ENTRIES e ON e.id = f.entry_id
+INNER JOIN PLATFORMS p ON p.id = e.platform_id
+ORDER BY f.id ASC;
+` )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]_BackupFile,0)
+  for rows.Next () {
+    var f _BackupFile
+    err= rows.Scan ( &f.ID, &f.name, &f.file_type, &f.Size, &f.MD5,
+      &f.SHA1, &f.entry, &f.platform, &f.Compression )
+    if err != nil { return nil,err }
+    ret= append(ret,f)
   }
-  if _,err:= db.Exec ( _CREATE_ENTRY_LABEL_PAIRS ); err != nil {
-    return nil,err
+  
+  return ret,rows.Err ()
+  
+} // end loadBackupFiles
+
+
+// Organització del directori 'files' d'una base de dades (no
+// necessàriament la principal). Les bases de dades sense la taula
+// SETTINGS són anteriors a l'organització per SHA1.
+func loadStore( conn *sql.DB ) (int,error) {
+
+  var n int
+  if err:= conn.QueryRow ( `
+SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'SETTINGS';
+` ).Scan ( &n ); err != nil {
+    return -1,err
   }
-  if _,err:= db.Exec ( _CREATE_FILES ); err != nil {
-    return nil,err
+  if n == 0 { return STORE_NAME,nil }
+  var value string
+  err:= conn.QueryRow ( `
+SELECT value FROM SETTINGS WHERE key = 'store';
+` ).Scan ( &value )
+  if errors.Is ( err, sql.ErrNoRows ) {
+    return STORE_NAME,nil
+  } else if err != nil {
+    return -1,err
+  }
+  switch value {
+  case "name":
+    return STORE_NAME,nil
+  case "sha1":
+    return STORE_SHA1,nil
+  default:
+    return -1,fmt.Errorf ( "Organització del directori de fitxers"+
+      " desconeguda: '%s'", value )
   }
   
-  return db,nil
-  
-} // end initDatabase
+} // end loadStore
+
+
+// Torna el nom curt de la plataforma i el nom de totes les entrades,
+// que és el que identifica el seu directori.
+func loadEntryFolders( conn *sql.DB ) ([][2]string,error) {
+
+  rows,err:= conn.Query ( `
+SELECT p.short_name,e.name
+FROM ENTRIES e
+INNER JOIN PLATFORMS p ON p.id = e.platform_id;
+` )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  ret:= make([][2]string,0)
+  for rows.Next () {
+    var folder [2]string
+    if err:= rows.Scan ( &folder[0], &folder[1] ); err != nil {
+      return nil,err
+    }
+    ret= append(ret,folder)
+  }
+
+  return ret,rows.Err ()
+
+} // end loadEntryFolders
 
 
 func (self *Database) buildFilter() (string,[]any) {
@@ -173,6 +391,15 @@ func (self *Database) buildFilter() (string,[]any) {
         tmp+= "( p.short_name LIKE ? OR p.name LIKE ? )"
         args= append(args,q.value)
         args= append(args,"%"+q.value+"%")
+
+      case QUERY_TYPE_HASH:
+        tmp+= ` EXISTS (
+     SELECT 1
+     FROM FILES f_h
+     WHERE e.id = f_h.entry_id AND
+           ? IN (f_h.md5,f_h.sha1,f_h.crc32,f_h.sha256,
+                 f_h.real_md5,f_h.real_sha1,f_h.real_crc32) ) `
+        args= append(args,strings.ToLower ( q.value ))
         
       }
     }
@@ -276,6 +503,36 @@ func (self *Database) Close() {
 } // end Close
 
 
+// Fa una còpia consistent de la base de dades en 'file_name'.
+func (self *Database) BackupTo( file_name string ) error {
+
+  dst,err:= sql.Open ( "sqlite3", file_name )
+  if err != nil { return err }
+  defer dst.Close ()
+
+  return copyDatabase ( dst, self.conn )
+  
+} // end BackupTo
+
+
+// Substitueix el contingut de la base de dades pel de 'file_name' i
+// l'actualitza a l'última versió de l'esquema.
+func (self *Database) RestoreFrom( file_name string, dirs *Dirs ) error {
+
+  src,err:= sql.Open ( "sqlite3", file_name )
+  if err != nil { return err }
+  defer src.Close ()
+  if err:= copyDatabase ( self.conn, src ); err != nil {
+    return err
+  }
+  db_fn,err:= dirs.GetDatabaseName ()
+  if err != nil { return err }
+  
+  return migrateDatabase ( self.conn, db_fn )
+  
+} // end RestoreFrom
+
+
 func (self *Database) CommitLastTransaction() error {
 
   if self.last_tx == nil {
@@ -380,8 +637,15 @@ func (self *Database) SetQuery( query *Query ) {
 
 func (self *Database) DeletePlatform( id int ) error {
 
-  _,err:= self.conn.Exec ( `
+  if _,err:= self.conn.Exec ( `
 DELETE FROM PLATFORMS WHERE id=?;
+`, id ); err != nil {
+    return err
+  }
+
+  // Els DATs associats es queden sense plataforma
+  _,err:= self.conn.Exec ( `
+UPDATE DATS SET platform_id = -1 WHERE platform_id=?;
 `, id )
 
   return err
@@ -548,6 +812,107 @@ UPDATE LABELS SET name = ?, color_r = ?, color_g = ?, color_b = ?
 
 // ENTRIES /////////////////////////////////////////////////////////////////////
 
+// Valor d'una clau de SETTINGS. Buit si no està.
+func (self *Database) getSetting( key string ) (string,error) {
+
+  var ret string
+  err:= self.conn.QueryRow ( `
+SELECT value FROM SETTINGS WHERE key = ?;
+`, key ).Scan ( &ret )
+  if errors.Is ( err, sql.ErrNoRows ) { return "",nil }
+
+  return ret,err
+  
+} // end getSetting
+
+
+func (self *Database) setSetting( key string, value string ) error {
+
+  _,err:= self.conn.Exec ( `
+INSERT OR REPLACE INTO SETTINGS (key,value) VALUES (?,?);
+`, key, value )
+
+  return err
+  
+} // end setSetting
+
+
+func (self *Database) GetImportMode() (int,error) {
+
+  value,err:= self.getSetting ( "import" )
+  if err != nil { return -1,err }
+  if value == "" { return IMPORT_HARDLINK,nil }
+
+  return ParseImportMode ( value )
+  
+} // end GetImportMode
+
+
+func (self *Database) SetImportMode( mode int ) error {
+  return self.setSetting ( "import", GetImportModeName ( mode ) )
+} // end SetImportMode
+
+
+// Compressió dels fitxers nous del tipus indicat. Buit si no es
+// comprimeixen.
+func (self *Database) GetCompression( file_type int ) (string,error) {
+  return self.getSetting ( fmt.Sprintf ( "compression.%d", file_type ) )
+} // end GetCompression
+
+
+func (self *Database) SetCompression(
+
+  file_type   int,
+  compression string,
+
+) error {
+
+  key:= fmt.Sprintf ( "compression.%d", file_type )
+  if compression == "" {
+    _,err:= self.conn.Exec ( `
+DELETE FROM SETTINGS WHERE key = ?;
+`, key )
+    return err
+  }
+
+  return self.setSetting ( key, compression )
+  
+} // end SetCompression
+
+
+func (self *Database) GetStore() (int,error) {
+  return loadStore ( self.conn )
+} // end GetStore
+
+
+// Canvia l'organització del directori 'files'. Amb STORE_NAME no pot
+// haver dos fitxers del mateix tipus amb el mateix nom.
+func (self *Database) SetStore( store int ) error {
+
+  value,index:= "name",`
+CREATE UNIQUE INDEX IF NOT EXISTS FILES_TYPE_NAME ON FILES (type,name);
+`
+  if store == STORE_SHA1 {
+    value,index= "sha1","DROP INDEX IF EXISTS FILES_TYPE_NAME;"
+  }
+  tx,err:= self.conn.Begin ()
+  if err != nil { return err }
+  if _,err:= tx.Exec ( index ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+  if _,err:= tx.Exec ( `
+INSERT OR REPLACE INTO SETTINGS (key,value) VALUES ('store',?);
+`, value ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+
+  return tx.Commit ()
+  
+} // end SetStore
+
+
 func (self *Database) DeleteEntryWithoutCommit( id int64 ) error {
 
   // Prepara
@@ -573,6 +938,24 @@ DELETE FROM ENTRIES WHERE id=?;
 
 // NOTA!!! En algun moment caldrà ficar la query.
 // NOTA!!! Sols es carreguen les dades bàsiques.
+func (self *Database) GetEntry( id int64 ) (
+  name        string,
+  platform_id int,
+  cover_id    int64,
+  err         error,
+) {
+
+  err= self.conn.QueryRow ( `
+SELECT name,platform_id,cover_id
+FROM ENTRIES
+WHERE id = ?;
+`, id ).Scan ( &name, &platform_id, &cover_id )
+
+  return
+  
+} // end GetEntry
+
+
 func (self *Database) LoadEntries( entries *Entries ) error {
 
   // Consulta base de dades
@@ -774,21 +1157,46 @@ DELETE FROM FILES WHERE id=?;
 } // end DeleteFileWithoutCommit
 
 
+func (self *Database) FileExists( id int64 ) (bool,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT COUNT(*)
+FROM FILES
+WHERE id = ?;
+`, id )
+  if err != nil { return false,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  if !rows.Next () {
+    return false,errors.New ( "Error inesperat en Database.FileExists" )
+  }
+  var ret int64
+  err= rows.Scan ( &ret )
+  if err != nil { return false,err }
+  
+  return ret>0,rows.Err ()
+  
+} // end FileExists
+
+
 // S'enten que ja està, no deuria fallar
 func (self *Database) GetFile( id int64 ) (
   name       string,
   entry_id   int64,
   file_type  int,
   size       int64,
-  md5        string,
-  sha1       string,
+  hashes     _Hashes,
   json       string,
   last_check int64,
+  compression string,
 ) {
   
   // Consulta base de dades
   rows,err:= self.conn.Query ( `
-SELECT name,entry_id,type,size,md5,sha1,extra_json,last_check
+SELECT name,entry_id,type,size,md5,sha1,crc32,sha256,
+       real_md5,real_sha1,real_crc32,extra_json,last_check,compression
 FROM FILES
 WHERE id = ?;
 `, id )
@@ -799,8 +1207,9 @@ WHERE id = ?;
   if !rows.Next () {
     log.Fatal ( "Error inesperat en Database.GetFile" )
   }
-  err= rows.Scan ( &name, &entry_id, &file_type, &size, &md5,
-    &sha1, &json, &last_check )
+  err= rows.Scan ( &name, &entry_id, &file_type, &size, &hashes.md5,
+    &hashes.sha1, &hashes.crc32, &hashes.sha256, &hashes.real_md5,
+    &hashes.real_sha1, &hashes.real_crc32, &json, &last_check, &compression )
   if err != nil { log.Fatal ( err ) }
   
   return
@@ -838,10 +1247,10 @@ func (self *Database) RegisterFileWithoutCommit(
   entry_id   int64,
   file_type  int,
   size       int64,
-  md5        string,
-  sha1       string,
+  hashes     *_Hashes,
   extra_json string,
   last_check int64,
+  compression string,
   
 ) error {
 
@@ -849,16 +1258,18 @@ func (self *Database) RegisterFileWithoutCommit(
   tx,err:= self.conn.Begin ()
   if err != nil { log.Fatal ( err ) }
   stmt,err:= tx.Prepare ( `
-   INSERT INTO FILES(name, entry_id, type, size, md5, sha1,
-                     extra_json, last_check)
-          VALUES(?,?,?,?,?,?,?,?);
+   INSERT INTO FILES(name, entry_id, type, size, md5, sha1, crc32, sha256,
+                     real_md5, real_sha1, real_crc32, extra_json, last_check,
+                     compression)
+          VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?);
 ` )
   if err != nil { log.Fatal ( err ) }
   defer stmt.Close ()
   
   // Inserta
-  _,err= stmt.Exec ( name, entry_id, file_type, size, md5,
-    sha1, extra_json, last_check )
+  _,err= stmt.Exec ( name, entry_id, file_type, size, hashes.md5,
+    hashes.sha1, hashes.crc32, hashes.sha256, hashes.real_md5,
+    hashes.real_sha1, hashes.real_crc32, extra_json, last_check, compression )
   if err != nil { tx.Rollback (); return err }
 
   // Registra transacció
@@ -869,6 +1280,92 @@ func (self *Database) RegisterFileWithoutCommit(
 } // end RegisterEntryWithoutCommit
 
 
+func (self *Database) UpdateFileHashes(
+
+  id     int64,
+  hashes *_Hashes,
+  
+) error {
+
+  _,err:= self.conn.Exec ( `
+UPDATE FILES SET crc32 = ?, sha256 = ?,
+                 real_md5 = ?, real_sha1 = ?, real_crc32 = ?
+       WHERE id = ?;
+`, hashes.crc32, hashes.sha256, hashes.real_md5, hashes.real_sha1,
+    hashes.real_crc32, id )
+
+  return err
+  
+} // end UpdateFileHashes
+
+
+func (self *Database) UpdateFileMetadata( id int64, json string ) error {
+
+  _,err:= self.conn.Exec ( `
+UPDATE FILES SET extra_json = ? WHERE id = ?;
+`, json, id )
+
+  return err
+  
+} // end UpdateFileMetadata
+
+
+func (self *Database) UpdateFileLastCheck( id int64, last_check int64 ) error {
+
+  _,err:= self.conn.Exec ( `
+UPDATE FILES SET last_check = ? WHERE id = ?;
+`, last_check, id )
+
+  return err
+  
+} // end UpdateFileLastCheck
+
+
+// Nombre de fitxers amb el SHA1 i la compressió indicats. Quan es
+// desen per SHA1 comparteixen el mateix fitxer en 'files'.
+func (self *Database) GetNumFilesWithSHA1(
+
+  sha1        string,
+  compression string,
+
+) (int64,error) {
+
+  var ret int64
+  err:= self.conn.QueryRow ( `
+SELECT COUNT(*) FROM FILES WHERE sha1 = ? AND compression = ?;
+`, sha1, compression ).Scan ( &ret )
+
+  return ret,err
+  
+} // end GetNumFilesWithSHA1
+
+
+func (self *Database) UpdateFileCompressionWithoutCommit(
+
+  id          int64,
+  compression string,
+  
+) error {
+
+  // Prepara
+  tx,err:= self.conn.Begin ()
+  if err != nil { log.Fatal ( err ) }
+  
+  // Actualitza
+  _,err= tx.Exec ( `
+UPDATE FILES SET compression = ?
+       WHERE id = ?;
+`, compression, id )
+  if err != nil { tx.Rollback (); return err }
+  
+  // Registra transacció
+  self.last_tx= tx
+  
+  return nil
+  
+} // end UpdateFileCompressionWithoutCommit
+
+
 func (self *Database) UpdateFileNameWithoutCommit(
 
   id          int64,
@@ -896,3 +1393,451 @@ UPDATE FILES SET name = ?
   return nil
   
 } // end UpdateFileNameWithoutCommit
+
+
+func (self *Database) GetFileIDs() ([]int64,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT id
+FROM FILES
+ORDER BY id ASC;
+` )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]int64,0)
+  for rows.Next () {
+    var id int64
+    err= rows.Scan ( &id )
+    if err != nil { return nil,err }
+    ret= append(ret,id)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end GetFileIDs
+
+
+// Fitxers registrats sense CRC32, SHA-256 o hashes reals (versions
+// anteriors).
+func (self *Database) GetFilesWithoutHashes() ([]_BackfillFile,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT id,name,type,size,sha1,compression
+FROM FILES
+WHERE crc32 = '' OR sha256 = '' OR real_md5 = ''
+ORDER BY id ASC;
+` )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]_BackfillFile,0)
+  for rows.Next () {
+    var f _BackfillFile
+    err= rows.Scan ( &f.id, &f.name, &f.file_type, &f.size, &f.sha1,
+      &f.compression )
+    if err != nil { return nil,err }
+    ret= append(ret,f)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end GetFilesWithoutHashes
+
+
+// Fitxers del tipus indicat amb unes metadades que no contenen la
+// clau 'key' (calculades per una versió anterior).
+func (self *Database) GetFilesWithOldMetadata(
+
+  file_type int,
+  key       string,
+  
+) ([]_BackfillFile,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT id,name,type,size,sha1,compression
+FROM FILES
+WHERE type = ? AND instr(extra_json,?) = 0
+ORDER BY id ASC;
+`, file_type, `"`+key+`"` )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]_BackfillFile,0)
+  for rows.Next () {
+    var f _BackfillFile
+    err= rows.Scan ( &f.id, &f.name, &f.file_type, &f.size, &f.sha1,
+      &f.compression )
+    if err != nil { return nil,err }
+    ret= append(ret,f)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end GetFilesWithOldMetadata
+
+
+func (self *Database) loadIntegrityFiles(
+
+  where string,
+  args  ...any,
+
+) ([]_IntegrityFile,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT f.id,f.name,f.type,f.size,f.md5,f.sha1,f.last_check,f.compression
+FROM FILES f
+INNER JOIN ENTRIES e ON e.id = f.entry_id
+WHERE `+where+`
+ORDER BY f.id ASC;
+`, args... )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]_IntegrityFile,0)
+  for rows.Next () {
+    var f _IntegrityFile
+    err= rows.Scan ( &f.id, &f.name, &f.file_type, &f.size, &f.md5,
+      &f.sha1, &f.last_check, &f.compression )
+    if err != nil { return nil,err }
+    ret= append(ret,f)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end loadIntegrityFiles
+
+
+// Fitxers que no s'han comprovat des de 'last_check'.
+func (self *Database) GetFilesCheckedBefore(
+  last_check int64,
+) ([]_IntegrityFile,error) {
+  return self.loadIntegrityFiles ( "f.last_check < ?", last_check )
+} // end GetFilesCheckedBefore
+
+
+func (self *Database) GetEntryIntegrityFiles(
+  entry_id int64,
+) ([]_IntegrityFile,error) {
+  return self.loadIntegrityFiles ( "f.entry_id = ?", entry_id )
+} // end GetEntryIntegrityFiles
+
+
+func (self *Database) GetPlatformIntegrityFiles(
+  platform_id int,
+) ([]_IntegrityFile,error) {
+  return self.loadIntegrityFiles ( "e.platform_id = ?", platform_id )
+} // end GetPlatformIntegrityFiles
+
+
+// Clau per a detectar duplicats: el hash sense capçalera si està
+// calculat o el SHA1.
+const _DUP_KEY= "CASE WHEN f.real_sha1 != '' THEN f.real_sha1 ELSE f.sha1 END"
+
+
+func (self *Database) loadDuplicateFiles(
+
+  where string,
+  args  ...any,
+
+) ([]_DuplicateFile,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT `+_DUP_KEY+`,f.id,f.name,e.id,e.name,p.short_name
+FROM FILES f
+INNER JOIN ENTRIES e ON e.id = f.entry_id
+INNER JOIN PLATFORMS p ON p.id = e.platform_id
+WHERE `+where+`
+ORDER BY 1 ASC,f.id ASC;
+`, args... )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]_DuplicateFile,0)
+  for rows.Next () {
+    var f _DuplicateFile
+    err= rows.Scan ( &f.key, &f.id, &f.name, &f.entry_id, &f.entry,
+      &f.platform )
+    if err != nil { return nil,err }
+    ret= append(ret,f)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end loadDuplicateFiles
+
+
+// Fitxers que tenen la mateixa clau que algun altre, ordenats per
+// clau.
+func (self *Database) GetDuplicateFiles() ([]_DuplicateFile,error) {
+  return self.loadDuplicateFiles ( _DUP_KEY+` IN (
+  SELECT `+_DUP_KEY+` FROM FILES f GROUP BY 1 HAVING COUNT(*) > 1 )` )
+} // end GetDuplicateFiles
+
+
+func (self *Database) GetFilesWithKey( key string ) ([]_DuplicateFile,error) {
+  return self.loadDuplicateFiles ( _DUP_KEY+" = ?", key )
+} // end GetFilesWithKey
+
+
+func (self *Database) GetPlatformFileIDs( platform_id int ) ([]int64,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT f.id
+FROM FILES f
+INNER JOIN ENTRIES e ON e.id = f.entry_id
+WHERE e.platform_id = ?
+ORDER BY f.id ASC;
+`, platform_id )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]int64,0)
+  for rows.Next () {
+    var id int64
+    err= rows.Scan ( &id )
+    if err != nil { return nil,err }
+    ret= append(ret,id)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end GetPlatformFileIDs
+
+
+// DATS ////////////////////////////////////////////////////////////////////////
+
+func (self *Database) DeleteDatWithoutCommit( id int64 ) error {
+
+  // Prepara
+  tx,err:= self.conn.Begin ()
+  if err != nil { log.Fatal ( err ) }
+
+  // Elimina (no confiem en ON DELETE CASCADE)
+  if _,err:= tx.Exec ( `
+DELETE FROM DAT_ROMS
+       WHERE game_id IN (SELECT id FROM DAT_GAMES WHERE dat_id = ?);
+`, id ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+  if _,err:= tx.Exec ( `
+DELETE FROM DAT_GAMES WHERE dat_id = ?;
+`, id ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+  if _,err:= tx.Exec ( `
+DELETE FROM DATS WHERE id = ?;
+`, id ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+  
+  // Registra transacció
+  self.last_tx= tx
+  
+  return nil
+  
+} // end DeleteDatWithoutCommit
+
+
+func (self *Database) LoadDats( dats *Dats ) error {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT d.id,d.name,d.description,d.version,d.author,d.format,
+       d.header,d.platform_id,d.file_name,d.import_date,
+       (SELECT COUNT(*) FROM DAT_GAMES g WHERE g.dat_id = d.id),
+       (SELECT COUNT(*) FROM DAT_ROMS r
+               INNER JOIN DAT_GAMES g ON g.id = r.game_id
+               WHERE g.dat_id = d.id)
+FROM DATS d
+ORDER BY d.name ASC, d.version ASC;
+` )
+  if err != nil { return err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  for rows.Next () {
+    var id,import_date,num_games,num_roms int64
+    var platform_id int
+    var name,description,version,author,format,header,file_name string
+    err= rows.Scan ( &id, &name, &description, &version, &author, &format,
+      &header, &platform_id, &file_name, &import_date, &num_games, &num_roms )
+    if err != nil { return err }
+    dats.add ( id, name, description, version, author, format, header,
+      platform_id, file_name, import_date, num_games, num_roms )
+  }
+  
+  return rows.Err ()
+  
+} // end LoadDats
+
+
+// Carrega els jocs (no els recursos) del DAT en l'informe. També
+// indica si ja existeix una entrada amb el mateix nom en la
+// plataforma de l'informe.
+func (self *Database) LoadDatReportGames(
+
+  dat_id int64,
+  report *DatReport,
+
+) error {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT g.name,g.description,
+       EXISTS (SELECT 1 FROM ENTRIES e
+               WHERE e.platform_id = ? AND e.name = g.name)
+FROM DAT_GAMES g
+WHERE g.dat_id = ? AND g.resource = 0
+ORDER BY g.name ASC;
+`, report.platform_id, dat_id )
+  if err != nil { return err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  for rows.Next () {
+    var name,description string
+    var has_entry bool
+    err= rows.Scan ( &name, &description, &has_entry )
+    if err != nil { return err }
+    report.add ( dat_id, name, description, has_entry )
+  }
+  
+  return rows.Err ()
+  
+} // end LoadDatReportGames
+
+
+// Busca les ROMs dels DATs que coincideixen amb els hashes
+// indicats, amb o sense capçalera. Té prioritat el SHA1, el MD5 sols
+// s'empra quan la ROM del DAT no té SHA1.
+// Les ROMs que sols tenen CRC (habitual en DATs d'arcade) es
+// comparen amb el CRC32 i la grandària. Sense capçalera la grandària
+// sols pot ser menor, perquè no es coneix la de la capçalera.
+func (self *Database) MatchDatRoms(
+  
+  hashes *_Hashes,
+  size   int64,
+  
+) ([]DatMatch,error) {
+
+  // Consulta base de dades
+  rows,err:= self.conn.Query ( `
+SELECT d.id,d.name,g.name,r.name,r.status
+FROM DAT_ROMS r
+INNER JOIN DAT_GAMES g ON g.id = r.game_id
+INNER JOIN DATS d ON d.id = g.dat_id
+WHERE (r.sha1 != '' AND r.sha1 IN (?,?)) OR
+      (r.sha1 = '' AND r.md5 != '' AND r.md5 IN (?,?)) OR
+      (r.sha1 = '' AND r.md5 = '' AND r.crc != '' AND
+       ((r.crc = ? AND r.size = ?) OR
+        (r.crc = ? AND r.crc != ? AND r.size < ?)))
+ORDER BY d.name ASC, g.name ASC;
+`, hashes.sha1, hashes.real_sha1, hashes.md5, hashes.real_md5,
+    hashes.crc32, size, hashes.real_crc32, hashes.crc32, size )
+  if err != nil { return nil,err }
+  defer rows.Close ()
+
+  // Recorre consulta
+  ret:= make([]DatMatch,0)
+  for rows.Next () {
+    var m DatMatch
+    err= rows.Scan ( &m.DatID, &m.DatName, &m.Game, &m.Rom, &m.Status )
+    if err != nil { return nil,err }
+    ret= append(ret,m)
+  }
+  
+  return ret,rows.Err ()
+  
+} // end MatchDatRoms
+
+
+func (self *Database) UpdateDatPlatform( id int64, platform_id int ) error {
+
+  _,err:= self.conn.Exec ( `
+UPDATE DATS SET platform_id = ?
+       WHERE id = ?;
+`, platform_id, id )
+
+  return err
+  
+} // end UpdateDatPlatform
+
+
+func (self *Database) RegisterDatWithoutCommit(
+  
+  d           *dat.Dat,
+  file_name   string,
+  import_date int64,
+  
+) error {
+
+  // Prepara
+  tx,err:= self.conn.Begin ()
+  if err != nil { log.Fatal ( err ) }
+  stmt_game,err:= tx.Prepare ( `
+   INSERT INTO DAT_GAMES(dat_id, name, description, clone_of, rom_of,
+                         resource)
+          VALUES(?,?,?,?,?,?);
+` )
+  if err != nil { log.Fatal ( err ) }
+  defer stmt_game.Close ()
+  stmt_rom,err:= tx.Prepare ( `
+   INSERT INTO DAT_ROMS(game_id, name, size, crc, md5, sha1, status, merge)
+          VALUES(?,?,?,?,?,?,?,?);
+` )
+  if err != nil { log.Fatal ( err ) }
+  defer stmt_rom.Close ()
+  
+  // Inserta DAT
+  res,err:= tx.Exec ( `
+   INSERT INTO DATS(name, description, version, author, format, header,
+                    file_name, import_date)
+          VALUES(?,?,?,?,?,?,?,?);
+`, d.Name, d.Description, d.Version, d.Author, d.Format, d.Header,
+    file_name, import_date )
+  if err != nil { tx.Rollback (); return err }
+  dat_id,err:= res.LastInsertId ()
+  if err != nil { tx.Rollback (); return err }
+
+  // Inserta jocs i ROMs
+  for i:= range d.Games {
+    game:= &d.Games[i]
+    resource:= 0
+    if game.Resource { resource= 1 }
+    res,err:= stmt_game.Exec ( dat_id, game.Name, game.Description,
+      game.CloneOf, game.RomOf, resource )
+    if err != nil { tx.Rollback (); return err }
+    game_id,err:= res.LastInsertId ()
+    if err != nil { tx.Rollback (); return err }
+    for j:= range game.Roms {
+      rom:= &game.Roms[j]
+      if _,err:= stmt_rom.Exec ( game_id, rom.Name, rom.Size, rom.CRC,
+        rom.MD5, rom.SHA1, rom.Status, rom.Merge ); err != nil {
+        tx.Rollback ()
+        return err
+      }
+    }
+  }
+  
+  // Registra transacció
+  self.last_tx= tx
+  
+  return nil
+  
+} // end RegisterDatWithoutCommit
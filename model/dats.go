@@ -0,0 +1,343 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  dats.go - Gestió dels fitxers DAT de referència. Manté una "cache"
+ *            dels DATs importats i compara els hashes dels fitxers.
+ */
+
+package model
+
+import (
+  "fmt"
+  "io"
+  "log"
+  "path/filepath"
+  "time"
+
+  "github.com/adriagipas/imgteka/model/dat"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/*************/
+/* CONSTANTS */
+/*************/
+
+const (
+  DAT_STATUS_UNKNOWN  = 0 // Cap DAT conté el fitxer
+  DAT_STATUS_VERIFIED = 1 // Coincideix amb una ROM bona d'algun DAT
+  DAT_STATUS_BADDUMP  = 2 // Sols coincideix amb ROMs marcades com dolentes
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func (self *Dats) add(
+
+  id          int64,
+  name        string,
+  description string,
+  version     string,
+  author      string,
+  format      string,
+  header      string,
+  platform_id int,
+  file_name   string,
+  import_date int64,
+  num_games   int64,
+  num_roms    int64,
+
+) {
+
+  self.ids= append ( self.ids, id )
+  self.v[id]= &Dat{
+    dats        : self,
+    id          : id,
+    name        : name,
+    description : description,
+    version     : version,
+    author      : author,
+    format      : format,
+    header      : header,
+    platform_id : platform_id,
+    file_name   : file_name,
+    import_date : import_date,
+    num_games   : num_games,
+    num_roms    : num_roms,
+  }
+
+} // end add
+
+
+func (self *Dats) reset() {
+
+  // Reseteja
+  self.ids= self.ids[:0]
+  self.v= make(map[int64]*Dat)
+
+  // Carrega
+  if err:= self.db.LoadDats ( self ); err != nil {
+    log.Fatal ( err )
+  }
+
+} // end reset
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Coincidència d'un fitxer amb una ROM d'un DAT.
+type DatMatch struct {
+  DatID   int64
+  DatName string
+  Game    string
+  Rom     string
+  Status  string // Estat de la ROM en el DAT
+}
+
+
+type Dats struct {
+  db  *Database
+  ids []int64
+  v   map[int64]*Dat
+}
+
+
+func NewDats ( db *Database ) *Dats {
+
+  ret:= Dats{
+    db  : db,
+    ids : nil,
+    v   : nil,
+  }
+  ret.reset ()
+
+  return &ret
+
+} // end NewDats
+
+
+func (self *Dats) GetIDs() []int64 { return self.ids }
+func (self *Dats) Get( id int64 ) *Dat { return self.v[id] }
+
+
+// Llig i registra un fitxer DAT.
+func (self *Dats) Import( file_name string ) error {
+
+  // Llig
+  d,err:= dat.Load ( file_name )
+  if err != nil {
+    return fmt.Errorf ( "No s'ha pogut llegir el DAT '%s': %s",
+      file_name, err )
+  }
+
+  // Registra
+  if err:= self.db.RegisterDatWithoutCommit ( d, filepath.Base ( file_name ),
+    time.Now ().Unix () ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut registrar el DAT '%s' (%s): %s",
+      d.Name, d.Version, err )
+  }
+  if err:= self.db.CommitLastTransaction (); err != nil {
+    return err
+  }
+  self.reset ()
+
+  return nil
+
+} // end Import
+
+
+// Compara els hashes del fitxer, amb i sense capçalera, amb tots els
+// DATs. Torna l'estat i les coincidències trobades.
+func (self *Dats) Match( hashes *_Hashes, size int64 ) (int,[]DatMatch) {
+
+  matches,err:= self.db.MatchDatRoms ( hashes, size )
+  if err != nil { log.Fatal ( err ) }
+
+  // Estat
+  status:= DAT_STATUS_UNKNOWN
+  for _,m:= range matches {
+    if m.Status == dat.ROM_STATUS_BADDUMP {
+      if status == DAT_STATUS_UNKNOWN {
+        status= DAT_STATUS_BADDUMP
+      }
+    } else {
+      status= DAT_STATUS_VERIFIED
+    }
+  }
+
+  return status,matches
+
+} // end Match
+
+
+func (self *Dats) Remove( id int64 ) error {
+
+  if _,ok:= self.v[id]; !ok {
+    return fmt.Errorf ( "El DAT indicat (%d) no existeix", id )
+  }
+  if err:= self.db.DeleteDatWithoutCommit ( id ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut esborrar el DAT: %s", err )
+  }
+  if err:= self.db.CommitLastTransaction (); err != nil {
+    return err
+  }
+  self.reset ()
+
+  return nil
+
+} // end Remove
+
+
+// Escriu en format Logiqx XML un DAT amb les entrades actuals
+// (d'acord amb el filtre aplicat). Cada entrada és un joc i cada
+// fitxer una ROM. Les entrades sense fitxers s'ignoren. La capçalera
+// es pren de 'header'.
+func (self *Dats) Export(
+
+  w         io.Writer,
+  header    *dat.Dat,
+  entries   *Entries,
+  create_pb func() view.ProgressBar,
+
+) error {
+
+  // Crea barra de progrés
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Crea DAT
+  d:= *header
+  d.Format= dat.FORMAT_LOGIQX
+  d.Games= make([]dat.Game,0)
+  ids:= entries.GetIDs ()
+  for i,id:= range ids {
+    e:= entries.Get ( id )
+    pb.Set ( e.GetName (), float32(i)/float32(len(ids)) )
+    fids:= e.GetFileIDs ()
+    if len(fids) == 0 { continue }
+    game:= dat.Game{
+      Name        : e.GetName (),
+      Description : e.GetName (),
+      Roms        : make([]dat.Rom,0,len(fids)),
+    }
+    for _,fid:= range fids {
+      f:= entries.GetFile ( fid )
+      crc,err:= f.loadCRC32 ()
+      if err != nil { return err }
+      game.Roms= append(game.Roms,dat.Rom{
+        Name : f.GetName (),
+        Size : f.GetSize (),
+        CRC  : crc,
+        MD5  : f.GetMD5 (),
+        SHA1 : f.GetSHA1 (),
+      })
+    }
+    d.Games= append(d.Games,game)
+  }
+
+  // Escriu
+  pb.Set ( "Escriu DAT...", 1.0 )
+  if err:= dat.WriteLogiqx ( w, &d ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut escriure el DAT: %s", err )
+  }
+
+  return nil
+
+} // end Export
+
+
+// Associa el DAT a una plataforma (-1 per a desassociar-lo).
+func (self *Dats) SetPlatform( id int64, platform_id int ) error {
+
+  if _,ok:= self.v[id]; !ok {
+    return fmt.Errorf ( "El DAT indicat (%d) no existeix", id )
+  }
+  if err:= self.db.UpdateDatPlatform ( id, platform_id ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut associar el DAT a la plataforma: %s",
+      err )
+  }
+  self.v[id].platform_id= platform_id
+
+  return nil
+
+} // end SetPlatform
+
+
+func DatStatusToText( status int ) string {
+
+  switch status {
+  case DAT_STATUS_VERIFIED:
+    return "Verificat"
+  case DAT_STATUS_BADDUMP:
+    return "Bolcat dolent"
+  default:
+    return "Desconegut"
+  }
+
+} // end DatStatusToText
+
+
+
+
+// DAT /////////////////////////////////////////////////////////////////////////
+
+type Dat struct {
+  dats        *Dats
+  id          int64
+  name        string
+  description string
+  version     string
+  author      string
+  format      string
+  header      string
+  platform_id int // -1 si no està associat a cap plataforma
+  file_name   string
+  import_date int64
+  num_games   int64
+  num_roms    int64
+}
+
+
+func (self *Dat) GetID() int64 { return self.id }
+func (self *Dat) GetName() string { return self.name }
+func (self *Dat) GetDescription() string { return self.description }
+func (self *Dat) GetVersion() string { return self.version }
+func (self *Dat) GetAuthor() string { return self.author }
+func (self *Dat) GetFormat() string { return self.format }
+func (self *Dat) GetHeader() string { return self.header }
+func (self *Dat) GetPlatformID() int { return self.platform_id }
+func (self *Dat) GetFileName() string { return self.file_name }
+func (self *Dat) GetImportDate() int64 { return self.import_date }
+func (self *Dat) GetNumGames() int64 { return self.num_games }
+func (self *Dat) GetNumRoms() int64 { return self.num_roms }
+
+
+func (self *Dat) SetPlatform( platform_id int ) error {
+  return self.dats.SetPlatform ( self.id, platform_id )
+} // end SetPlatform
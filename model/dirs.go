@@ -17,13 +17,25 @@
  * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
  */
 /*
- *  dirs.go - Gestiona el directoris on es desen els fitxers.
+ *  dirs.go - Gestiona el directoris on es desen els fitxers. Per
+ *            defecte se segueix l'especificació XDG (cada perfil en el
+ *            seu subdirectori), però es pot indicar un directori arrel
+ *            de la biblioteca on es desa tot (base de dades, fitxers i
+ *            memòria cau), de manera que la biblioteca es pot moure a
+ *            altra màquina.
+ *
+ *            Els fitxers es poden desar per tipus i nom
+ *            (files/TIPUS/NOM) o pel SHA1 del contingut
+ *            (files/ab/cdef...). En els dos casos les entrades
+ *            (entries/PLATAFORMA/ENTRADA/NOM) són enllaços als fitxers.
  */
 
 package model
 
 import (
+  "os"
   "path"
+  "path/filepath"
   "strconv"
   
   "github.com/adrg/xdg"
@@ -39,6 +51,42 @@ import (
 const _ROOT_NAME= "imgteka"
 const _ROOT_ENTRIES= "entries"
 const _ROOT_FILES= "files"
+const _ROOT_CACHE= "cache" // Sols amb directori arrel
+const _ROOT_PROFILES= "profiles"
+
+
+// Nom relatiu al directori de dades d'un fitxer de 'files'.
+func storeRelName(
+
+  store       int,
+  file_type   string,
+  name        string,
+  sha1        string,
+  compression string,
+
+) string {
+
+  if store == STORE_SHA1 {
+    return path.Join ( _ROOT_FILES, sha1[:2],
+      compressedName ( sha1[2:], compression ) )
+  }
+
+  return path.Join ( _ROOT_FILES, file_type,
+    compressedName ( name, compression ) )
+  
+} // end storeRelName
+
+
+// Crea els directoris pare de 'fn' i el torna.
+func makeParentDirs( fn string ) (string,error) {
+
+  if err:= os.MkdirAll ( filepath.Dir ( fn ), 0755 ); err != nil {
+    return "",err
+  }
+
+  return fn,nil
+  
+} // end makeParentDirs
 
 
 
@@ -47,16 +95,29 @@ const _ROOT_FILES= "files"
 /* PART PÚBLICA */
 /****************/
 
+// Organització del directori 'files'.
+const (
+  STORE_NAME = iota // files/TIPUS/NOM
+  STORE_SHA1        // files/ab/cdef... (SHA1 del contingut)
+)
+
+
 type Dirs struct {
+  root     string  // Directori arrel de la biblioteca (buit per a XDG)
+  profile  string  // Perfil dins dels directoris XDG (buit per defecte)
+  store    int     // Organització del directori 'files'
   db_name  *string // Nom base de dades
   cmd_name *string // Nom json commandaments
 }
 
 
-func NewDirs() *Dirs {
+func NewDirs( lib *Library ) *Dirs {
   
   ret:= Dirs{
-    db_name : nil,
+    root     : lib.root,
+    profile  : lib.profile,
+    store    : STORE_NAME,
+    db_name  : nil,
     cmd_name : nil,
   }
 
@@ -65,14 +126,50 @@ func NewDirs() *Dirs {
 } // end NewDirs
 
 
+// Directori dins dels directoris XDG.
+func (self *Dirs) getXDGName() string {
+
+  if self.profile == "" { return _ROOT_NAME }
+
+  return path.Join ( _ROOT_NAME, _ROOT_PROFILES, self.profile )
+  
+} // end getXDGName
+
+
+// Nom absolut d'un fitxer de dades. Crea els directoris que falten.
+func (self *Dirs) dataFile( rel_name string ) (string,error) {
+
+  if self.root == "" {
+    return xdg.DataFile ( path.Join ( self.getXDGName (), rel_name ) )
+  }
+
+  return makeParentDirs ( filepath.Join ( self.root,
+    filepath.FromSlash ( rel_name ) ) )
+  
+} // end dataFile
+
+
+// Nom absolut d'un fitxer de la memòria cau. Crea els directoris que
+// falten.
+func (self *Dirs) cacheFile( rel_name string ) (string,error) {
+
+  if self.root == "" {
+    return xdg.CacheFile ( path.Join ( self.getXDGName (), rel_name ) )
+  }
+
+  return makeParentDirs ( filepath.Join ( self.root, _ROOT_CACHE,
+    filepath.FromSlash ( rel_name ) ) )
+  
+} // end cacheFile
+
+
 func (self *Dirs) GetDatabaseName() (string,error) {
 
   var ret string
   var err error
   
   if self.db_name == nil {
-    path:= path.Join ( _ROOT_NAME, "database.db" )
-    ret,err= xdg.DataFile ( path )
+    ret,err= self.dataFile ( "database.db" )
     if err == nil {
       self.db_name= &ret
     }
@@ -91,8 +188,7 @@ func (self *Dirs) GetCommandsConfName() (string,error) {
   var err error
   
   if self.cmd_name == nil {
-    path:= path.Join ( _ROOT_NAME, "commands.json" )
-    ret,err= xdg.DataFile ( path )
+    ret,err= self.dataFile ( "commands.json" )
     if err == nil {
       self.cmd_name= &ret
     }
@@ -105,6 +201,14 @@ func (self *Dirs) GetCommandsConfName() (string,error) {
 } // end GetCommandsConfName
 
 
+// Nom absolut d'un fitxer del directori de dades a partir del seu
+// nom relatiu (p.e. "files/NES/smb.nes"). Crea els directoris que
+// falten.
+func (self *Dirs) GetDataFileName( rel_name string ) (string,error) {
+  return self.dataFile ( rel_name )
+} // end GetDataFileName
+
+
 func (self *Dirs) GetEntryFolder(
   
   platform string,
@@ -112,11 +216,11 @@ func (self *Dirs) GetEntryFolder(
   
 ) (string,error) {
 
-  mpath:= path.Join ( _ROOT_NAME, _ROOT_ENTRIES, platform, name, "kk.kk" )
-  ret,err:= xdg.DataFile ( mpath )
+  mpath:= path.Join ( _ROOT_ENTRIES, platform, name, "kk.kk" )
+  ret,err:= self.dataFile ( mpath )
   if err != nil { return "",err }
 
-  return path.Dir ( ret ),nil
+  return filepath.Dir ( ret ),nil
   
 } // end GetEntryFolder
 
@@ -129,8 +233,8 @@ func (self *Dirs) GetFileNameEntries(
   
 ) (string,error) {
 
-  tmp:= path.Join ( _ROOT_NAME, _ROOT_ENTRIES, platform, entry, name )
-  ret,err:= xdg.DataFile ( tmp )
+  tmp:= path.Join ( _ROOT_ENTRIES, platform, entry, name )
+  ret,err:= self.dataFile ( tmp )
   if err != nil { return "",err }
   
   return ret,nil
@@ -145,8 +249,8 @@ func (self *Dirs) GetFileNameFiles(
   
 ) (string,error) {
 
-  tmp:= path.Join ( _ROOT_NAME, _ROOT_FILES, file_type, name )
-  ret,err:= xdg.DataFile ( tmp )
+  tmp:= path.Join ( _ROOT_FILES, file_type, name )
+  ret,err:= self.dataFile ( tmp )
   if err != nil { return "",err }
 
   return ret,nil
@@ -154,6 +258,21 @@ func (self *Dirs) GetFileNameFiles(
 } // end GetFileNameFiles
 
 
+// Nom absolut d'un fitxer de 'files' segons l'organització de la
+// biblioteca. Crea els directoris que falten.
+func (self *Dirs) GetFileNameStore(
+
+  file_type   string,
+  name        string,
+  sha1        string,
+  compression string,
+  
+) (string,error) {
+  return self.dataFile ( storeRelName ( self.store, file_type, name, sha1,
+    compression ) )
+} // end GetFileNameStore
+
+
 func (self *Dirs) GetFileNameTemp(
 
   file_type string,
@@ -161,8 +280,8 @@ func (self *Dirs) GetFileNameTemp(
   
 ) (string,error) {
 
-  tmp:= path.Join ( _ROOT_NAME, _ROOT_FILES, file_type, name )
-  ret,err:= xdg.CacheFile ( tmp )
+  tmp:= path.Join ( _ROOT_FILES, file_type, name )
+  ret,err:= self.cacheFile ( tmp )
   if err != nil { return "",err }
   
   return ret,nil
@@ -170,12 +289,22 @@ func (self *Dirs) GetFileNameTemp(
 } // end GetFileNameTemp
 
 
+func (self *Dirs) GetStore() int { return self.store }
+func (self *Dirs) SetStore( store int ) { self.store= store }
+
+
+// Nom absolut d'un fitxer de la memòria cau a partir del seu nom
+// relatiu. Crea els directoris que falten.
+func (self *Dirs) GetCachedFileName( rel_name string ) (string,error) {
+  return self.cacheFile ( rel_name )
+} // end GetCachedFileName
+
+
 func (self *Dirs) GetCachedImageName( max_wh int, id string ) (string,error) {
 
   
-  tmp:= path.Join ( _ROOT_NAME, "images",
-    strconv.FormatInt ( int64(max_wh), 10 ), id )
-  ret,err:= xdg.CacheFile ( tmp )
+  tmp:= path.Join ( "images", strconv.FormatInt ( int64(max_wh), 10 ), id )
+  ret,err:= self.cacheFile ( tmp )
   if err != nil { return "",err }
   
   return ret,nil
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  duplicates.go - Fitxers repetits en la biblioteca. Dos fitxers són
+ *                  el mateix si tenen el mateix SHA1 sense capçalera
+ *                  (o el SHA1 si encara no s'ha calculat).
+ */
+
+package model
+
+import (
+  "fmt"
+  "log"
+  "slices"
+
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _DuplicateFile struct {
+  key      string
+  id       int64
+  name     string
+  entry_id int64
+  entry    string
+  platform string
+}
+
+
+func (self *_DuplicateFile) String() string {
+  return fmt.Sprintf ( "fitxer %d ('%s') de l'entrada [%s] %s",
+    self.id, self.name, self.platform, self.entry )
+} // end String
+
+
+// Per als fitxers afegits sense interacció (p.e. des d'un ZIP).
+func logDuplicates( name string, dups []string ) {
+  for _,d:= range dups {
+    log.Printf ( "Avís: '%s' és un duplicat del %s", name, d )
+  }
+} // end logDuplicates
+
+
+type _DuplicateGroup struct {
+  key   string
+  files []_DuplicateFile
+}
+
+
+// Fitxers amb la mateixa clau que 'file_id', inclòs ell mateix.
+func (self *Model) getDuplicateGroup( file_id int64 ) ([]_DuplicateFile,error) {
+
+  if !self.files.Exists ( file_id ) {
+    return nil,fmt.Errorf ( "El fitxer indicat (%d) no existeix", file_id )
+  }
+  f:= self.files.Get ( file_id )
+
+  return self.db.GetFilesWithKey ( f.hashes.getKey () )
+
+} // end getDuplicateGroup
+
+
+func (self *Model) removeFile( entry_id int64, file_id int64 ) error {
+
+  e,err:= self.entries.getEntry ( entry_id )
+  if err != nil { return err }
+
+  return e.RemoveFile ( file_id )
+
+} // end removeFile
+
+
+// Passa les etiquetes de l'entrada 'src' a 'dst' i elimina 'src'.
+func (self *Model) mergeEntry( dst_id int64, src_id int64 ) error {
+
+  src,err:= self.entries.getEntry ( src_id )
+  if err != nil { return err }
+  dst,err:= self.entries.getEntry ( dst_id )
+  if err != nil { return err }
+  dst_labels:= dst.GetLabelIDs ()
+  for _,id:= range src.GetLabelIDs () {
+    if slices.Contains ( dst_labels, id ) { continue }
+    if err:= dst.AddLabel ( id ); err != nil { return err }
+  }
+
+  if err:= self.entries.Remove ( src_id ); err != nil { return err }
+  self.cmds.removeEntry ( src_id )
+
+  return nil
+
+} // end mergeEntry
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+type DuplicatesReport struct {
+  groups []_DuplicateGroup
+}
+
+
+func NewDuplicatesReport( files *Files ) (*DuplicatesReport,error) {
+
+  dups,err:= files.db.GetDuplicateFiles ()
+  if err != nil { return nil,err }
+  ret:= DuplicatesReport{
+    groups : make([]_DuplicateGroup,0),
+  }
+  for _,f:= range dups {
+    n:= len(ret.groups)
+    if n == 0 || ret.groups[n-1].key != f.key {
+      ret.groups= append(ret.groups,_DuplicateGroup{key : f.key})
+      n++
+    }
+    ret.groups[n-1].files= append(ret.groups[n-1].files,f)
+  }
+
+  return &ret,nil
+
+} // end NewDuplicatesReport
+
+
+func (self *DuplicatesReport) GetNumGroups() int { return len(self.groups) }
+func (self *DuplicatesReport) GetHash( group int ) string {
+  return self.groups[group].key
+} // end GetHash
+func (self *DuplicatesReport) GetNumFiles( group int ) int {
+  return len(self.groups[group].files)
+} // end GetNumFiles
+func (self *DuplicatesReport) GetFileID( group int, i int ) int64 {
+  return self.groups[group].files[i].id
+} // end GetFileID
+func (self *DuplicatesReport) GetEntryID( group int, i int ) int64 {
+  return self.groups[group].files[i].entry_id
+} // end GetEntryID
+func (self *DuplicatesReport) GetFileName( group int, i int ) string {
+  return self.groups[group].files[i].name
+} // end GetFileName
+
+
+// Descripció del fitxer: nom i entrada.
+func (self *DuplicatesReport) GetFileText( group int, i int ) string {
+
+  f:= &self.groups[group].files[i]
+
+  return fmt.Sprintf ( "%s  -  [%s] %s", f.name, f.platform, f.entry )
+
+} // end GetFileText
+
+
+func (self *Model) GetDuplicates() (view.DuplicatesReport,error) {
+
+  ret,err:= NewDuplicatesReport ( self.files )
+  if err != nil { return nil,err }
+
+  return ret,nil
+
+} // end GetDuplicates
+
+
+// Elimina una còpia d'un fitxer repetit. No es pot eliminar l'última
+// còpia.
+func (self *Model) RemoveDuplicate( file_id int64 ) error {
+
+  group,err:= self.getDuplicateGroup ( file_id )
+  if err != nil { return err }
+  if len(group) < 2 {
+    return fmt.Errorf ( "El fitxer indicat (%d) no està repetit", file_id )
+  }
+  for _,f:= range group {
+    if f.id == file_id {
+      return self.removeFile ( f.entry_id, f.id )
+    }
+  }
+
+  return nil
+
+} // end RemoveDuplicate
+
+
+// Conserva el fitxer 'keep_id' i elimina la resta de còpies. Les
+// entrades que es queden sense fitxers s'eliminen i les seues
+// etiquetes passen a l'entrada del fitxer conservat.
+func (self *Model) MergeDuplicates( keep_id int64 ) error {
+
+  group,err:= self.getDuplicateGroup ( keep_id )
+  if err != nil { return err }
+  keep_entry:= self.files.Get ( keep_id ).GetEntryID ()
+  for _,f:= range group {
+    if f.id == keep_id { continue }
+    if err:= self.removeFile ( f.entry_id, f.id ); err != nil {
+      return err
+    }
+    if f.entry_id == keep_entry { continue }
+    e,err:= self.entries.getEntry ( f.entry_id )
+    if err != nil { return err }
+    if len(e.GetFileIDs ()) == 0 {
+      if err:= self.mergeEntry ( keep_entry, f.entry_id ); err != nil {
+        return err
+      }
+    }
+  }
+
+  return nil
+
+} // end MergeDuplicates
@@ -55,6 +55,40 @@ func (self *Entries) add(
 } // end add
 
 
+// Torna l'entrada encara que no estiga en el filtre actual. En eixe
+// cas la carrega (sense afegir-la a la llista d'identificadors).
+func (self *Entries) getEntry( id int64 ) (*Entry,error) {
+
+  if e,ok:= self.v[id]; ok { return e,nil }
+  name,platform_id,cover_id,err:= self.db.GetEntry ( id )
+  if err != nil {
+    return nil,fmt.Errorf ( "La entrada indicada (%d) no existeix", id )
+  }
+  e:= NewEntry ( self, id, name, platform_id, cover_id )
+  self.v[id]= e
+  
+  return e,nil
+  
+} // end getEntry
+
+
+// Nom de l'enllaç del fitxer en el directori de l'entrada.
+func (self *Entries) getFileLinkName( f *File ) (string,error) {
+
+  e,err:= self.getEntry ( f.GetEntryID () )
+  if err != nil { return "",err }
+  plat:= self.plats.GetPlatform ( e.GetPlatformID () )
+  if plat == nil {
+    return "",fmt.Errorf ( "La plataforma indicada (%d) no existeix",
+      e.GetPlatformID () )
+  }
+
+  return self.dirs.GetFileNameEntries ( plat.GetShortName (), e.GetName (),
+    f.getStoredName () )
+  
+} // end getFileLinkName
+
+
 func (self *Entries) reset() {
 
   // Reseteja
@@ -158,24 +192,41 @@ func (self *Entries) AddFileEntry(
   file_type int,
   create_pb func() view.ProgressBar,
   
-) error {
+) ([]string,error) {
   
   // Obtindre entrada
   e,ok:= self.v[id]
   if !ok { // No deuria passar
-    return fmt.Errorf ( "La entrada indicada (%d) no existeix", id )
+    return nil,fmt.Errorf ( "La entrada indicada (%d) no existeix", id )
   }
 
   // Afegeix
-  if err:= self.files.Add ( e, path, name, file_type, create_pb ); err != nil {
-    return err
-  }
-  
-  return nil
+  return self.files.Add ( e, path, name, file_type, create_pb )
   
 } // end AddFileEntry
 
 
+func (self *Entries) AddFilesFromArchiveEntry(
+
+  id        int64,
+  path      string,
+  members   []string,
+  keep      bool,
+  create_pb func() view.ProgressBar,
+  
+) (int,error) {
+  
+  // Obtindre entrada
+  e,ok:= self.v[id]
+  if !ok { // No deuria passar
+    return 0,fmt.Errorf ( "La entrada indicada (%d) no existeix", id )
+  }
+
+  return self.files.AddFromArchive ( e, path, members, keep, create_pb )
+  
+} // end AddFilesFromArchiveEntry
+
+
 func (self *Entries) AddLabelEntry( id int64, label_id int ) error {
 
   if err:= self.db.RegisterEntryLabelPair ( id, label_id ); err != nil {
@@ -187,6 +238,21 @@ func (self *Entries) AddLabelEntry( id int64, label_id int ) error {
 } // end AddLabelEntry
 
 
+func (self *Entries) CheckIntegrityEntry(
+
+  id        int64,
+  create_pb func() view.ProgressBar,
+
+) (*IntegrityReport,error) {
+
+  ifiles,err:= self.db.GetEntryIntegrityFiles ( id )
+  if err != nil { return nil,err }
+
+  return self.files.checkIntegrity ( ifiles, create_pb )
+
+} // end CheckIntegrityEntry
+
+
 func (self *Entries) Filter( query *Query ) {
 
   self.db.SetQuery ( query )
@@ -155,6 +155,8 @@ func NewEntry(
 } // end NewEntry
 
 
+// Torna la descripció dels fitxers idèntics que ja estaven en la
+// biblioteca.
 func (self *Entry) AddFile(
 
   path      string,
@@ -162,22 +164,42 @@ func (self *Entry) AddFile(
   file_type int,
   create_pb func() view.ProgressBar,
   
-) error {
+) ([]string,error) {
 
   // Afegeix
-  if err:= self.entries.AddFileEntry ( self.id, path, name,
-    file_type, create_pb ); err != nil {
-    return err
-  }
+  dups,err:= self.entries.AddFileEntry ( self.id, path, name,
+    file_type, create_pb )
+  if err != nil { return nil,err }
   
   // Reseteja.
   self.resetFiles ()
 
-  return nil
+  return dups,nil
   
 } // end AddFile
 
 
+func (self *Entry) AddFilesFromArchive(
+
+  path      string,
+  members   []string,
+  keep      bool,
+  create_pb func() view.ProgressBar,
+  
+) (int,error) {
+
+  // Afegeix
+  n,err:= self.entries.AddFilesFromArchiveEntry ( self.id, path, members,
+    keep, create_pb )
+  
+  // Reseteja (encara que haja fallat poden haver-se afegit fitxers).
+  if n > 0 { self.resetFiles () }
+
+  return n,err
+  
+} // end AddFilesFromArchive
+
+
 func (self *Entry) AddLabel( id int ) error {
 
   // Afegeix
@@ -193,6 +215,18 @@ func (self *Entry) AddLabel( id int ) error {
 } // end AddLabel
 
 
+func (self *Entry) CheckIntegrity(
+  create_pb func() view.ProgressBar,
+) (view.IntegrityReport,error) {
+
+  ret,err:= self.entries.CheckIntegrityEntry ( self.id, create_pb )
+  if err != nil { return nil,err }
+
+  return ret,nil
+  
+} // end CheckIntegrity
+
+
 func (self *Entry) GetCover( max_wh int ) image.Image {
 
   var ret image.Image
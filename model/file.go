@@ -28,7 +28,10 @@ import (
   "image/png"
   "log"
   "os"
+  "path"
+  "strconv"
   
+  "github.com/adriagipas/imgteka/model/dat"
   "github.com/adriagipas/imgteka/model/file_type"
   "github.com/adriagipas/imgteka/view"
   "github.com/nfnt/resize"
@@ -125,14 +128,16 @@ type File struct {
   
   dirs         *Dirs
   cmds         *Commands
+  dats         *Dats
   id           int64
   name         string
   entry        int64
   file_type_id int
   file_type    file_type.FileType
   size         int64
-  md5          string
-  sha1         string
+  hashes       _Hashes // CRC32, SHA-256 i reals buits si no s'han calculat
+  compression  string  // Compressió en 'files' (buit si no en té)
+  last_check   int64   // Data (Unix) de l'última comprovació correcta
 
   // Metadata
   md []view.StringPair
@@ -144,15 +149,16 @@ func NewFile(
 
   dirs        *Dirs,
   cmds        *Commands,
+  dats        *Dats,
   id           int64,
   name         string,
   entry        int64,
   file_type_id int,
   size         int64,
-  md5          string,
-  sha1         string,
+  hashes       *_Hashes,
   json         string,
   last_check   int64,
+  compression  string,
   
 ) *File {
 
@@ -160,23 +166,42 @@ func NewFile(
   ret:= File{
     dirs         : dirs,
     cmds         : cmds,
+    dats         : dats,
     id           : id,
     name         : name,
     entry        : entry,
     file_type_id : file_type_id,
     size         : size,
-    md5          : md5,
-    sha1         : sha1,
+    hashes       : *hashes,
+    compression  : compression,
+    last_check   : last_check,
   }
   var err error
   ret.file_type,err= file_type.Get ( file_type_id )
   if err != nil { log.Fatal ( err ) }
 
   // Crea metadata
-  ret.md= make([]view.StringPair,3)
-  ret.md[0]= &MetadataValue{"md5",md5}
-  ret.md[1]= &MetadataValue{"sha1",sha1}
-  ret.md[2]= &MetadataValue{"Grandària",size2text ( size )}
+  ret.md= make([]view.StringPair,2,8)
+  ret.md[0]= &MetadataValue{"md5",hashes.md5}
+  ret.md[1]= &MetadataValue{"sha1",hashes.sha1}
+  if hashes.crc32 != "" {
+    ret.md= append(ret.md,&MetadataValue{"crc32",hashes.crc32})
+  }
+  if hashes.sha256 != "" {
+    ret.md= append(ret.md,&MetadataValue{"sha256",hashes.sha256})
+  }
+  if hashes.real_sha1 != "" && hashes.real_sha1 != hashes.sha1 {
+    ret.md= append(ret.md,&MetadataValue{"md5 (sense capçalera)",
+      hashes.real_md5})
+    ret.md= append(ret.md,&MetadataValue{"sha1 (sense capçalera)",
+      hashes.real_sha1})
+    ret.md= append(ret.md,&MetadataValue{"crc32 (sense capçalera)",
+      hashes.real_crc32})
+  }
+  ret.md= append(ret.md,&MetadataValue{"Grandària",size2text ( size )})
+  if compression != "" {
+    ret.md= append(ret.md,&MetadataValue{"Compressió",compression})
+  }
   ret.md= ret.file_type.ParseMetadata ( ret.md, json )
   
   return &ret
@@ -184,6 +209,47 @@ func NewFile(
 } // end NewFile
 
 
+// Torna l'estat del fitxer respecte als DATs importats i el nom
+// canònic de la ROM (cadena buida si no s'ha trobat).
+func (self *File) GetDatStatus() (string,string) {
+
+  status,matches:= self.dats.Match ( &self.hashes, self.size )
+  name:= ""
+  for _,m:= range matches {
+    if m.Status != dat.ROM_STATUS_BADDUMP {
+      name= m.Rom
+      break
+    } else if name == "" {
+      name= m.Rom
+    }
+  }
+  
+  return DatStatusToText ( status ),name
+  
+} // end GetDatStatus
+
+
+// Torna el CRC32. Si encara no s'ha calculat (fitxers registrats amb
+// versions anteriors) el calcula llegint el fitxer.
+func (self *File) loadCRC32() (string,error) {
+
+  if self.hashes.crc32 != "" { return self.hashes.crc32,nil }
+  f,err:= openStoreFile ( self.GetStoredPath (), self.compression )
+  if err != nil {
+    return "",fmt.Errorf ( "No s'ha pogut obrir el fitxer '%s': %s",
+      self.GetStoredPath (), err )
+  }
+  defer f.Close ()
+  hashes,err:= calcHashes ( f, self.size )
+  if err != nil { return "",err }
+  self.hashes= hashes
+  
+  return self.hashes.crc32,nil
+  
+} // end loadCRC32
+
+
+func (self *File) GetCRC32() string { return self.hashes.crc32 }
 func (self *File) GetEntryID() int64 { return self.entry }
 
 
@@ -194,7 +260,7 @@ func (self *File) GetImage( max_wh int ) image.Image {
   if self.file_type.IsImage () {
 
     // Nom en la cache
-    cache_name:= fmt.Sprintf ( "%d-%s.png", self.id, self.md5)
+    cache_name:= fmt.Sprintf ( "%d-%s.png", self.id, self.hashes.md5)
     cache_fn,err:= self.dirs.GetCachedImageName ( max_wh, cache_name )
     if err != nil {
       log.Printf ( "Error inesperat en File.GetImage: %s", err )
@@ -233,23 +299,107 @@ func (self *File) GetImage( max_wh int ) image.Image {
 } // end GetImage
 
 
+// Nom del fitxer en 'files' i en l'entrada (amb l'extensió de la
+// compressió).
+func (self *File) getStoredName() string {
+  return compressedName ( self.name, self.compression )
+} // end getStoredName
+
+
+// Nom del fitxer en el directori de la memòria cau de l'entrada, on
+// es descomprimeixen tots els fitxers de l'entrada amb el seu nom.
+func (self *File) getCachedName() (string,error) {
+  return self.dirs.GetCachedFileName ( path.Join ( _CACHE_UNCOMPRESSED,
+    strconv.FormatInt ( self.entry, 10 ), self.name ) )
+} // end getCachedName
+
+
+// Torna el fitxer en el directori de la memòria cau de l'entrada: la
+// còpia sense comprimir o, si no està comprimit, un enllaç al de
+// 'files'.
+func (self *File) getCachedPath() (string,error) {
+
+  if self.compression != "" { return self.getUncompressedPath () }
+  ret,err:= self.getCachedName ()
+  if err != nil { return "",err }
+  target:= self.GetStoredPath ()
+  if cur,err:= os.Readlink ( ret ); err == nil && cur == target {
+    return ret,nil
+  }
+  os.Remove ( ret )
+  if err:= os.Symlink ( target, ret ); err != nil { return "",err }
+
+  return ret,nil
+  
+} // end getCachedPath
+
+
+// Crea, si no existeix ja, una còpia sense comprimir en la memòria
+// cau i torna el seu nom.
+func (self *File) getUncompressedPath() (string,error) {
+
+  ret,err:= self.getCachedName ()
+  if err != nil { return "",err }
+  if info,err:= os.Stat ( ret ); err == nil && info.Size () == self.size {
+    return ret,nil
+  }
+  if err:= uncompressFile ( self.GetStoredPath (), ret,
+    self.compression ); err != nil {
+    return "",fmt.Errorf ( "No s'ha pogut descomprimir '%s': %s",
+      self.GetStoredPath (), err )
+  }
+
+  return ret,nil
+  
+} // end getUncompressedPath
+
+
+func (self *File) GetCompression() string { return self.compression }
+func (self *File) GetID() int64 { return self.id }
+func (self *File) GetLastCheck() int64 { return self.last_check }
+func (self *File) GetMD5() string { return self.hashes.md5 }
+
+
 func (self *File) GetMetadata() []view.StringPair { return self.md }
 
 
 func (self *File) GetName() string { return self.name }
 
 
+// Camí per a obrir el fitxer. Si està comprimit és una còpia
+// temporal sense comprimir (buit si no s'ha pogut crear).
 func (self *File) GetPath() string {
 
-  ret,err:= self.dirs.GetFileNameFiles (
-    self.file_type.GetShortName (), self.name )
-  if err != nil { log.Fatal ( err ) }
+  if self.compression == "" { return self.GetStoredPath () }
+  ret,err:= self.getUncompressedPath ()
+  if err != nil {
+    log.Print ( err )
+    return ""
+  }
 
   return ret
   
 } // end GetPath
 
 
+// Camí del fitxer en 'files', possiblement comprimit.
+func (self *File) GetStoredPath() string {
+
+  ret,err:= self.dirs.GetFileNameStore ( self.file_type.GetShortName (),
+    self.name, self.hashes.sha1, self.compression )
+  if err != nil { log.Fatal ( err ) }
+
+  return ret
+  
+} // end GetStoredPath
+
+
+func (self *File) GetRealCRC32() string { return self.hashes.real_crc32 }
+func (self *File) GetRealMD5() string { return self.hashes.real_md5 }
+func (self *File) GetRealSHA1() string { return self.hashes.real_sha1 }
+func (self *File) GetSHA1() string { return self.hashes.sha1 }
+func (self *File) GetSHA256() string { return self.hashes.sha256 }
+func (self *File) GetSize() int64 { return self.size }
 func (self *File) GetTypeID() int { return self.file_type_id }
 
 
@@ -258,11 +408,27 @@ func (self *File) IsImage() bool {
 } // end IsImage
 
 
+func (self *File) GetLaunchers() []string {
+  return self.cmds.GetFileLaunchers ( self )
+} // end GetLaunchers
+
+
+func (self *File) GetLastLauncher() string {
+  return self.cmds.GetLastLauncher ( self.entry )
+} // end GetLastLauncher
+
+
 func (self *File) Run() error {
-  return self.cmds.Run ( self.file_type_id, self.GetPath () )
+  return self.RunLauncher ( "" )
 } // end Run
 
 
+func (self *File) RunLauncher( name string ) error {
+  return self.cmds.Run ( self, name )
+  
+} // end RunLauncher
+
+
 
 
 // METADATAVALUE ///////////////////////////////////////////////////////////////
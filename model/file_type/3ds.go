@@ -190,3 +190,15 @@ func (self *N3DS) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_3DS,
+    ShortName  : "3DS",
+    Extensions : []string{".3ds",".cci"},
+    Detect     : sniffMagic ( 0x100, "NCSD", 100 ),
+    Caps       : CAP_IMAGE,
+    Type       : &N3DS{},
+  })
+} // end init
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  archive.go - Accés als fitxers continguts en fitxers
+ *               d'emmagatzemament (ZIP, TAR).
+ */
+
+package file_type
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "time"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Lector d'un fitxer contingut que en tancar-se tanca també el
+// fitxer que el conté.
+type _MemberReader struct {
+  r  io.Reader
+  rc io.Closer // Pot ser nil
+  fd *os.File
+}
+
+
+func (self *_MemberReader) Read( buf []byte ) (int,error) {
+  return self.r.Read ( buf )
+} // end Read
+
+
+func (self *_MemberReader) Close() error {
+
+  if self.rc != nil {
+    if err:= self.rc.Close (); err != nil {
+      self.fd.Close ()
+      return err
+    }
+  }
+  
+  return self.fd.Close ()
+  
+} // end Close
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Tipus que contenen altres fitxers i permeten llegir-los. Els
+// fitxers d'emmagatzemament (CAP_CONTAINER) a més es poden importar
+// fitxer a fitxer, les imatges de disc sols es poden explorar.
+type Archive interface {
+
+  // Torna els fitxers (no els directoris) que conté el fitxer
+  // indicat en l'ordre en què estan emmagatzemats. Els noms inclouen
+  // la ruta separada per '/'.
+  GetMembers(file_name string) ([]*ArchiveMember,error)
+
+  // Obri per a llegir el contingut (sense comprimir) del fitxer
+  // 'member' del fitxer indicat.
+  OpenMember(file_name string,member string) (io.ReadCloser,error)
+  
+}
+
+
+type ArchiveMember struct {
+  name string
+  size int64
+  date time.Time // Zero si no es coneix
+}
+func (self *ArchiveMember) GetDate() time.Time { return self.date }
+func (self *ArchiveMember) GetName() string { return self.name }
+func (self *ArchiveMember) GetSize() int64 { return self.size }
+
+
+// Torna la implementació d'Archive del tipus indicat. Falla si el
+// tipus no permet llegir els fitxers que conté.
+func GetArchive( id int ) (Archive,error) {
+
+  reg,err:= GetRegistration ( id )
+  if err != nil { return nil,err }
+  ret,ok:= reg.Type.(Archive)
+  if !ok {
+    return nil,fmt.Errorf ( "No es poden llegir els fitxers continguts en"+
+      " un fitxer de tipus %s", reg.ShortName )
+  }
+
+  return ret,nil
+  
+} // end GetArchive
@@ -57,3 +57,12 @@ func (self *BIN) ParseMetadata(
 ) []view.StringPair {
   return v
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_BIN,
+    ShortName  : "BIN",
+    Type       : &BIN{},
+  })
+} // end init
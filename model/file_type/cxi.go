@@ -657,3 +657,15 @@ func (self *CXI) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_EXE_CXI,
+    ShortName  : "CXI",
+    Extensions : []string{".cxi"},
+    Detect     : sniffMagic ( 0x100, "NCCH", 100 ),
+    Caps       : CAP_IMAGE|CAP_EXECUTABLE,
+    Type       : &CXI{},
+  })
+} // end init
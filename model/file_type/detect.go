@@ -0,0 +1,158 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  detect.go - Detecció automàtica del tipus d'un fitxer.
+ */
+
+package file_type
+
+import (
+  "bytes"
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "slices"
+  "sort"
+  "strings"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Bytes de l'inici del fitxer que es consulten. Inclou el descriptor
+// de volum primari d'una imatge ISO-9660 en format cru (0x9319).
+const _DETECT_SIZE= 0x10000
+
+
+// Puntuació mínima. La té sempre el tipus binari genèric.
+const _SCORE_BIN= 1
+
+
+// Bonificació quan l'extensió del fitxer és una de les registrades
+// pel tipus. Sols desempata entre tipus que ja s'han detectat.
+const _SCORE_EXT= 5
+
+
+func hasMagic( data []byte, offset int, magic string ) bool {
+
+  end:= offset+len(magic)
+
+  return end <= len(data) && string(data[offset:end]) == magic
+
+} // end hasMagic
+
+
+// Imatges ISO-9660 (cuinades o crues) i fitxers CUE.
+func sniffCD( data []byte, size int64 ) bool {
+
+  if hasMagic ( data, 0x8001, "CD001" ) || // 2048 bytes/sector
+    hasMagic ( data, 0x9311, "CD001" ) || // 2352 bytes/sector, mode 1
+    hasMagic ( data, 0x9319, "CD001" ) { // 2352 bytes/sector, mode 2
+    return true
+  }
+  if size < 4096 {
+    text:= bytes.ToUpper ( data )
+    return bytes.Contains ( text, []byte("FILE") ) &&
+      bytes.Contains ( text, []byte("TRACK") )
+  }
+
+  return false
+
+} // end sniffCD
+
+
+func sniffCDScore( score int ) Detector {
+  return func( data []byte, size int64 ) int {
+    if sniffCD ( data, size ) { return score }
+    return 0
+  }
+} // end sniffCDScore
+
+
+func sniffMagic( offset int, magic string, score int ) Detector {
+  return func( data []byte, size int64 ) int {
+    if hasMagic ( data, offset, magic ) { return score }
+    return 0
+  }
+} // end sniffMagic
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Tipus candidat per a un fitxer.
+type Candidate struct {
+  id,score int
+}
+func (self *Candidate) GetID() int { return self.id }
+func (self *Candidate) GetScore() int { return self.score }
+
+
+// Torna els tipus que poden correspondre al fitxer ordenats de més a
+// menys probable. Primer es consulta el detector de cada tipus
+// registrat i després es comprova amb GetMetadata que el fitxer es pot
+// interpretar. L'últim candidat sempre és el binari genèric.
+func Detect( file_name string ) ([]Candidate,error) {
+
+  // Llig l'inici
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+  info,err:= fd.Stat ()
+  if err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut obtindre la grandària: %s", err )
+  }
+  size:= info.Size ()
+  data:= make([]byte,_DETECT_SIZE)
+  n,err:= io.ReadFull ( fd, data )
+  if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+    return nil,err
+  }
+  data= data[:n]
+  ext:= strings.ToLower ( filepath.Ext ( file_name ) )
+
+  // Puntua
+  ret:= make([]Candidate,0,4)
+  for _,id:= range _IDS {
+    reg:= _REGS[id]
+    if reg.Detect == nil { continue }
+    score:= reg.Detect ( data, size )
+    if score == 0 { continue }
+    if _,err:= reg.Type.GetMetadata ( file_name ); err != nil { continue }
+    if slices.Contains ( reg.Extensions, ext ) {
+      score+= _SCORE_EXT
+    }
+    ret= append(ret,Candidate{id,score})
+  }
+  sort.SliceStable ( ret, func(i,j int) bool {
+    return ret[i].score > ret[j].score
+  })
+  ret= append(ret,Candidate{ID_BIN,_SCORE_BIN})
+
+  return ret,nil
+
+} // end Detect
@@ -0,0 +1,494 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  fat.go - Lectura dels directoris i fitxers dels volums FAT12 i
+ *           FAT16 (disquets i particions de discs durs).
+ */
+
+package file_type
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "strings"
+  "time"
+
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Nombre màxim de fitxers que es desen en les metadades.
+const _FAT_MAX_LISTED_FILES = 500
+
+
+type _FAT_FileInfo struct {
+
+  Name string
+  Size int64
+  
+}
+
+
+// Contingut d'un volum que es desa en les metadades.
+type _FAT_Contents struct {
+
+  Label     string          // Etiqueta del directori arrel
+  NFiles    int
+  Files     []_FAT_FileInfo // Com a molt _FAT_MAX_LISTED_FILES
+  FreeBytes int64
+  
+}
+
+
+func (self *_FAT_Contents) Parse(
+
+  v      []view.StringPair,
+  prefix string,
+  
+) []view.StringPair {
+
+  var kv *KeyValue
+
+  // Etiqueta
+  if self.Label != "" {
+    kv= &KeyValue{prefix+"Etiqueta directori",self.Label}
+    v= append(v,kv)
+  }
+
+  // Espai lliure
+  kv= &KeyValue{prefix+"Espai lliure",fmt.Sprintf ( "%s (%d B)",
+    NumBytesToStr ( uint64(self.FreeBytes) ), self.FreeBytes )}
+  v= append(v,kv)
+
+  // Fitxers
+  kv= &KeyValue{prefix+"Nº Fitxers",fmt.Sprint ( self.NFiles )}
+  v= append(v,kv)
+  if len(self.Files) > 0 {
+    names:= make([]string,len(self.Files))
+    for i,f:= range self.Files {
+      names[i]= f.Name
+    }
+    text:= strings.Join ( names, ", " )
+    if self.NFiles > len(self.Files) { text+= ", ..." }
+    kv= &KeyValue{prefix+"Fitxers",text}
+    v= append(v,kv)
+  }
+  
+  return v
+  
+} // end Parse
+
+
+// Volum FAT obert. Sols es carreguen en memòria la FAT i el
+// directori arrel, els clusters es llegeixen quan cal.
+type _FAT_Volume struct {
+
+  fd           *os.File
+  offset       int64 // Inici del volum dins del fitxer
+  bits         int   // 12 o 16
+  fat          []byte
+  cluster_size int
+  nclusters    int
+  root         []byte // Entrades del directori arrel
+  data_begin   int64  // Inici del cluster 2 (relatiu a 'offset')
+  
+}
+
+
+// 'size' és la grandària de la partició (0 fins al final del fitxer).
+func _FAT_OpenVolume(
+  
+  fd     *os.File,
+  offset int64,
+  size   int64,
+  
+) (*_FAT_Volume,error) {
+
+  // Grandària disponible
+  info,err:= fd.Stat ()
+  if err != nil { return nil,err }
+  if avail:= info.Size ()-offset; size <= 0 || size > avail {
+    size= avail
+  }
+  
+  // Llig el sector d'arrancada
+  boot:= make([]byte,_FAT12_SEC_SIZE)
+  if _,err:= fd.ReadAt ( boot, offset ); err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut llegir el sector d'arrancada: %s",
+      err )
+  }
+  md:= _FAT12_Metadata{}
+  if _,err:= md.readBPB ( boot ); err != nil {
+    return nil,err
+  }
+
+  // Geometria
+  bps:= int64(md.BytesPerSector)
+  reserved:= int64(boot[14]) | (int64(boot[15])<<8)
+  root_entries:= int64(boot[17]) | (int64(boot[18])<<8)
+  fat_sectors:= int64(boot[22]) | (int64(boot[23])<<8)
+  if (bps != 512 && bps != 1024 && bps != 2048 && bps != 4096) ||
+    md.SectorsPerCluster == 0 || fat_sectors == 0 || md.NumFATs == 0 {
+    return nil,errors.New ( "Geometria del volum FAT no vàlida" )
+  }
+  fat_begin:= reserved*bps
+  root_begin:= fat_begin + int64(md.NumFATs)*fat_sectors*bps
+  root_sectors:= (root_entries*32+bps-1)/bps
+  data_begin:= root_begin + root_sectors*bps
+  if root_begin > size || data_begin > size {
+    return nil,errors.New ( "La FAT o el directori arrel del volum FAT"+
+      " estan fora del fitxer" )
+  }
+  data_sectors:= int64(md.TotalSectors) - data_begin/bps
+  if data_sectors <= 0 {
+    return nil,errors.New ( "Geometria del volum FAT no vàlida" )
+  }
+  ret:= _FAT_Volume{
+    fd           : fd,
+    offset       : offset,
+    cluster_size : int(md.SectorsPerCluster)*int(bps),
+    nclusters    : int(data_sectors/int64(md.SectorsPerCluster)),
+    data_begin   : data_begin,
+  }
+
+  // El tipus de FAT depén del nombre de clusters
+  if ret.nclusters < 4085 {
+    ret.bits= 12
+  } else if ret.nclusters < 65525 {
+    ret.bits= 16
+  } else {
+    return nil,errors.New ( "Els volums FAT32 no estan suportats" )
+  }
+  
+  // Carrega FAT i directori arrel
+  ret.fat= make([]byte,fat_sectors*bps)
+  if _,err:= fd.ReadAt ( ret.fat, offset+fat_begin ); err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut llegir la FAT: %s", err )
+  }
+  ret.root= make([]byte,root_entries*32)
+  if _,err:= fd.ReadAt ( ret.root, offset+root_begin ); err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut llegir el directori arrel: %s",
+      err )
+  }
+  
+  return &ret,nil
+  
+} // end _FAT_OpenVolume
+
+
+// Torna el valor de la FAT per al cluster indicat.
+func (self *_FAT_Volume) get( cluster int ) int {
+
+  if self.bits == 16 {
+    off:= cluster*2
+    if off+1 >= len(self.fat) { return 0xFFFF }
+    return int(self.fat[off]) | (int(self.fat[off+1])<<8)
+  }
+  off:= cluster*3/2
+  if off+1 >= len(self.fat) { return 0xFFF }
+  val:= int(self.fat[off]) | (int(self.fat[off+1])<<8)
+  if cluster&1 == 1 {
+    return val>>4
+  }
+  
+  return val&0xFFF
+  
+} // end get
+
+
+// Indica si 'cluster' és un cluster de dades vàlid. Els valors de
+// final de cadena queden fora del rang.
+func (self *_FAT_Volume) isData( cluster int ) bool {
+  return cluster >= 2 && cluster < self.nclusters+2
+} // end isData
+
+
+func (self *_FAT_Volume) readCluster( cluster int, buf []byte ) error {
+
+  off:= self.offset + self.data_begin +
+    int64(cluster-2)*int64(self.cluster_size)
+  if _,err:= self.fd.ReadAt ( buf[:self.cluster_size], off ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut llegir el cluster %d: %s",
+      cluster, err )
+  }
+
+  return nil
+  
+} // end readCluster
+
+
+// Llig la cadena de clusters d'un directori.
+func (self *_FAT_Volume) readDir( cluster int ) ([]byte,error) {
+
+  ret:= make([]byte,0,self.cluster_size)
+  buf:= make([]byte,self.cluster_size)
+  for n:= 0; self.isData ( cluster ); n++ {
+    if n > self.nclusters {
+      return nil,errors.New ( "Cadena de clusters FAT circular" )
+    }
+    if err:= self.readCluster ( cluster, buf ); err != nil {
+      return nil,err
+    }
+    ret= append(ret,buf...)
+    cluster= self.get ( cluster )
+  }
+  
+  return ret,nil
+  
+} // end readDir
+
+
+// Recorre recursivament els fitxers (no els directoris). Les entrades
+// tenen 32 bytes. Si 'fn' torna cert para el recorregut.
+func (self *_FAT_Volume) walk(
+
+  entries []byte,
+  prefix  string,
+  fn      func(name string,entry []byte) (bool,error),
+  
+) (bool,error) {
+  return self.walkDir ( entries, prefix, 0, make(map[int]bool), fn )
+} // end walk
+
+
+// Els directoris que ja s'han visitat (p.e. una entrada que apunta a
+// un directori pare) es boten per a evitar recorreguts infinits o
+// exponencials.
+func (self *_FAT_Volume) walkDir(
+
+  entries []byte,
+  prefix  string,
+  depth   int,
+  visited map[int]bool,
+  fn      func(name string,entry []byte) (bool,error),
+  
+) (bool,error) {
+
+  if depth > 32 {
+    return false,errors.New ( "Massa nivells de directoris en el volum" )
+  }
+  for off:= 0; off+32 <= len(entries); off+= 32 {
+    e:= entries[off:off+32]
+    if e[0] == 0x00 { break } // Final
+    if e[0] == 0xE5 { continue } // Esborrat
+    attr:= e[11]
+    if attr == 0x0F || (attr&0x08) != 0 { continue } // LFN o etiqueta
+    name:= _FAT_EntryName ( e )
+    if name == "." || name == ".." { continue }
+    if (attr&0x10) != 0 {
+      cluster:= _FAT_EntryCluster ( e )
+      if visited[cluster] { continue }
+      visited[cluster]= true
+      sub,err:= self.readDir ( cluster )
+      if err != nil { return false,err }
+      if stop,err:= self.walkDir ( sub, prefix+name+"/", depth+1, visited,
+        fn ); err != nil || stop {
+        return stop,err
+      }
+    } else if stop,err:= fn ( prefix+name, e ); err != nil || stop {
+      return stop,err
+    }
+  }
+
+  return false,nil
+  
+} // end walk
+
+
+// Etiqueta desada en el directori arrel.
+func (self *_FAT_Volume) getLabel() string {
+
+  for off:= 0; off+32 <= len(self.root); off+= 32 {
+    e:= self.root[off:off+32]
+    if e[0] == 0x00 { break }
+    if e[0] == 0xE5 || e[11] == 0x0F || (e[11]&0x08) == 0 { continue }
+    return strings.TrimRight ( string(e[0:11]), " " )
+  }
+
+  return ""
+  
+} // end getLabel
+
+
+func (self *_FAT_Volume) getFreeBytes() int64 {
+
+  var ret int64= 0
+  for c:= 2; c < self.nclusters+2; c++ {
+    if self.get ( c ) == 0 {
+      ret+= int64(self.cluster_size)
+    }
+  }
+
+  return ret
+  
+} // end getFreeBytes
+
+
+func (self *_FAT_Volume) getContents() (*_FAT_Contents,error) {
+
+  ret:= _FAT_Contents{
+    Label     : self.getLabel (),
+    FreeBytes : self.getFreeBytes (),
+  }
+  if _,err:= self.walk ( self.root, "",
+    func(name string,e []byte) (bool,error) {
+      ret.NFiles++
+      if len(ret.Files) < _FAT_MAX_LISTED_FILES {
+        ret.Files= append(ret.Files,_FAT_FileInfo{
+          Name : name,
+          Size : _FAT_EntrySize ( e ),
+        })
+      }
+      return false,nil
+    }); err != nil {
+    return nil,err
+  }
+
+  return &ret,nil
+  
+} // end getContents
+
+
+func (self *_FAT_Volume) getMembers( prefix string ) ([]*ArchiveMember,error) {
+
+  ret:= make([]*ArchiveMember,0,16)
+  if _,err:= self.walk ( self.root, prefix,
+    func(name string,e []byte) (bool,error) {
+      ret= append(ret,&ArchiveMember{
+        name : name,
+        size : _FAT_EntrySize ( e ),
+        date : _FAT_EntryDate ( e ),
+      })
+      return false,nil
+    }); err != nil {
+    return nil,err
+  }
+
+  return ret,nil
+  
+} // end getMembers
+
+
+// Torna nil si no existeix.
+func (self *_FAT_Volume) openMember( member string ) (io.Reader,error) {
+
+  var ret io.Reader= nil
+  if _,err:= self.walk ( self.root, "",
+    func(name string,e []byte) (bool,error) {
+      if name != member { return false,nil }
+      ret= &_FAT_FileReader{
+        vol     : self,
+        cluster : _FAT_EntryCluster ( e ),
+        remain  : _FAT_EntrySize ( e ),
+      }
+      return true,nil
+    }); err != nil {
+    return nil,err
+  }
+
+  return ret,nil
+  
+} // end openMember
+
+
+type _FAT_FileReader struct {
+
+  vol     *_FAT_Volume
+  cluster int
+  remain  int64
+  buf     []byte
+  nread   int // Clusters llegits, per a detectar cadenes circulars
+  
+}
+
+
+func (self *_FAT_FileReader) Read( p []byte ) (int,error) {
+
+  if self.remain == 0 { return 0,io.EOF }
+
+  // Carrega el següent cluster
+  if len(self.buf) == 0 {
+    if !self.vol.isData ( self.cluster ) {
+      return 0,errors.New ( "Fitxer FAT truncat" )
+    }
+    if self.nread++; self.nread > self.vol.nclusters {
+      return 0,errors.New ( "Cadena de clusters FAT circular" )
+    }
+    self.buf= make([]byte,self.vol.cluster_size)
+    if err:= self.vol.readCluster ( self.cluster, self.buf ); err != nil {
+      return 0,err
+    }
+    if int64(len(self.buf)) > self.remain {
+      self.buf= self.buf[:self.remain]
+    }
+    self.cluster= self.vol.get ( self.cluster )
+  }
+
+  // Copia
+  n:= copy ( p, self.buf )
+  self.buf= self.buf[n:]
+  self.remain-= int64(n)
+  
+  return n,nil
+  
+} // end Read
+
+
+func _FAT_EntryName( e []byte ) string {
+
+  base:= []byte(strings.TrimRight ( string(e[0:8]), " " ))
+  if len(base) > 0 && base[0] == 0x05 { base[0]= 0xE5 }
+  ext:= strings.TrimRight ( string(e[8:11]), " " )
+  if ext == "" { return string(base) }
+  
+  return string(base)+"."+ext
+  
+} // end _FAT_EntryName
+
+
+func _FAT_EntryCluster( e []byte ) int {
+  return int(e[26]) | (int(e[27])<<8)
+} // end _FAT_EntryCluster
+
+
+func _FAT_EntrySize( e []byte ) int64 {
+  return int64(e[28]) | (int64(e[29])<<8) | (int64(e[30])<<16) |
+    (int64(e[31])<<24)
+} // end _FAT_EntrySize
+
+
+// Data de modificació en hora local.
+func _FAT_EntryDate( e []byte ) time.Time {
+
+  t:= int(e[22]) | (int(e[23])<<8)
+  d:= int(e[24]) | (int(e[25])<<8)
+  if d == 0 { return time.Time{} }
+  
+  return time.Date ( 1980+(d>>9), time.Month ( (d>>5)&0xF ), d&0x1F,
+    t>>11, (t>>5)&0x3F, (t&0x1F)*2, 0, time.Local )
+  
+} // end _FAT_EntryDate
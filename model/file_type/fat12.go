@@ -27,6 +27,7 @@ import (
   "errors"
   "fmt"
   "image"
+  "io"
   "log"
   "os"
   
@@ -56,14 +57,30 @@ type _FAT12_Metadata struct {
   VolumeInfoPresent bool
   VolumeID          uint32
   VolumeLabel       string
+  Contents          *_FAT_Contents // nil si no s'ha pogut llegir
   
 }
 
 func (self *_FAT12_Metadata) Read( data []byte ) error {
 
+  fstype,err:= self.readBPB ( data )
+  if err != nil { return err }
+  if fstype != "FAT12" && fstype != "FAT" && fstype != "" {
+    return fmt.Errorf ( "Tipus de format desconegut: '%s'", fstype )
+  }
+
+  return nil
+  
+} // end Read
+
+
+// Llig els camps del sector d'arrancada comuns a FAT12 i FAT16. Torna
+// el tipus de format indicat en la informació del volum.
+func (self *_FAT12_Metadata) readBPB( data []byte ) (string,error) {
+
   // Comprova que és un boot sector. He relaxat les condicions.
   if data[0]!=0xe9 && (data[0]!=0xeb /*|| data[2]!=0x90*/) {
-    return errors.New ( "El primer sector no és executable" )
+    return "",errors.New ( "El primer sector no és executable" )
   }
 
   // OEM
@@ -103,6 +120,7 @@ func (self *_FAT12_Metadata) Read( data []byte ) error {
     (uint32(data[31])<<24)
 
   // Informació del volum
+  fstype:= ""
   self.VolumeInfoPresent= data[38]==0x29
   if self.VolumeInfoPresent {
     self.VolumeID= uint32(data[39]) |
@@ -110,20 +128,35 @@ func (self *_FAT12_Metadata) Read( data []byte ) error {
       (uint32(data[41])<<16) |
       (uint32(data[42])<<24)
     self.VolumeLabel= BytesToStr_trim_0s ( data[43:54] )
-    fstype:= BytesToStr_trim_0s ( data[54:62] )
-    if fstype != "FAT12" && fstype != "FAT" && fstype != "" {
-      return fmt.Errorf ( "Tipus de format desconegut: '%s'", fstype )
-    }
+    fstype= BytesToStr_trim_0s ( data[54:62] )
   }
 
   // Comprova la firma
   if data[510] != 0x55 || data[511] != 0xaa {
-    return errors.New ( "No es tracta d'un disquet amb format FAT12" )
+    return "",errors.New ( "No es tracta d'un volum amb format FAT" )
   }
   
-  return nil
+  return fstype,nil
   
-} // end Read
+} // end readBPB
+
+
+func sniffFAT12( data []byte, size int64 ) int {
+
+  if size < _FAT12_SEC_SIZE || len(data) < _FAT12_SEC_SIZE {
+    return 0
+  }
+  md:= _FAT12_Metadata{}
+  if md.Read ( data ) != nil { return 0 }
+  if size%_FAT12_SEC_SIZE == 0 &&
+    int64(md.TotalSectors)*int64(md.BytesPerSector) == size {
+    return 70
+  }
+
+  return 50
+
+} // end sniffFAT12
+
 
 
 
@@ -136,6 +169,47 @@ type FAT12 struct {
 }
 
 
+func (self *FAT12) GetMembers( file_name string ) ([]*ArchiveMember,error) {
+
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+  vol,err:= _FAT_OpenVolume ( fd, 0, 0 )
+  if err != nil { return nil,err }
+  
+  return vol.getMembers ( "" )
+  
+} // end GetMembers
+
+
+func (self *FAT12) OpenMember(
+
+  file_name string,
+  member    string,
+  
+) (io.ReadCloser,error) {
+
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  vol,err:= _FAT_OpenVolume ( fd, 0, 0 )
+  if err != nil {
+    fd.Close ()
+    return nil,err
+  }
+  r,err:= vol.openMember ( member )
+  if err != nil || r == nil {
+    fd.Close ()
+    if err == nil {
+      err= fmt.Errorf ( "El disquet no conté '%s'", member )
+    }
+    return nil,err
+  }
+  
+  return &_MemberReader{r:r,fd:fd},nil
+  
+} // end OpenMember
+
+
 func (self *FAT12) GetImage( file_name string) (image.Image,error) {
   return nil,fmt.Errorf (
     "No es pot interpretar com una imatge un disquet formatat com FAT12" )
@@ -173,6 +247,15 @@ func (self *FAT12) GetMetadata(file_name string) (string,error) {
   if err:= md.Read ( mem ); err != nil {
     return "",err
   }
+
+  // Contingut. Un directori corrupte no impedeix reconéixer el disquet.
+  if vol,err:= _FAT_OpenVolume ( fd, 0, 0 ); err != nil {
+    log.Printf ( "[FAT12] no s'ha pogut llegir el contingut de '%s': %s",
+      file_name, err )
+  } else if md.Contents,err= vol.getContents (); err != nil {
+    log.Printf ( "[FAT12] no s'ha pogut llegir el contingut de '%s': %s",
+      file_name, err )
+  }
   
   // Converteix a json
   b,err:= json.Marshal ( md )
@@ -254,7 +337,23 @@ func (self *FAT12) ParseMetadata(
     kv= &KeyValue{"Etiqueta volum",md.VolumeLabel}
     v= append(v,kv)
   }
+
+  // Contingut
+  if md.Contents != nil {
+    v= md.Contents.Parse ( v, "" )
+  }
   
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_FLP_FAT12,
+    ShortName  : "FAT12",
+    Extensions : []string{".img",".ima",".dsk"},
+    Detect     : sniffFAT12,
+    Type       : &FAT12{},
+  })
+} // end init
@@ -0,0 +1,513 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  fat12_write.go - Creació de disquets FAT12 buits i escriptura de
+ *                   fitxers en disquets FAT12.
+ */
+
+package file_type
+
+import (
+  "errors"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _FAT12_Format struct {
+
+  name          string
+  total_sectors int
+  spt           int // Sectors per track
+  spc           int // Sectors per cluster
+  root_entries  int
+  fat_sectors   int
+  media         uint8
+  
+}
+
+
+var _FAT12_FORMATS= []_FAT12_Format{
+  {"360K",720,9,2,112,2,0xFD},
+  {"720K",1440,9,2,112,3,0xF9},
+  {"1.44M",2880,18,1,224,9,0xF0},
+}
+
+
+// Disquet FAT12 carregat en memòria per a modificar-lo.
+type _FAT12_Image struct {
+
+  data         []byte
+  fats         [][]byte // Còpies de la FAT (totes apunten a 'data')
+  cluster_size int
+  nclusters    int
+  root         []byte
+  data_begin   int
+  
+}
+
+
+func _FAT12_LoadImage( data []byte ) (*_FAT12_Image,error) {
+
+  // Sector d'arrancada
+  if len(data) < _FAT12_SEC_SIZE {
+    return nil,errors.New (
+      "La grandària del fitxer no és correspon amb el d'un disquet" )
+  }
+  md:= _FAT12_Metadata{}
+  if err:= md.Read ( data[:_FAT12_SEC_SIZE] ); err != nil {
+    return nil,err
+  }
+
+  // Geometria
+  bps:= int(md.BytesPerSector)
+  reserved:= int(data[14]) | (int(data[15])<<8)
+  root_entries:= int(data[17]) | (int(data[18])<<8)
+  fat_sectors:= int(data[22]) | (int(data[23])<<8)
+  if bps < 32 || md.SectorsPerCluster == 0 || fat_sectors == 0 ||
+    md.NumFATs == 0 {
+    return nil,errors.New ( "Geometria del disquet FAT12 no vàlida" )
+  }
+  fat_begin:= reserved*bps
+  root_begin:= fat_begin + int(md.NumFATs)*fat_sectors*bps
+  data_begin:= root_begin + ((root_entries*32+bps-1)/bps)*bps
+  total:= int(md.TotalSectors)*bps
+  if total > len(data) || data_begin >= total {
+    return nil,errors.New ( "El disquet FAT12 està truncat" )
+  }
+  ret:= _FAT12_Image{
+    data         : data,
+    cluster_size : int(md.SectorsPerCluster)*bps,
+    root         : data[root_begin:root_begin+root_entries*32],
+    data_begin   : data_begin,
+  }
+  ret.nclusters= (total-data_begin)/ret.cluster_size
+  if ret.nclusters >= 4085 {
+    return nil,errors.New ( "El disquet no té format FAT12" )
+  }
+  if (ret.nclusters+1)*3/2+1 >= fat_sectors*bps {
+    return nil,errors.New (
+      "La FAT del disquet és massa menuda per al nombre de clústers" )
+  }
+  for i:= 0; i < int(md.NumFATs); i++ {
+    off:= fat_begin + i*fat_sectors*bps
+    ret.fats= append(ret.fats,data[off:off+fat_sectors*bps])
+  }
+  
+  return &ret,nil
+  
+} // end _FAT12_LoadImage
+
+
+func (self *_FAT12_Image) get( cluster int ) int {
+
+  fat:= self.fats[0]
+  off:= cluster*3/2
+  if off+1 >= len(fat) { return 0xFFF }
+  val:= int(fat[off]) | (int(fat[off+1])<<8)
+  if cluster&1 == 1 {
+    return val>>4
+  }
+  
+  return val&0xFFF
+  
+} // end get
+
+
+// Modifica totes les còpies de la FAT.
+func (self *_FAT12_Image) set( cluster int, val int ) {
+
+  off:= cluster*3/2
+  for _,fat:= range self.fats {
+    if off+1 >= len(fat) { continue }
+    if cluster&1 == 1 {
+      fat[off]= (fat[off]&0x0F) | uint8((val<<4)&0xF0)
+      fat[off+1]= uint8(val>>4)
+    } else {
+      fat[off]= uint8(val)
+      fat[off+1]= (fat[off+1]&0xF0) | uint8((val>>8)&0x0F)
+    }
+  }
+  
+} // end set
+
+
+func (self *_FAT12_Image) isData( cluster int ) bool {
+  return cluster >= 2 && cluster < self.nclusters+2
+} // end isData
+
+
+func (self *_FAT12_Image) getCluster( cluster int ) []byte {
+  off:= self.data_begin + (cluster-2)*self.cluster_size
+  return self.data[off:off+self.cluster_size]
+} // end getCluster
+
+
+func (self *_FAT12_Image) getNumFreeClusters() int {
+
+  ret:= 0
+  for c:= 2; c < self.nclusters+2; c++ {
+    if self.get ( c ) == 0 { ret++ }
+  }
+
+  return ret
+  
+} // end getNumFreeClusters
+
+
+// Reserva un cluster buit com a final de cadena i l'enllaça després
+// de 'prev' (si és un cluster vàlid).
+func (self *_FAT12_Image) alloc( prev int ) (int,error) {
+
+  for c:= 2; c < self.nclusters+2; c++ {
+    if self.get ( c ) != 0 { continue }
+    self.set ( c, 0xFFF )
+    if self.isData ( prev ) { self.set ( prev, c ) }
+    clear ( self.getCluster ( c ) )
+    return c,nil
+  }
+  
+  return -1,errors.New ( "El disquet està ple" )
+  
+} // end alloc
+
+
+func (self *_FAT12_Image) freeChain( cluster int ) {
+
+  for n:= 0; self.isData ( cluster ) && n <= self.nclusters; n++ {
+    next:= self.get ( cluster )
+    self.set ( cluster, 0 )
+    cluster= next
+  }
+  
+} // end freeChain
+
+
+// Entrades d'un directori. El cluster 0 és l'arrel.
+func (self *_FAT12_Image) getDirEntries( cluster int ) [][]byte {
+
+  ret:= make([][]byte,0)
+  add:= func(data []byte) {
+    for off:= 0; off+32 <= len(data); off+= 32 {
+      ret= append(ret,data[off:off+32])
+    }
+  }
+  if cluster == 0 {
+    add ( self.root )
+  } else {
+    for n:= 0; self.isData ( cluster ) && n <= self.nclusters; n++ {
+      add ( self.getCluster ( cluster ) )
+      cluster= self.get ( cluster )
+    }
+  }
+
+  return ret
+  
+} // end getDirEntries
+
+
+// Busca l'entrada amb el nom 8.3 indicat.
+func (self *_FAT12_Image) findEntry( cluster int, name []byte ) []byte {
+
+  for _,e:= range self.getDirEntries ( cluster ) {
+    if e[0] == 0x00 { break }
+    if e[0] == 0xE5 || e[11] == 0x0F || (e[11]&0x08) != 0 { continue }
+    if string(e[:11]) == string(name) { return e }
+  }
+
+  return nil
+  
+} // end findEntry
+
+
+// Torna una entrada lliure. Els subdirectoris creixen si cal, l'arrel
+// no.
+func (self *_FAT12_Image) newEntry( cluster int ) ([]byte,error) {
+
+  entries:= self.getDirEntries ( cluster )
+  for i,e:= range entries {
+    if e[0] == 0x00 || e[0] == 0xE5 {
+      // Si era l'última, la següent marca el final
+      if e[0] == 0x00 && i+1 < len(entries) {
+        entries[i+1][0]= 0x00
+      }
+      return e,nil
+    }
+  }
+  if cluster == 0 {
+    return nil,errors.New ( "El directori arrel del disquet està ple" )
+  }
+
+  // Amplia el directori
+  last:= cluster
+  for n:= 0; self.isData ( self.get ( last ) ) && n <= self.nclusters; n++ {
+    last= self.get ( last )
+  }
+  c,err:= self.alloc ( last )
+  if err != nil { return nil,err }
+  
+  return self.getCluster ( c )[:32],nil
+  
+} // end newEntry
+
+
+func _FAT12_SetEntry(
+
+  e       []byte,
+  name    []byte,
+  attr    uint8,
+  cluster int,
+  size    int,
+  mtime   time.Time,
+  
+) {
+
+  clear ( e )
+  copy ( e[:11], name )
+  e[11]= attr
+  t:= (mtime.Hour ()<<11) | (mtime.Minute ()<<5) | (mtime.Second ()/2)
+  d:= 0
+  if year:= mtime.Year (); year >= 1980 && year <= 2107 {
+    d= ((year-1980)<<9) | (int(mtime.Month ())<<5) | mtime.Day ()
+  }
+  e[22],e[23]= uint8(t),uint8(t>>8)
+  e[24],e[25]= uint8(d),uint8(d>>8)
+  e[26],e[27]= uint8(cluster),uint8(cluster>>8)
+  e[28],e[29],e[30],e[31]= uint8(size),uint8(size>>8),
+    uint8(size>>16),uint8(size>>24)
+  
+} // end _FAT12_SetEntry
+
+
+// Crea un subdirectori i torna el seu cluster.
+func (self *_FAT12_Image) mkdir(
+
+  parent int,
+  name   []byte,
+  mtime  time.Time,
+  
+) (int,error) {
+
+  e,err:= self.newEntry ( parent )
+  if err != nil { return -1,err }
+  c,err:= self.alloc ( -1 )
+  if err != nil { return -1,err }
+  _FAT12_SetEntry ( e, name, 0x10, c, 0, mtime )
+  data:= self.getCluster ( c )
+  _FAT12_SetEntry ( data[0:32], []byte(".          "), 0x10, c, 0, mtime )
+  _FAT12_SetEntry ( data[32:64], []byte("..         "), 0x10, parent, 0,
+    mtime )
+  
+  return c,nil
+  
+} // end mkdir
+
+
+// Afegeix o reemplaça un fitxer. 'path' és la ruta dins del disquet
+// separada per '/'. Els directoris que falten es creen.
+func (self *_FAT12_Image) putFile(
+
+  path    string,
+  content []byte,
+  mtime   time.Time,
+  
+) error {
+
+  // Directoris
+  comps:= strings.Split ( strings.Trim ( path, "/" ), "/" )
+  dir:= 0
+  for _,comp:= range comps[:len(comps)-1] {
+    name,err:= _FAT12_ShortName ( comp )
+    if err != nil { return err }
+    if e:= self.findEntry ( dir, name ); e != nil {
+      if (e[11]&0x10) == 0 {
+        return fmt.Errorf ( "'%s' no és un directori", comp )
+      }
+      dir= int(e[26]) | (int(e[27])<<8)
+    } else if dir,err= self.mkdir ( dir, name, mtime ); err != nil {
+      return err
+    }
+  }
+
+  // Entrada del fitxer
+  name,err:= _FAT12_ShortName ( comps[len(comps)-1] )
+  if err != nil { return err }
+  e:= self.findEntry ( dir, name )
+  if e != nil {
+    if (e[11]&0x10) != 0 {
+      return fmt.Errorf ( "'%s' és un directori", path )
+    }
+    self.freeChain ( int(e[26]) | (int(e[27])<<8) )
+  } else if e,err= self.newEntry ( dir ); err != nil {
+    return err
+  }
+
+  // Dades
+  needed:= (len(content)+self.cluster_size-1)/self.cluster_size
+  if needed > self.getNumFreeClusters () {
+    return fmt.Errorf ( "No hi ha prou espai en el disquet per a '%s'", path )
+  }
+  first,prev:= 0,-1
+  for i:= 0; i < needed; i++ {
+    c,err:= self.alloc ( prev )
+    if err != nil { return err }
+    if i == 0 { first= c }
+    copy ( self.getCluster ( c ), content[i*self.cluster_size:] )
+    prev= c
+  }
+  _FAT12_SetEntry ( e, name, 0x20, first, len(content), mtime )
+  
+  return nil
+  
+} // end putFile
+
+
+// Converteix un nom a format 8.3 (11 bytes, amb espais).
+func _FAT12_ShortName( name string ) ([]byte,error) {
+
+  bad:= fmt.Errorf ( "'%s' no és un nom de fitxer 8.3 vàlid", name )
+  name= strings.ToUpper ( name )
+  base,ext:= name,""
+  if pos:= strings.LastIndexByte ( name, '.' ); pos != -1 {
+    base,ext= name[:pos],name[pos+1:]
+  }
+  if len(base) == 0 || len(base) > 8 || len(ext) > 3 { return nil,bad }
+  for _,c:= range base+ext {
+    if !((c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+      strings.ContainsRune ( "!#$%&'()-@^_`{}~", c )) {
+      return nil,bad
+    }
+  }
+  
+  return []byte(fmt.Sprintf ( "%-8s%-3s", base, ext )),nil
+  
+} // end _FAT12_ShortName
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Formats de disquet que es poden crear amb CreateFloppy.
+func GetFloppyFormats() []string {
+
+  ret:= make([]string,len(_FAT12_FORMATS))
+  for i,f:= range _FAT12_FORMATS {
+    ret[i]= f.name
+  }
+
+  return ret
+  
+} // end GetFloppyFormats
+
+
+// Crea un disquet FAT12 buit i formatat.
+func CreateFloppy( file_name string, format string ) error {
+
+  // Format
+  var f *_FAT12_Format= nil
+  for i:= range _FAT12_FORMATS {
+    if _FAT12_FORMATS[i].name == format {
+      f= &_FAT12_FORMATS[i]
+    }
+  }
+  if f == nil {
+    return fmt.Errorf ( "Format de disquet desconegut: '%s'", format )
+  }
+
+  // Sector d'arrancada
+  data:= make([]byte,f.total_sectors*_FAT12_SEC_SIZE)
+  boot:= data[:_FAT12_SEC_SIZE]
+  copy ( boot, []byte{0xEB,0x3C,0x90} )
+  copy ( boot[3:11], "MSDOS5.0" )
+  boot[11],boot[12]= uint8(_FAT12_SEC_SIZE&0xFF),uint8(_FAT12_SEC_SIZE>>8)
+  boot[13]= uint8(f.spc)
+  boot[14],boot[15]= 1,0 // Sectors reservats
+  boot[16]= 2 // Nombre de FATs
+  boot[17],boot[18]= uint8(f.root_entries),uint8(f.root_entries>>8)
+  boot[19],boot[20]= uint8(f.total_sectors),uint8(f.total_sectors>>8)
+  boot[21]= f.media
+  boot[22],boot[23]= uint8(f.fat_sectors),uint8(f.fat_sectors>>8)
+  boot[24],boot[25]= uint8(f.spt),0
+  boot[26],boot[27]= 2,0 // Capçals
+  boot[38]= 0x29
+  id:= uint32(time.Now ().Unix ())
+  boot[39],boot[40],boot[41],boot[42]= uint8(id),uint8(id>>8),
+    uint8(id>>16),uint8(id>>24)
+  copy ( boot[43:54], "NO NAME    " )
+  copy ( boot[54:62], "FAT12   " )
+  boot[510],boot[511]= 0x55,0xAA
+
+  // FATs
+  for i:= 0; i < 2; i++ {
+    off:= (1+i*f.fat_sectors)*_FAT12_SEC_SIZE
+    data[off],data[off+1],data[off+2]= f.media,0xFF,0xFF
+  }
+  
+  return os.WriteFile ( file_name, data, 0644 )
+  
+} // end CreateFloppy
+
+
+// Copia el disquet 'src' en 'dst' afegint (o reemplaçant) els
+// fitxers locals 'files' en el directori 'dir' del disquet ("" és
+// l'arrel). Els noms han de ser 8.3.
+func WriteFloppy(
+
+  src   string,
+  dst   string,
+  files []string,
+  dir   string,
+  
+) error {
+
+  // Carrega
+  data,err:= os.ReadFile ( src )
+  if err != nil { return err }
+  img,err:= _FAT12_LoadImage ( data )
+  if err != nil { return err }
+
+  // Afegeix fitxers
+  dir= strings.Trim ( strings.ReplaceAll ( dir, "\\", "/" ), "/" )
+  for _,fn:= range files {
+    info,err:= os.Stat ( fn )
+    if err != nil { return err }
+    content,err:= os.ReadFile ( fn )
+    if err != nil { return err }
+    path:= filepath.Base ( fn )
+    if dir != "" { path= dir+"/"+path }
+    if err:= img.putFile ( path, content, info.ModTime () ); err != nil {
+      return err
+    }
+  }
+  
+  return os.WriteFile ( dst, data, 0644 )
+  
+} // end WriteFloppy
@@ -316,6 +316,22 @@ func _GBC_ReadHeader( header *_GBC_Metadata, data []byte ) {
 } // end _GBC_ReadHeader
 
 
+func sniffGBC( data []byte, size int64 ) int {
+
+  if size%_GBC_BANK_SIZE != 0 || len(data) < 0x150 ||
+    !_GBC_CheckNintendoLogo ( data ) {
+    return 0
+  }
+  if _GBC_CalcChecksum ( data ) != data[0x14d] {
+    return 80
+  }
+
+  return 100
+
+} // end sniffGBC
+
+
+
 
 
 /****************/
@@ -492,3 +508,14 @@ func (self *GBC) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_GBC,
+    ShortName  : "GBC",
+    Extensions : []string{".gb",".gbc"},
+    Detect     : sniffGBC,
+    Type       : &GBC{},
+  })
+} // end init
@@ -243,3 +243,28 @@ func (self *GG) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func sniffGG( data []byte, size int64 ) int {
+
+  if size == 0 || size%_GG_BANK_SIZE != 0 { return 0 }
+  for _,pos:= range []int{0x1ff0,0x3ff0,0x7ff0} {
+    if hasMagic ( data, pos, _GG_HEADER ) {
+      return 90
+    }
+  }
+
+  return 0
+
+} // end sniffGG
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_GG,
+    ShortName  : "GG",
+    Extensions : []string{".gg"},
+    Detect     : sniffGG,
+    Type       : &GG{},
+  })
+} // end init
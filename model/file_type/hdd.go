@@ -0,0 +1,314 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  hdd.go - Tipus de fitxer imatge de disc dur amb taula de particions
+ *           MBR i particions FAT12/FAT16 (MS-DOS).
+ */
+
+package file_type
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "image"
+  "io"
+  "log"
+  "os"
+  
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+const _MBR_SEC_SIZE = 512
+
+
+type _MBR_Partition struct {
+
+  Index    int   // 1..4
+  Type     uint8
+  Bootable bool
+  Start    int64 // En bytes
+  Size     int64 // En bytes
+  Fs       *_FAT_Contents // nil si no és FAT o no s'ha pogut llegir
+  
+}
+
+
+type _HDD_Metadata struct {
+
+  Partitions []_MBR_Partition
+  
+}
+
+
+// Llig la taula de particions primàries. Sols torna les particions
+// no buides.
+func _MBR_Read( data []byte ) ([]_MBR_Partition,error) {
+
+  if len(data) < _MBR_SEC_SIZE || data[510] != 0x55 || data[511] != 0xaa {
+    return nil,errors.New ( "No hi ha una taula de particions MBR" )
+  }
+  ret:= make([]_MBR_Partition,0,4)
+  for i:= 0; i < 4; i++ {
+    e:= data[446+16*i:446+16*(i+1)]
+    if e[0] != 0x00 && e[0] != 0x80 {
+      return nil,errors.New ( "Taula de particions MBR no vàlida" )
+    }
+    if e[4] == 0x00 { continue }
+    start:= uint32(e[8]) | (uint32(e[9])<<8) |
+      (uint32(e[10])<<16) | (uint32(e[11])<<24)
+    length:= uint32(e[12]) | (uint32(e[13])<<8) |
+      (uint32(e[14])<<16) | (uint32(e[15])<<24)
+    ret= append(ret,_MBR_Partition{
+      Index    : i+1,
+      Type     : e[4],
+      Bootable : e[0] == 0x80,
+      Start    : int64(start)*_MBR_SEC_SIZE,
+      Size     : int64(length)*_MBR_SEC_SIZE,
+    })
+  }
+  if len(ret) == 0 {
+    return nil,errors.New ( "La taula de particions MBR està buida" )
+  }
+  
+  return ret,nil
+  
+} // end _MBR_Read
+
+
+// Tipus de partició FAT12 o FAT16.
+func _MBR_IsFAT( ptype uint8 ) bool {
+  return ptype == 0x01 || ptype == 0x04 || ptype == 0x06 || ptype == 0x0E
+} // end _MBR_IsFAT
+
+
+func sniffHDD( data []byte, size int64 ) int {
+
+  parts,err:= _MBR_Read ( data )
+  if err != nil { return 0 }
+  for _,p:= range parts {
+    if _MBR_IsFAT ( p.Type ) && p.Start > 0 && p.Start+p.Size <= size {
+      return 60
+    }
+  }
+  
+  return 0
+  
+} // end sniffHDD
+
+
+type _HDD_Volume struct {
+  prefix string // Prefix dels noms dels fitxers
+  vol    *_FAT_Volume
+}
+
+
+// Obri els volums de les particions FAT. Si n'hi ha més d'una els
+// noms dels fitxers comencen per "P<N>/".
+func _HDD_OpenVolumes( fd *os.File ) ([]_HDD_Volume,error) {
+
+  mbr:= make([]byte,_MBR_SEC_SIZE)
+  if _,err:= fd.ReadAt ( mbr, 0 ); err != nil { return nil,err }
+  parts,err:= _MBR_Read ( mbr )
+  if err != nil { return nil,err }
+  ret:= make([]_HDD_Volume,0,len(parts))
+  for _,p:= range parts {
+    if !_MBR_IsFAT ( p.Type ) { continue }
+    vol,err:= _FAT_OpenVolume ( fd, p.Start, p.Size )
+    if err != nil { return nil,err }
+    ret= append(ret,_HDD_Volume{fmt.Sprintf ( "P%d/", p.Index ),vol})
+  }
+  if len(ret) == 1 {
+    ret[0].prefix= ""
+  }
+
+  return ret,nil
+  
+} // end _HDD_OpenVolumes
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+type HDD struct {
+}
+
+
+func (self *HDD) GetImage( file_name string) (image.Image,error) {
+  return nil,fmt.Errorf (
+    "No es pot interpretar com una imatge un disc dur FAT16" )
+} // end GetImage
+
+
+func (self *HDD) GetMembers( file_name string ) ([]*ArchiveMember,error) {
+
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+  vols,err:= _HDD_OpenVolumes ( fd )
+  if err != nil { return nil,err }
+  ret:= make([]*ArchiveMember,0,16)
+  for _,v:= range vols {
+    members,err:= v.vol.getMembers ( v.prefix )
+    if err != nil { return nil,err }
+    ret= append(ret,members...)
+  }
+  
+  return ret,nil
+  
+} // end GetMembers
+
+
+func (self *HDD) GetMetadata(file_name string) (string,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return "",err }
+  defer fd.Close ()
+
+  // Particions
+  mbr:= make([]byte,_MBR_SEC_SIZE)
+  if _,err:= fd.ReadAt ( mbr, 0 ); err != nil { return "",err }
+  parts,err:= _MBR_Read ( mbr )
+  if err != nil { return "",err }
+
+  // Contingut de les particions FAT
+  md:= _HDD_Metadata{
+    Partitions : parts,
+  }
+  nfat:= 0
+  for i:= range md.Partitions {
+    p:= &md.Partitions[i]
+    if !_MBR_IsFAT ( p.Type ) { continue }
+    vol,err:= _FAT_OpenVolume ( fd, p.Start, p.Size )
+    if err != nil {
+      log.Printf ( "[HDD] no s'ha pogut llegir la partició %d de '%s': %s",
+        p.Index, file_name, err )
+      continue
+    }
+    nfat++
+    if p.Fs,err= vol.getContents (); err != nil {
+      log.Printf ( "[HDD] no s'ha pogut llegir el contingut de la"+
+        " partició %d de '%s': %s", p.Index, file_name, err )
+    }
+  }
+  if nfat == 0 {
+    return "",errors.New ( "El disc no té cap partició FAT12 o FAT16" )
+  }
+  
+  // Converteix a json
+  b,err:= json.Marshal ( md )
+  if err != nil { return "",err }
+  
+  return string(b),nil
+  
+} // end GetMetadata
+
+
+func (self *HDD) GetName() string {
+  return "Disc dur FAT16 (MS-DOS)"
+}
+func (self *HDD) GetShortName() string { return "HDD" }
+func (self *HDD) IsImage() bool { return false }
+
+
+func (self *HDD) OpenMember(
+
+  file_name string,
+  member    string,
+  
+) (io.ReadCloser,error) {
+
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  vols,err:= _HDD_OpenVolumes ( fd )
+  if err != nil {
+    fd.Close ()
+    return nil,err
+  }
+  for _,v:= range vols {
+    if len(member) <= len(v.prefix) || member[:len(v.prefix)] != v.prefix {
+      continue
+    }
+    r,err:= v.vol.openMember ( member[len(v.prefix):] )
+    if err != nil {
+      fd.Close ()
+      return nil,err
+    }
+    if r != nil {
+      return &_MemberReader{r:r,fd:fd},nil
+    }
+  }
+  fd.Close ()
+  
+  return nil,fmt.Errorf ( "El disc no conté '%s'", member )
+  
+} // end OpenMember
+
+
+func (self *HDD) ParseMetadata(
+
+  v         []view.StringPair,
+  meta_data string,
+
+) []view.StringPair {
+
+  // Parseja
+  md:= _HDD_Metadata{}
+  if err:= json.Unmarshal ( []byte(meta_data), &md ); err != nil {
+    log.Printf ( "[HDD] no s'ha pogut parsejar '%s': %s", meta_data, err )
+    return v
+  }
+
+  // Particions
+  for _,p:= range md.Partitions {
+    text:= fmt.Sprintf ( "tipus %02Xh, %s", p.Type,
+      NumBytesToStr ( uint64(p.Size) ) )
+    if p.Bootable { text+= ", arrencable" }
+    prefix:= fmt.Sprintf ( "Partició %d", p.Index )
+    v= append(v,&KeyValue{prefix,text})
+    if p.Fs != nil {
+      v= p.Fs.Parse ( v, prefix+" - " )
+    }
+  }
+  
+  return v
+  
+} // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_HDD_FAT16,
+    ShortName  : "HDD",
+    Extensions : []string{".img",".hdd",".vhd"},
+    Detect     : sniffHDD,
+    Type       : &HDD{},
+  })
+} // end init
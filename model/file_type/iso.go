@@ -26,7 +26,10 @@ import (
   "encoding/json"
   "fmt"
   "image"
+  "io"
   "log"
+  "strings"
+  "time"
   
   "github.com/adriagipas/imgcp/cdread"
   "github.com/adriagipas/imgteka/view"
@@ -47,6 +50,72 @@ type _CDISO_Metadata struct {
 }
 
 
+func _ISO_Open( file_name string ) (*cdread.ISO,error) {
+  
+  cd,err:= cdread.Open ( file_name )
+  if err != nil { return nil,err }
+  
+  return cdread.ReadISO ( cd, 0, 0 )
+  
+} // end _ISO_Open
+
+
+// Lleva el número de versió (";1") i el punt final dels noms sense
+// extensió.
+func _ISO_CleanName( id string ) string {
+
+  if pos:= strings.LastIndexByte ( id, ';' ); pos != -1 {
+    id= id[:pos]
+  }
+  if len(id) > 1 { id= strings.TrimSuffix ( id, "." ) }
+
+  return id
+  
+} // end _ISO_CleanName
+
+
+func _ISO_Date( dt *cdread.ISO_DateTimeRecord ) time.Time {
+
+  if dt.Empty { return time.Time{} }
+  loc:= time.FixedZone ( "", dt.GMT*15*60 )
+  
+  return time.Date ( dt.Year, time.Month ( dt.Month ), int(dt.Day),
+    int(dt.Hour), int(dt.Minute), int(dt.Second), 0, loc )
+  
+} // end _ISO_Date
+
+
+// Recorre recursivament els fitxers (no els directoris) del
+// directori. Si 'fn' torna cert para el recorregut.
+func _ISO_Walk(
+
+  dir    *cdread.ISO_Directory,
+  prefix string,
+  fn     func(name string,it *cdread.ISO_DirectoryIter) (bool,error),
+  
+) (bool,error) {
+
+  it,err:= dir.Begin ()
+  for ; err == nil && !it.End (); err= it.Next () {
+    id:= it.Id ()
+    if id == "." || id == ".." { continue }
+    name:= prefix+_ISO_CleanName ( id )
+    if (it.Flags ()&cdread.FILE_FLAGS_DIRECTORY) != 0 {
+      sub,err:= it.GetDirectory ()
+      if err != nil { return false,err }
+      if stop,err:= _ISO_Walk ( sub, name+"/", fn ); err != nil || stop {
+        return stop,err
+      }
+    } else if stop,err:= fn ( name, it ); err != nil || stop {
+      return stop,err
+    }
+  }
+  
+  return false,err
+  
+} // end _ISO_Walk
+
+
 
 
 /****************/
@@ -89,6 +158,67 @@ type ISO struct {
 }
 
 
+func (self *ISO) GetMembers( file_name string ) ([]*ArchiveMember,error) {
+
+  // Obri
+  iso,err:= _ISO_Open ( file_name )
+  if err != nil { return nil,err }
+  root,err:= iso.Root ()
+  if err != nil { return nil,err }
+
+  // Fitxers
+  ret:= make([]*ArchiveMember,0,16)
+  if _,err:= _ISO_Walk ( root, "",
+    func(name string,it *cdread.ISO_DirectoryIter) (bool,error) {
+      ret= append(ret,&ArchiveMember{
+        name : name,
+        size : int64(it.Size ()),
+        date : _ISO_Date ( it.DateTime () ),
+      })
+      return false,nil
+    }); err != nil {
+    return nil,err
+  }
+  
+  return ret,nil
+  
+} // end GetMembers
+
+
+func (self *ISO) OpenMember(
+
+  file_name string,
+  member    string,
+  
+) (io.ReadCloser,error) {
+
+  // Obri
+  iso,err:= _ISO_Open ( file_name )
+  if err != nil { return nil,err }
+  root,err:= iso.Root ()
+  if err != nil { return nil,err }
+
+  // Busca
+  var ret io.ReadCloser= nil
+  if _,err:= _ISO_Walk ( root, "",
+    func(name string,it *cdread.ISO_DirectoryIter) (bool,error) {
+      if name != member { return false,nil }
+      f,err:= it.GetFileReader ()
+      if err != nil { return false,err }
+      ret= f
+      return true,nil
+    }); err != nil {
+    return nil,err
+  }
+  if ret == nil {
+    return nil,fmt.Errorf ( "La imatge de CD no conté '%s'", member )
+  }
+  
+  return ret,nil
+  
+} // end OpenMember
+
+
 func (self *ISO) GetImage( file_name string ) (image.Image,error) {
   return nil,fmt.Errorf (
     "No es pot interpretar com una imatge una imatge de CD ISO-9660" )
@@ -145,3 +275,20 @@ func (self *ISO) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_CD_ISO,
+    ShortName  : "ISO",
+    Extensions : []string{".iso",".cue",".bin"},
+    Detect     : sniffCDScore ( 50 ),
+    Type       : &ISO{},
+  })
+  mustRegister ( &Registration{
+    ID         : ID_AUX_CD_ISO,
+    ShortName  : "ISO",
+    Extensions : []string{".bin"},
+    Type       : &ISO_Aux{},
+  })
+} // end init
@@ -110,3 +110,15 @@ func (self *JPEG) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_IMAGE_JPEG,
+    ShortName  : "JPEG",
+    Extensions : []string{".jpg",".jpeg"},
+    Detect     : sniffMagic ( 0, "\xff\xd8\xff", 80 ),
+    Caps       : CAP_IMAGE,
+    Type       : &JPEG{},
+  })
+} // end init
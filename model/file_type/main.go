@@ -25,6 +25,8 @@ package file_type
 import (
   "fmt"
   "image"
+  "log"
+  "sort"
   
   "github.com/adriagipas/imgteka/view"
 )
@@ -67,6 +69,7 @@ const ID_DVD_PS2    = 0x602
 const ID_CD_ISO     = 0x603
 
 const ID_FLP_FAT12  = 0x700
+const ID_HDD_FAT16  = 0x701
 
 const ID_DOC_PDF    = 0x800
 
@@ -119,11 +122,17 @@ func (self *KeyValue) GetValue() string { return self.value }
 /* PART PRIVADA */
 /****************/
 
-var _IDS []int= []int{
+var _REGS= make(map[int]*Registration)
+var _IDS []int= nil
+
+// Ordre en què es mostren i es proven els tipus del paquet. Els
+// tipus que no apareixen ací van darrere en ordre de registre, i el
+// binari genèric sempre al final.
+var _ORDER []int= []int{
   
   ID_IMAGE_PNG,
   ID_IMAGE_JPEG,
-
+  
   ID_DOC_PDF,
   
   ID_ROM_GBC,
@@ -132,11 +141,11 @@ var _IDS []int= []int{
   ID_ROM_NES,
   ID_ROM_NDS,
   ID_ROM_3DS,
-
+  
   ID_EXE_CXI,
   ID_EXE_SFZ,
   ID_EXE_ZBLORB,
-
+  
   ID_CD_PS1,
   ID_AUX_CD_PS1,
   ID_DVD_PS2,
@@ -146,36 +155,32 @@ var _IDS []int= []int{
   
   ID_ARCH_ZIP,
   ID_ARCH_TAR,
-
-  ID_FLP_FAT12,
   
-  ID_BIN,
+  ID_FLP_FAT12,
+  ID_HDD_FAT16,
   
 }
 
-// Tipus globals
-var _vPNG PNG= PNG{}
-var _vJPEG JPEG= JPEG{}
-var _vPDF PDF= PDF{}
-var _vGBC GBC= GBC{}
-var _vGG GG= GG{}
-var _vMD MD= MD{}
-var _vNES NES= NES{}
-var _vNDS NDS= NDS{}
-var _v3DS N3DS= N3DS{}
-var _vCXI CXI= CXI{}
-var _vSFZ SFZ= SFZ{}
-var _vZBlorb ZBlorb= ZBlorb{}
-var _vPS1 PS1= PS1{}
-var _vPS1_Aux PS1_Aux= PS1_Aux{}
-var _vPS2 PS2= PS2{}
-var _vPSP PSP= PSP{}
-var _vISO ISO= ISO{}
-var _vISO_Aux ISO_Aux= ISO_Aux{}
-var _vZIP ZIP= ZIP{}
-var _vTAR TAR= TAR{}
-var _vFAT12 FAT12= FAT12{}
-var _vBIN BIN= BIN{}
+
+func orderRank( id int ) int {
+
+  if id == ID_BIN { return len(_ORDER)+1 }
+  for i,o:= range _ORDER {
+    if o == id { return i }
+  }
+  
+  return len(_ORDER)
+  
+} // end orderRank
+
+
+// Per als tipus del paquet. Un error en el registre és un error de
+// programació.
+func mustRegister( reg *Registration ) {
+  if err:= Register ( reg ); err != nil {
+    log.Fatal ( err )
+  }
+} // end mustRegister
 
 
 
@@ -184,65 +189,72 @@ var _vBIN BIN= BIN{}
 /* PART PÚBLICA */
 /****************/
 
+// Capacitats d'un tipus.
+const (
+  CAP_IMAGE      = 0x01 // Es pot obtindre una imatge (IsImage)
+  CAP_EXECUTABLE = 0x02 // És un programa o un fitxer d'història
+  CAP_CONTAINER  = 0x04 // Conté altres fitxers
+)
+
+
+// Torna una puntuació entre 0 (no és del tipus) i 100 (segur que ho
+// és) a partir de les signatures i l'estructura de les primeres dades
+// del fitxer. 'size' és la grandària total del fitxer.
+type Detector func(data []byte,size int64) int
+
+
+// Descripció d'un tipus de fitxer per a registrar-lo.
+type Registration struct {
+  ID         int
+  ShortName  string   // Ha de coincidir amb Type.GetShortName
+  Extensions []string // En minúscules i amb el punt (".nes")
+  Detect     Detector // nil si no es pot detectar automàticament
+  Caps       int
+  Type       FileType
+}
+
+
+// Registra un tipus de fitxer. Falla si l'identificador ja està
+// registrat, perquè els identificadors es guarden en la base de dades
+// i no es poden reassignar.
+func Register( reg *Registration ) error {
+
+  if reg.Type == nil {
+    return fmt.Errorf ( "Tipus de fitxer sense implementació: 0x%03x",
+      reg.ID )
+  }
+  if prev,ok:= _REGS[reg.ID]; ok {
+    return fmt.Errorf ( "Identificador de tipus de fitxer duplicat:"+
+      " 0x%03x (%s i %s)", reg.ID, prev.Type.GetName (), reg.Type.GetName () )
+  }
+  if reg.ShortName != reg.Type.GetShortName () {
+    return fmt.Errorf ( "El nom curt registrat per a 0x%03x (%s) no"+
+      " coincideix amb el del tipus (%s)", reg.ID, reg.ShortName,
+      reg.Type.GetShortName () )
+  }
+  if ((reg.Caps&CAP_IMAGE) != 0) != reg.Type.IsImage () {
+    return fmt.Errorf ( "La capacitat d'imatge registrada per a 0x%03x no"+
+      " coincideix amb la del tipus", reg.ID )
+  }
+
+  // Afegeix mantenint l'ordre de _ORDER.
+  _REGS[reg.ID]= reg
+  _IDS= append(_IDS,reg.ID)
+  sort.SliceStable ( _IDS, func(i,j int) bool {
+    return orderRank ( _IDS[i] ) < orderRank ( _IDS[j] )
+  })
+  
+  return nil
+  
+} // end Register
+
+
 func Get( id int ) (FileType,error) {
+
+  reg,err:= GetRegistration ( id )
+  if err != nil { return nil,err }
   
-  switch id {
-    
-  case ID_IMAGE_PNG:
-    return &_vPNG,nil
-  case ID_IMAGE_JPEG:
-    return &_vJPEG,nil
-
-  case ID_DOC_PDF:
-    return &_vPDF,nil
-    
-  case ID_ROM_3DS:
-    return &_v3DS,nil
-  case ID_ROM_GBC:
-    return &_vGBC,nil
-  case ID_ROM_GG:
-    return &_vGG,nil
-  case ID_ROM_MD:
-    return &_vMD,nil
-  case ID_ROM_NES:
-    return &_vNES,nil
-  case ID_ROM_NDS:
-    return &_vNDS,nil
-    
-  case ID_EXE_CXI:
-    return &_vCXI,nil
-  case ID_EXE_SFZ:
-    return &_vSFZ,nil
-  case ID_EXE_ZBLORB:
-    return &_vZBlorb,nil
-
-  case ID_CD_PS1:
-    return &_vPS1,nil
-  case ID_AUX_CD_PS1:
-    return &_vPS1_Aux,nil
-  case ID_DVD_PS2:
-    return &_vPS2,nil
-  case ID_UMD_PSP:
-    return &_vPSP,nil
-  case ID_CD_ISO:
-    return &_vISO,nil
-  case ID_AUX_CD_ISO:
-    return &_vISO_Aux,nil
-    
-  case ID_ARCH_ZIP:
-    return &_vZIP,nil
-  case ID_ARCH_TAR:
-    return &_vTAR,nil
-
-  case ID_FLP_FAT12:
-    return &_vFAT12,nil
-    
-  case ID_BIN:
-    return &_vBIN,nil
-    
-  default:
-    return nil,fmt.Errorf ( "Tipus de fitxer desconegut:", id )
-  }
+  return reg.Type,nil
   
 } // end Get
 
@@ -250,3 +262,15 @@ func Get( id int ) (FileType,error) {
 func GetIDs() []int {
   return _IDS
 } // end GetIDs
+
+
+func GetRegistration( id int ) (*Registration,error) {
+
+  reg,ok:= _REGS[id]
+  if !ok {
+    return nil,fmt.Errorf ( "Tipus de fitxer desconegut: %d", id )
+  }
+
+  return reg,nil
+  
+} // end GetRegistration
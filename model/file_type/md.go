@@ -238,6 +238,19 @@ func _MD_DecodeRegion( code string ) string {
 } // _MD_DecodeRegion
 
 
+func sniffMD( data []byte, size int64 ) int {
+
+  if size%2 != 0 || size < 0x200 { return 0 }
+  if hasMagic ( data, 0x100, "SEGA" ) || hasMagic ( data, 0x101, "SEGA" ) {
+    return 90
+  }
+
+  return 0
+
+} // end sniffMD
+
+
+
 
 
 /****************/
@@ -417,3 +430,14 @@ func (self *MD) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_MD,
+    ShortName  : "MD",
+    Extensions : []string{".md",".gen",".smd"},
+    Detect     : sniffMD,
+    Type       : &MD{},
+  })
+} // end init
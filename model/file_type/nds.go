@@ -300,6 +300,18 @@ func (self *_NDS_Metadata) InitTitles( data []byte ) {
 } // end InitTitles
 
 
+func sniffNDS( data []byte, size int64 ) int {
+
+  if size < 0x1000 || len(data) < 0x160 { return 0 }
+  crc:= uint16(data[0x15e]) | (uint16(data[0x15f])<<8)
+  if _NDS_CRC16 ( data[:0x15e] ) != crc { return 0 }
+
+  return 100
+
+} // end sniffNDS
+
+
+
 
 
 /****************/
@@ -502,3 +514,15 @@ func (self *NDS) ParseMetadata(
   return v
   
 } // end ParseMetada
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_NDS,
+    ShortName  : "NDS",
+    Extensions : []string{".nds"},
+    Detect     : sniffNDS,
+    Caps       : CAP_IMAGE,
+    Type       : &NDS{},
+  })
+} // end init
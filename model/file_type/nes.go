@@ -1184,3 +1184,14 @@ func (self *NES) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ROM_NES,
+    ShortName  : "NES",
+    Extensions : []string{".nes"},
+    Detect     : sniffMagic ( 0, "NES\x1a", 100 ),
+    Type       : &NES{},
+  })
+} // end init
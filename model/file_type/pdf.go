@@ -169,3 +169,14 @@ func (self *PDF) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_DOC_PDF,
+    ShortName  : "PDF",
+    Extensions : []string{".pdf"},
+    Detect     : sniffMagic ( 0, "%PDF-", 100 ),
+    Type       : &PDF{},
+  })
+} // end init
@@ -110,3 +110,15 @@ func (self *PNG) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_IMAGE_PNG,
+    ShortName  : "PNG",
+    Extensions : []string{".png"},
+    Detect     : sniffMagic ( 0, "\x89PNG\r\n\x1a\n", 100 ),
+    Caps       : CAP_IMAGE,
+    Type       : &PNG{},
+  })
+} // end init
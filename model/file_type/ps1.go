@@ -272,3 +272,20 @@ func (self *PS1) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_CD_PS1,
+    ShortName  : "PS1",
+    Extensions : []string{".cue",".bin",".img"},
+    Detect     : sniffCDScore ( 90 ),
+    Type       : &PS1{},
+  })
+  mustRegister ( &Registration{
+    ID         : ID_AUX_CD_PS1,
+    ShortName  : "PS1",
+    Extensions : []string{".bin",".sub",".sbi"},
+    Type       : &PS1_Aux{},
+  })
+} // end init
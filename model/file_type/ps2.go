@@ -204,3 +204,14 @@ func (self *PS2) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_DVD_PS2,
+    ShortName  : "PS2",
+    Extensions : []string{".iso",".cue",".bin"},
+    Detect     : sniffCDScore ( 90 ),
+    Type       : &PS2{},
+  })
+} // end init
@@ -233,3 +233,15 @@ func (self *PSP) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_UMD_PSP,
+    ShortName  : "PSP",
+    Extensions : []string{".iso"},
+    Detect     : sniffCDScore ( 90 ),
+    Caps       : CAP_IMAGE,
+    Type       : &PSP{},
+  })
+} // end init
@@ -158,6 +158,19 @@ func SFZ_ParseMetadata(
 } // end SFZ_ParseMetadata
 
 
+func sniffSFZ( data []byte, size int64 ) int {
+
+  if size < 64 || size > 512*1024 || len(data) < 64 { return 0 }
+  if version:= data[0]; version < 1 || version > 8 {
+    return 0
+  }
+
+  return 10
+
+} // end sniffSFZ
+
+
+
 
 
 /****************/
@@ -229,3 +242,15 @@ func (self *SFZ) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_EXE_SFZ,
+    ShortName  : "SFZ",
+    Extensions : []string{".z1",".z2",".z3",".z4",".z5",".z6",".z7",".z8"},
+    Detect     : sniffSFZ,
+    Caps       : CAP_EXECUTABLE,
+    Type       : &SFZ{},
+  })
+} // end init
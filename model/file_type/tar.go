@@ -80,6 +80,64 @@ func _TAR_Open( fd *os.File ) (*tar.Reader,error) {
 type TAR struct {}
 
 
+func (self *TAR) GetMembers( file_name string ) ([]*ArchiveMember,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+  r,err:= _TAR_Open ( fd )
+  if err != nil { return nil,err }
+
+  // Fitxers
+  ret:= make([]*ArchiveMember,0,16)
+  h,err:= r.Next ()
+  for ; err == nil; h,err= r.Next () {
+    if !h.FileInfo ().Mode ().IsRegular () { continue }
+    ret= append(ret,&ArchiveMember{
+      name : h.Name,
+      size : h.Size,
+      date : h.ModTime,
+    })
+  }
+  if err != io.EOF { return nil,err }
+  
+  return ret,nil
+  
+} // end GetMembers
+
+
+func (self *TAR) OpenMember(
+
+  file_name string,
+  member    string,
+  
+) (io.ReadCloser,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  r,err:= _TAR_Open ( fd )
+  if err != nil {
+    fd.Close ()
+    return nil,err
+  }
+
+  // Busca
+  h,err:= r.Next ()
+  for ; err == nil; h,err= r.Next () {
+    if h.Name == member && h.FileInfo ().Mode ().IsRegular () {
+      return &_MemberReader{r:r,fd:fd},nil
+    }
+  }
+  fd.Close ()
+  if err != io.EOF { return nil,err }
+  
+  return nil,fmt.Errorf ( "El fitxer TAR no conté '%s'", member )
+  
+} // end OpenMember
+
+
 func (self *TAR) GetImage( file_name string) (image.Image,error) {
   return nil,fmt.Errorf (
     "No es pot interpretar com una imatge un fitxer de tipus TAR" )
@@ -150,3 +208,28 @@ func (self *TAR) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func sniffTAR( data []byte, size int64 ) int {
+
+  if hasMagic ( data, 257, "ustar" ) {
+    return 80
+  } else if hasMagic ( data, 0, "\x1f\x8b" ) { // tar.gz
+    return 40
+  }
+
+  return 0
+
+} // end sniffTAR
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ARCH_TAR,
+    ShortName  : "TAR",
+    Extensions : []string{".tar",".gz",".tgz"},
+    Detect     : sniffTAR,
+    Caps       : CAP_CONTAINER,
+    Type       : &TAR{},
+  })
+} // end init
@@ -459,3 +459,15 @@ func (self *ZBlorb) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_EXE_ZBLORB,
+    ShortName  : "ZBLORB",
+    Extensions : []string{".zblorb",".zlb",".blorb"},
+    Detect     : sniffMagic ( 8, "IFRS", 100 ),
+    Caps       : CAP_IMAGE|CAP_EXECUTABLE,
+    Type       : &ZBlorb{},
+  })
+} // end init
@@ -27,6 +27,7 @@ import (
   "encoding/json"
   "fmt"
   "image"
+  "io"
   "log"
   "os"
 
@@ -44,9 +45,102 @@ type _ZIP_Metadata struct {
 }
 
 
+func _ZIP_Open( fd *os.File ) (*zip.Reader,error) {
+
+  info,err:= fd.Stat ()
+  if err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut obtindre les metadades: %s", err )
+  }
+  ret,err:= zip.NewReader ( fd, info.Size () )
+  if err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut crear un lector de fitxers ZIP: %s",
+      err )
+  }
+
+  return ret,nil
+  
+} // end _ZIP_Open
+
+
 type ZIP struct {}
 
 
+func (self *ZIP) GetMembers( file_name string ) ([]*ArchiveMember,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  defer fd.Close ()
+  reader,err:= _ZIP_Open ( fd )
+  if err != nil { return nil,err }
+
+  // Fitxers
+  ret:= make([]*ArchiveMember,0,len(reader.File))
+  for _,f:= range reader.File {
+    if !f.Mode ().IsRegular () { continue }
+    ret= append(ret,&ArchiveMember{
+      name : f.Name,
+      size : int64(f.UncompressedSize64),
+      date : f.Modified,
+    })
+  }
+  
+  return ret,nil
+  
+} // end GetMembers
+
+
+func (self *ZIP) OpenMember(
+
+  file_name string,
+  member    string,
+  
+) (io.ReadCloser,error) {
+
+  // Obri
+  fd,err:= os.Open ( file_name )
+  if err != nil { return nil,err }
+  reader,err:= _ZIP_Open ( fd )
+  if err != nil {
+    fd.Close ()
+    return nil,err
+  }
+
+  // Busca
+  for _,f:= range reader.File {
+    if f.Name != member || !f.Mode ().IsRegular () { continue }
+
+    // Sense compressió es llig directament del fitxer
+    if f.Method == zip.Store {
+      offset,err:= f.DataOffset ()
+      if err != nil {
+        fd.Close ()
+        return nil,err
+      }
+      r,err:= NewSubfileReader ( fd, offset, int64(f.UncompressedSize64) )
+      if err != nil {
+        fd.Close ()
+        return nil,err
+      }
+      return &_MemberReader{r:r,fd:fd},nil
+    }
+
+    // Amb compressió
+    rc,err:= f.Open ()
+    if err != nil {
+      fd.Close ()
+      return nil,err
+    }
+    return &_MemberReader{r:rc,rc:rc,fd:fd},nil
+    
+  }
+  fd.Close ()
+  
+  return nil,fmt.Errorf ( "El fitxer ZIP no conté '%s'", member )
+  
+} // end OpenMember
+
+
 func (self *ZIP) GetImage( file_name string) (image.Image,error) {
   return nil,fmt.Errorf (
     "No es pot interpretar com una imatge un fitxer de tipus ZIP" )
@@ -61,15 +155,8 @@ func (self *ZIP) GetMetadata(file_name string) (string,error) {
   defer fd.Close ()
   
   // Crea ZIP reader
-  info,err:= fd.Stat ()
-  if err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut obtindre les metadades: %s", err )
-  }
-  reader,err:= zip.NewReader ( fd, info.Size () )
-  if err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut crear un lector de fitxers ZIP: %s",
-      err )
-  }
+  reader,err:= _ZIP_Open ( fd )
+  if err != nil { return "",err }
 
   // Metadades
   md:= _ZIP_Metadata{
@@ -124,3 +211,15 @@ func (self *ZIP) ParseMetadata(
   return v
   
 } // end ParseMetadata
+
+
+func init() {
+  mustRegister ( &Registration{
+    ID         : ID_ARCH_ZIP,
+    ShortName  : "ZIP",
+    Extensions : []string{".zip"},
+    Detect     : sniffMagic ( 0, "PK\x03\x04", 80 ),
+    Caps       : CAP_CONTAINER,
+    Type       : &ZIP{},
+  })
+} // end init
@@ -24,15 +24,22 @@
 package model
 
 import (
+  "bufio"
   "crypto/md5"
   "crypto/sha1"
+  "crypto/sha256"
+  "errors"
   "fmt"
+  "hash/crc32"
   "io"
   "log"
   "os"
+  "path/filepath"
+  "sync"
   "time"
   
   "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/model/header"
   "github.com/adriagipas/imgteka/view"
 )
 
@@ -41,44 +48,108 @@ import (
 
 // UTILS ///////////////////////////////////////////////////////////////////////
 
-func calcMD5( f *os.File ) (string,error) {
+type _Hashes struct {
+  md5        string
+  sha1       string
+  crc32      string
+  sha256     string
+  real_md5   string // Sense capçalera (igual que md5 si no en té)
+  real_sha1  string
+  real_crc32 string
+}
+
+
+// Calcula MD5, SHA1, CRC32 i SHA-256 llegint el fitxer una única
+// vegada. En la mateixa passada calcula també els hashes "reals" del
+// contingut sense la capçalera de copiadora o contenidor.
+func calcHashes( r io.Reader, size int64 ) (_Hashes,error) {
 
-  // Rebobina
-  if _,err:= f.Seek ( 0, 0 ); err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut calcular el MD5: %s", err )
+  // Detecta capçalera
+  br:= bufio.NewReaderSize ( r, header.DETECT_SIZE )
+  data,err:= br.Peek ( header.DETECT_SIZE )
+  if err != nil && err != io.EOF {
+    return _Hashes{},fmt.Errorf ( "No s'han pogut calcular els hashes: %w",
+      err )
+  }
+  skip,_:= header.Detect ( data, size )
+
+  // Calcula
+  h_md5:= md5.New ()
+  h_sha1:= sha1.New ()
+  h_crc32:= crc32.NewIEEE ()
+  h_sha256:= sha256.New ()
+  w:= io.MultiWriter ( h_md5, h_sha1, h_crc32, h_sha256 )
+  hr_md5:= md5.New ()
+  hr_sha1:= sha1.New ()
+  hr_crc32:= crc32.NewIEEE ()
+  if skip > 0 {
+    if _,err:= io.CopyN ( w, br, skip ); err != nil {
+      return _Hashes{},fmt.Errorf ( "No s'han pogut calcular els hashes: %w",
+        err )
+    }
+    w= io.MultiWriter ( w, hr_md5, hr_sha1, hr_crc32 )
+  }
+  if _,err:= io.Copy ( w, br ); err != nil {
+    return _Hashes{},fmt.Errorf ( "No s'han pogut calcular els hashes: %w",
+      err )
   }
 
-  // Calcula MD5
-  h:= md5.New ()
-  if _,err:= io.Copy ( h, f ); err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut calcular el MD5: %s", err )
+  ret:= _Hashes{
+    md5    : fmt.Sprintf ( "%x", h_md5.Sum ( nil ) ),
+    sha1   : fmt.Sprintf ( "%x", h_sha1.Sum ( nil ) ),
+    crc32  : fmt.Sprintf ( "%08x", h_crc32.Sum32 () ),
+    sha256 : fmt.Sprintf ( "%x", h_sha256.Sum ( nil ) ),
+  }
+  if skip > 0 {
+    ret.real_md5= fmt.Sprintf ( "%x", hr_md5.Sum ( nil ) )
+    ret.real_sha1= fmt.Sprintf ( "%x", hr_sha1.Sum ( nil ) )
+    ret.real_crc32= fmt.Sprintf ( "%08x", hr_crc32.Sum32 () )
+  } else {
+    ret.real_md5,ret.real_sha1,ret.real_crc32= ret.md5,ret.sha1,ret.crc32
   }
+  
+  return ret,nil
+  
+} // end calcHashes
 
-  ret:= fmt.Sprintf ( "%x", h.Sum ( nil ) )
 
-  return ret,nil
+// Clau per a detectar duplicats (vegeu _DUP_KEY).
+func (self *_Hashes) getKey() string {
+
+  if self.real_sha1 != "" { return self.real_sha1 }
+
+  return self.sha1
   
-} // end calcMD5
+} // end getKey
 
 
-func calcSHA1( f *os.File ) (string,error) {
+// Lector que s'atura quan es tanca el canal 'stop'.
+type _StopReader struct {
+  r    io.Reader
+  stop <-chan struct{}
+}
 
-  // Rebobina
-  if _,err:= f.Seek ( 0, 0 ); err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut calcular el SHA1: %s", err )
-  }
 
-  // Calcula MD5
-  h:= sha1.New ()
-  if _,err:= io.Copy ( h, f ); err != nil {
-    return "",fmt.Errorf ( "No s'ha pogut calcular el SHA1: %s", err )
+func (self *_StopReader) Read( p []byte ) (int,error) {
+
+  select {
+  case <-self.stop:
+    return 0,errStopped
+  default:
+    return self.r.Read ( p )
   }
   
-  ret:= fmt.Sprintf ( "%x", h.Sum ( nil ) )
-  
-  return ret,nil
-  
-} // end calcSHA1
+} // end Read
+
+
+var errStopped= errors.New ( "S'ha aturat el procés" )
+
+
+// Barra de progrés que no mostra res. Per a tasques en segon pla.
+type _NullProgressBar struct {}
+
+func (self *_NullProgressBar) Close() {}
+func (self *_NullProgressBar) Set( msg string, f float32 ) {}
 
 
 func linkFile( oldname string, newname string ) error {
@@ -125,7 +196,31 @@ type Files struct {
   plats *Platforms
   dirs  *Dirs
   cmds  *Commands
+  dats  *Dats
   v     map[int64]*File
+
+  // Càlcul en segon pla dels hashes que falten
+  bf_stop chan struct{}
+  bf_done chan struct{}
+
+  // Serialitza les escriptures en la base de dades del càlcul en
+  // segon pla amb la transacció de Add, que pot durar molt (còpia).
+  write_mu sync.Mutex
+
+  // Fitxers actualitzats en segon pla que s'han de tornar a carregar
+  bf_mu      sync.Mutex
+  bf_updated []int64
+}
+
+
+// Fitxer pendent de calcular els hashes nous.
+type _BackfillFile struct {
+  id          int64
+  name        string
+  file_type   int
+  size        int64
+  sha1        string
+  compression string
 }
 
 
@@ -135,6 +230,7 @@ func NewFiles (
   plats *Platforms,
   dirs  *Dirs,
   cmds  *Commands,
+  dats  *Dats,
 
 ) *Files {
 
@@ -143,15 +239,48 @@ func NewFiles (
     plats : plats,
     dirs  : dirs,
     cmds  : cmds,
+    dats  : dats,
     v     : nil,
   }
-  ret.v= make(map[int64]*File)
+  ret.reset ()
   
   return &ret
   
 } // end NewFiles
 
 
+func (self *Files) reset() {
+  self.v= make(map[int64]*File)
+} // end reset
+
+
+// Apunta que el fitxer ha canviat en la base de dades. Es pot cridar
+// des del fil del càlcul en segon pla.
+func (self *Files) markUpdated( id int64 ) {
+
+  self.bf_mu.Lock ()
+  self.bf_updated= append(self.bf_updated,id)
+  self.bf_mu.Unlock ()
+  
+} // end markUpdated
+
+
+// Descarta de 'v' els fitxers que han canviat en segon pla perquè es
+// tornen a carregar. S'ha de cridar abans d'accedir a 'v'.
+func (self *Files) dropUpdated() {
+
+  self.bf_mu.Lock ()
+  for _,id:= range self.bf_updated {
+    delete(self.v,id)
+  }
+  self.bf_updated= nil
+  self.bf_mu.Unlock ()
+  
+} // end dropUpdated
+
+
+// Torna la descripció dels fitxers idèntics que ja estaven en la
+// biblioteca (buit si no n'hi ha).
 func (self *Files) Add(
 
   e         *Entry,
@@ -160,7 +289,7 @@ func (self *Files) Add(
   ftype     int,
   create_pb func() view.ProgressBar,
 
-) error {
+) ([]string,error) {
   
   // Crea barra de progress
   pb:= create_pb ()
@@ -170,86 +299,316 @@ func (self *Files) Add(
   pb.Set ( "Comprova que existeix...", 0.1 )
   path,err:= readLink ( path )
   if err != nil {
-    return fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s': %s", path, err )
+    return nil,fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s': %s", path, err )
   }
   f,err:= os.Open ( path )
   if err != nil {
-    return fmt.Errorf ( "No s'ha pogut obrir el fitxer '%s': %s", path, err )
+    return nil,fmt.Errorf ( "No s'ha pogut obrir el fitxer '%s': %s", path, err )
   }
   defer f.Close ()
   info,err:= f.Stat ()
   if err != nil {
-    return fmt.Errorf ( "No s'ha pogut llegir el fitxer '%s': %s", path, err )
+    return nil,fmt.Errorf ( "No s'ha pogut llegir el fitxer '%s': %s", path, err )
   }
   size:= info.Size ()
   
   // Comprova tipus i obté metadades
   pb.Set ( "Comprova tipus i obté metadades...", 0.2 )
   ft,err:= file_type.Get ( ftype )
-  if err != nil { return err }
+  if err != nil { return nil,err }
   md,err:= ft.GetMetadata ( path )
-  if err != nil { return err }
+  if err != nil { return nil,err }
   
-  // Calcula MD5
-  pb.Set ( "Calcula MD5...", 0.3 )
-  md5,err:= calcMD5 ( f )
-  if err != nil { return err }
-
-  // Calcula SHA1
-  pb.Set ( "Calcula SHA1...", 0.4 )
-  sha1,err:= calcSHA1 ( f )
-  if err != nil { return err }
+  // Calcula hashes
+  pb.Set ( "Calcula hashes...", 0.3 )
+  hashes,err:= calcHashes ( f, size )
+  if err != nil { return nil,err }
+
+  // Fitxers idèntics que ja estan en la biblioteca
+  dups,err:= self.db.GetFilesWithKey ( hashes.getKey () )
+  if err != nil { return nil,err }
+  dups_text:= make([]string,len(dups))
+  for i:= range dups {
+    dups_text[i]= dups[i].String ()
+  }
 
   // Obté noms i stamp
+  compression,err:= self.db.GetCompression ( ftype )
+  if err != nil { return nil,err }
   plat_name:= self.plats.GetPlatform ( e.GetPlatformID () ).GetShortName ()
-  ename,err:= self.dirs.GetFileNameEntries ( plat_name, e.GetName (), name )
-  if err != nil { return err }
-  fname,err:= self.dirs.GetFileNameFiles ( ft.GetShortName (), name )
-  if err != nil { return err }
+  ename,err:= self.dirs.GetFileNameEntries ( plat_name, e.GetName (),
+    compressedName ( name, compression ) )
+  if err != nil { return nil,err }
+  fname,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), name,
+    hashes.sha1, compression )
+  if err != nil { return nil,err }
   time_now:= time.Now ().Unix ()
+
+  // Quan es desen per SHA1 els fitxers idèntics comparteixen el
+  // fitxer de 'files'.
+  shared:= false
+  if self.dirs.GetStore () == STORE_SHA1 {
+    if _,err:= os.Stat ( fname ); err == nil {
+      shared= true
+    }
+  }
+  mode,err:= self.db.GetImportMode ()
+  if err != nil { return nil,err }
   
   // Intenta commit
   pb.Set ( "Insereix en base de dades...", 0.6 )
+  self.write_mu.Lock ()
+  defer self.write_mu.Unlock ()
   if err:= self.db.RegisterFileWithoutCommit ( name, e.GetID (), ftype,
-    size, md5, sha1, md, time_now ); err != nil {
-    return err
+    size, &hashes, md, time_now, compression ); err != nil {
+    return nil,err
   }
 
   // Crea fitxers
   pb.Set ( "Desa fitxers en disc...", 0.7 )
-  if err:= linkFile ( path, ename ); err != nil {
-    self.db.RollbackLastTransaction ()
-    return err
+  if !shared {
+    if err:= importFile ( path, fname, mode, compression, size, &hashes, pb );
+    err != nil {
+      self.db.RollbackLastTransaction ()
+      return nil,err
+    }
   }
-  if err:= linkFile ( path, fname ); err != nil {
-    if err2:= os.Remove ( ename ); err2 != nil { log.Fatal ( err2 )}
+  if err:= linkFile ( fname, ename ); err != nil {
+    if !shared {
+      if err2:= os.Remove ( fname ); err2 != nil { log.Fatal ( err2 ) }
+    }
     self.db.RollbackLastTransaction ()
-    return err
+    return nil,err
   }
   
   // Consolida commit
   if err:= self.db.CommitLastTransaction (); err != nil {
     if err2:= os.Remove ( ename ); err2 != nil {
-      os.Remove ( fname )
+      if !shared { os.Remove ( fname ) }
       log.Fatal ( err2 )
     }
-    if err2:= os.Remove ( fname ); err2 != nil { log.Fatal ( err2 ) }
-    return err
+    if !shared {
+      if err2:= os.Remove ( fname ); err2 != nil { log.Fatal ( err2 ) }
+    }
+    return nil,err
+  }
+
+  // Elimina l'original
+  if mode == IMPORT_MOVE {
+    if err:= os.Remove ( path ); err != nil {
+      log.Printf ( "No s'ha pogut eliminar '%s': %s", path, err )
+    }
   }
   
-  return nil
+  return dups_text,nil
   
 } // end Add
 
 
+// Calcula els hashes que falten (CRC32, SHA-256 i hashes reals) dels
+// fitxers registrats amb versions anteriors. Torna el nombre de fitxers
+// actualitzats. Si es tanca 'stop' s'atura sense error.
+func (self *Files) Backfill(
+
+  stop      <-chan struct{},
+  create_pb func() view.ProgressBar,
+
+) (int,error) {
+
+  // Crea barra de progrés
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Fitxers pendents
+  bfiles,err:= self.db.GetFilesWithoutHashes ()
+  if err != nil { return 0,err }
+
+  // Calcula
+  n:= 0
+  for i,bf:= range bfiles {
+    pb.Set ( bf.name, float32(i)/float32(len(bfiles)) )
+    ft,err:= file_type.Get ( bf.file_type )
+    if err != nil { return n,err }
+    path,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), bf.name,
+      bf.sha1, bf.compression )
+    if err != nil { return n,err }
+    f,err:= openStoreFile ( path, bf.compression )
+    if err != nil {
+      log.Printf ( "No s'ha pogut obrir el fitxer '%s': %s", path, err )
+      continue
+    }
+    hashes,err:= calcHashes ( &_StopReader{f,stop}, bf.size )
+    f.Close ()
+    if errors.Is ( err, errStopped ) {
+      return n,nil
+    } else if err != nil {
+      log.Printf ( "%s: %s", path, err )
+      continue
+    }
+    // Si la base de dades està ocupada es deixa per a la pròxima
+    // vegada.
+    self.write_mu.Lock ()
+    err= self.db.UpdateFileHashes ( bf.id, &hashes )
+    self.write_mu.Unlock ()
+    if err != nil {
+      log.Printf ( "No s'han pogut desar els hashes de '%s': %s", path, err )
+      continue
+    }
+    self.markUpdated ( bf.id )
+    n++
+  }
+  
+  return n,nil
+  
+} // end Backfill
+
+
+// Tipus de fitxer amb metadades que s'han ampliat, i clau que tenen
+// les metadades noves.
+var _METADATA_REFRESH= map[int]string{
+  file_type.ID_FLP_FAT12 : "Contents",
+}
+
+
+// Torna a calcular les metadades dels fitxers registrats abans que
+// s'ampliaren (p.e. la llista de fitxers dels disquets FAT12). Torna
+// el nombre de fitxers actualitzats. Si es tanca 'stop' s'atura sense
+// error.
+func (self *Files) RefreshMetadata(
+
+  stop      <-chan struct{},
+  create_pb func() view.ProgressBar,
+
+) (int,error) {
+
+  // Crea barra de progrés
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Fitxers pendents
+  var bfiles []_BackfillFile
+  for ftype,key:= range _METADATA_REFRESH {
+    tmp,err:= self.db.GetFilesWithOldMetadata ( ftype, key )
+    if err != nil { return 0,err }
+    bfiles= append(bfiles,tmp...)
+  }
+  if len(bfiles) == 0 { return 0,nil }
+  tmp_dir,clean,err:= self.newTempDir ( "metadata" )
+  if err != nil { return 0,err }
+  defer clean ()
+
+  // Calcula
+  n:= 0
+  for i,bf:= range bfiles {
+    select {
+    case <-stop:
+      return n,nil
+    default:
+    }
+    pb.Set ( bf.name, float32(i)/float32(len(bfiles)) )
+    ft,err:= file_type.Get ( bf.file_type )
+    if err != nil { return n,err }
+    path,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), bf.name,
+      bf.sha1, bf.compression )
+    if err != nil { return n,err }
+    if bf.compression != "" {
+      tmp_fn:= filepath.Join ( tmp_dir, "file" )
+      if err:= uncompressFile ( path, tmp_fn, bf.compression ); err != nil {
+        log.Printf ( "No s'ha pogut descomprimir '%s': %s", path, err )
+        continue
+      }
+      path= tmp_fn
+    }
+    md,err:= ft.GetMetadata ( path )
+    if err != nil {
+      log.Printf ( "%s: %s", path, err )
+      continue
+    }
+    self.write_mu.Lock ()
+    err= self.db.UpdateFileMetadata ( bf.id, md )
+    self.write_mu.Unlock ()
+    if err != nil {
+      log.Printf ( "No s'han pogut desar les metadades de '%s': %s",
+        path, err )
+      continue
+    }
+    self.markUpdated ( bf.id )
+    n++
+  }
+  
+  return n,nil
+  
+} // end RefreshMetadata
+
+
+// Executa Backfill en segon pla fins que acaba o es crida a
+// StopBackfill.
+func (self *Files) StartBackfill() {
+
+  if self.bf_stop != nil { return }
+  self.bf_stop= make(chan struct{})
+  self.bf_done= make(chan struct{})
+  go func() {
+    defer close ( self.bf_done )
+    n,err:= self.Backfill ( self.bf_stop, func() view.ProgressBar {
+      return &_NullProgressBar{}
+    })
+    if err != nil {
+      log.Printf ( "Error al calcular els hashes que falten: %s", err )
+    } else if n > 0 {
+      log.Printf ( "S'han calculat els hashes que faltaven de %d fitxers", n )
+    }
+    n,err= self.RefreshMetadata ( self.bf_stop, func() view.ProgressBar {
+      return &_NullProgressBar{}
+    })
+    if err != nil {
+      log.Printf ( "Error al actualitzar les metadades: %s", err )
+    } else if n > 0 {
+      log.Printf ( "S'han actualitzat les metadades de %d fitxers", n )
+    }
+  }()
+  
+} // end StartBackfill
+
+
+// Atura el càlcul en segon pla i espera que acabe. Torna cert si
+// estava en marxa, per a tornar-lo a llançar amb StartBackfill.
+func (self *Files) StopBackfill() bool {
+
+  if self.bf_stop == nil { return false }
+  close ( self.bf_stop )
+  <-self.bf_done
+  self.bf_stop,self.bf_done= nil,nil
+  self.dropUpdated ()
+  
+  return true
+  
+} // end StopBackfill
+
+
+func (self *Files) Exists( id int64 ) bool {
+
+  self.dropUpdated ()
+  if _,ok:= self.v[id]; ok {
+    return true
+  }
+  ret,err:= self.db.FileExists ( id )
+  if err != nil { log.Fatal ( err ) }
+  
+  return ret
+  
+} // end Exists
+
+
 func (self *Files) Get( id int64 ) *File {
 
+  self.dropUpdated ()
   ret,ok:= self.v[id]
   if !ok {
-    name,entry_id,file_type,size,md5,sha1,json,last_check:= 
+    name,entry_id,file_type,size,hashes,json,last_check,compression:= 
       self.db.GetFile ( id )
-    ret= NewFile ( self.dirs, self.cmds, id, name, entry_id,
-      file_type, size, md5, sha1, json, last_check )
+    ret= NewFile ( self.dirs, self.cmds, self.dats, id, name, entry_id,
+      file_type, size, &hashes, json, last_check, compression )
     self.v[id]= ret
   }
   
@@ -258,6 +617,17 @@ func (self *Files) Get( id int64 ) *File {
 } // end Get
 
 
+// Identificadors de tots els fitxers de la biblioteca (sense filtre).
+func (self *Files) GetIDs() []int64 {
+
+  ret,err:= self.db.GetFileIDs ()
+  if err != nil { log.Fatal ( err ) }
+
+  return ret
+  
+} // end GetIDs
+
+
 func (self *Files) Remove( id int64, e *Entry ) error {
 
   // Obté fitxer
@@ -266,15 +636,26 @@ func (self *Files) Remove( id int64, e *Entry ) error {
   // Obté noms
   plat_name:= self.plats.GetPlatform ( e.GetPlatformID () ).GetShortName ()
   ename,err:= self.dirs.GetFileNameEntries (
-    plat_name, e.GetName (), f.GetName () )
+    plat_name, e.GetName (), f.getStoredName () )
   if err != nil { return err }
   ft,err:= file_type.Get ( f.GetTypeID () )
   if err != nil { return err }
-  fname,err:= self.dirs.GetFileNameFiles ( ft.GetShortName (), f.GetName () )
+  fname,err:= self.dirs.GetFileNameStore ( ft.GetShortName (),
+    f.GetName (), f.GetSHA1 (), f.GetCompression () )
   if err != nil { return err }
-  tname,err:= self.dirs.GetFileNameTemp ( ft.GetShortName (), f.GetName () )
+  tname,err:= self.dirs.GetFileNameTemp ( ft.GetShortName (),
+    f.getStoredName () )
   if err != nil { return err }
   defer os.Remove ( tname )
+
+  // Quan es desen per SHA1 el fitxer de 'files' no s'esborra si
+  // altres fitxers el comparteixen.
+  shared:= false
+  if self.dirs.GetStore () == STORE_SHA1 {
+    n,err:= self.db.GetNumFilesWithSHA1 ( f.GetSHA1 (), f.GetCompression () )
+    if err != nil { return err }
+    shared= n > 1
+  }
   
   // Crea fitxer temporal
   if err:= linkFile ( fname, tname ); err != nil {
@@ -287,8 +668,11 @@ func (self *Files) Remove( id int64, e *Entry ) error {
   }
   
   // Intenta esborrar fitxers
+  var err_f error
   err_e:= os.Remove ( ename )
-  err_f:= os.Remove ( fname )
+  if !shared {
+    err_f= os.Remove ( fname )
+  }
   if err_e != nil || err_f != nil {
     self.db.RollbackLastTransaction ()
     if err_e != nil {
@@ -307,10 +691,18 @@ func (self *Files) Remove( id int64, e *Entry ) error {
   // Força commit. Si falla intente recuperar
   if err:= self.db.CommitLastTransaction (); err != nil {
     if err2:= linkFile ( tname, ename ); err2 != nil { log.Fatal ( err2 ) }
-    if err2:= linkFile ( tname, fname ); err2 != nil { log.Fatal ( err2 ) }
+    if !shared {
+      if err2:= linkFile ( tname, fname ); err2 != nil { log.Fatal ( err2 ) }
+    }
     return err
   }
 
+  // Esborra la còpia de la memòria cau, un altre fitxer amb el mateix
+  // nom podria reaprofitar-la.
+  if cname,err:= f.getCachedName (); err == nil {
+    os.Remove ( cname )
+  }
+  
   // Esborra del mapa
   delete(self.v,id)
   
@@ -327,20 +719,22 @@ func (self *Files) UpdateName( id int64, e *Entry, new_name string ) error {
   // Obté nom entry
   plat_name:= self.plats.GetPlatform ( e.GetPlatformID () ).GetShortName ()
   old_ename,err:= self.dirs.GetFileNameEntries (
-    plat_name, e.GetName (), f.GetName () )
+    plat_name, e.GetName (), f.getStoredName () )
   if err != nil { return err }
   new_ename,err:= self.dirs.GetFileNameEntries (
-    plat_name, e.GetName (), new_name )
+    plat_name, e.GetName (), compressedName ( new_name, f.GetCompression () ) )
   if err != nil { return err }
   
-  // Obté nom files
+  // Obté nom files. Si es desen per SHA1 no canvia.
   ft,err:= file_type.Get ( f.GetTypeID () )
   if err != nil { return err }
-  old_fname,err:= self.dirs.GetFileNameFiles ( ft.GetShortName (),
-    f.GetName () )
+  old_fname,err:= self.dirs.GetFileNameStore ( ft.GetShortName (),
+    f.GetName (), f.GetSHA1 (), f.GetCompression () )
   if err != nil { return err }
-  new_fname,err:= self.dirs.GetFileNameFiles ( ft.GetShortName (), new_name )
+  new_fname,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), new_name,
+    f.GetSHA1 (), f.GetCompression () )
   if err != nil { return err }
+  move_f:= old_fname != new_fname
 
   // Intenta commit
   if err:= self.db.UpdateFileNameWithoutCommit ( id, new_name ); err != nil {
@@ -352,7 +746,10 @@ func (self *Files) UpdateName( id int64, e *Entry, new_name string ) error {
     self.db.RollbackLastTransaction ()
     return err
   }
-  if err:= os.Rename ( old_fname, new_fname ); err != nil {
+  if move_f {
+    err= os.Rename ( old_fname, new_fname )
+  }
+  if err != nil {
     self.db.RollbackLastTransaction ()
     // Intenta recuperar...
     if err2:= os.Rename ( new_ename, old_ename ); err2 != nil {
@@ -364,8 +761,11 @@ func (self *Files) UpdateName( id int64, e *Entry, new_name string ) error {
   // Intenta commit final
   if err:= self.db.CommitLastTransaction (); err != nil {
     // Intent desesperat per recuperar...
+    var err_f error
     err_e:= os.Rename ( new_ename, old_ename )
-    err_f:= os.Rename ( new_fname, old_fname )
+    if move_f {
+      err_f= os.Rename ( new_fname, old_fname )
+    }
     if err_e != nil { log.Fatal ( err_e ) }
     if err_f != nil { log.Fatal ( err_f ) }
     return err
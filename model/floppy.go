@@ -0,0 +1,126 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  floppy.go - Creació i modificació de disquets FAT12. Els fitxers
+ *              de la biblioteca són de sols lectura, per això el
+ *              resultat sempre s'afegeix com un fitxer nou.
+ */
+
+package model
+
+import (
+  "fmt"
+  "path/filepath"
+
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func (self *Model) GetFloppyFormats() []string {
+  return file_type.GetFloppyFormats ()
+} // end GetFloppyFormats
+
+
+// Indica si és un disquet FAT12 en el qual es poden escriure fitxers.
+func (self *File) IsFloppy() bool {
+  return self.file_type_id == file_type.ID_FLP_FAT12
+} // end IsFloppy
+
+
+// Afegeix a l'entrada un disquet FAT12 buit amb el format indicat.
+func (self *Entry) CreateFloppy(
+
+  name      string,
+  format    string,
+  create_pb func() view.ProgressBar,
+  
+) error {
+
+  files:= self.entries.files
+  tmp_dir,clean,err:= files.newTempDir ( "floppy" )
+  if err != nil { return err }
+  defer clean ()
+  tmp_fn:= filepath.Join ( tmp_dir, "floppy.img" )
+  if err:= file_type.CreateFloppy ( tmp_fn, format ); err != nil {
+    return err
+  }
+
+  dups,err:= self.AddFile ( tmp_fn, name, file_type.ID_FLP_FAT12, create_pb )
+  if err != nil { return err }
+  logDuplicates ( name, dups )
+
+  return nil
+  
+} // end CreateFloppy
+
+
+// Afegeix a l'entrada, amb el nom 'name', una còpia del disquet
+// 'file_id' amb els fitxers locals 'paths' escrits en el directori
+// 'dir' del disquet. El disquet original no es modifica.
+func (self *Entry) AddFilesToFloppy(
+
+  file_id   int64,
+  name      string,
+  paths     []string,
+  dir       string,
+  create_pb func() view.ProgressBar,
+  
+) error {
+
+  // Comprova disquet
+  files:= self.entries.files
+  if !files.Exists ( file_id ) {
+    return fmt.Errorf ( "El fitxer indicat (%d) no existeix", file_id )
+  }
+  f:= files.Get ( file_id )
+  if f.GetEntryID () != self.id {
+    return fmt.Errorf ( "El fitxer %d no pertany a l'entrada '%s'",
+      file_id, self.name )
+  }
+  if !f.IsFloppy () {
+    return fmt.Errorf ( "El fitxer '%s' no és un disquet FAT12", f.GetName () )
+  }
+  src:= f.GetPath ()
+  if src == "" {
+    return fmt.Errorf ( "No s'ha pogut accedir al fitxer '%s'", f.GetName () )
+  }
+
+  // Escriu la còpia
+  tmp_dir,clean,err:= files.newTempDir ( "floppy" )
+  if err != nil { return err }
+  defer clean ()
+  tmp_fn:= filepath.Join ( tmp_dir, "floppy.img" )
+  if err:= file_type.WriteFloppy ( src, tmp_fn, paths, dir ); err != nil {
+    return err
+  }
+  
+  dups,err:= self.AddFile ( tmp_fn, name, file_type.ID_FLP_FAT12, create_pb )
+  if err != nil { return err }
+  logDuplicates ( name, dups )
+
+  return nil
+  
+} // end AddFilesToFloppy
@@ -0,0 +1,425 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  fsck.go - Comprova que el directori de dades és coherent amb la
+ *            base de dades i, opcionalment, ho repara.
+ */
+
+package model
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+  "path"
+  "path/filepath"
+  "time"
+
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Directori (dins del de dades) on es mouen els fitxers i directoris
+// que no es poden reparar de manera segura.
+const _ROOT_QUARANTINE= "quarantine"
+
+
+type _Fsck struct {
+  root       string // Directori de dades
+  repair     bool
+  quarantine string // Relatiu a 'root'
+  known      map[string]bool
+  folders    map[string]bool
+  report     *FsckReport
+}
+
+
+func (self *_Fsck) abs( rel string ) string {
+  return filepath.Join ( self.root, filepath.FromSlash ( rel ) )
+} // end abs
+
+
+// Afegeix un problema. Si s'està reparant i 'fix' no és nil el
+// repara.
+func (self *_Fsck) add( typ int, rel string, desc string, fix func() error ) {
+
+  p:= FsckProblem{
+    typ  : typ,
+    path : rel,
+    desc : desc,
+  }
+  if self.repair && fix != nil {
+    if err:= fix (); err != nil {
+      p.err= err
+    } else {
+      p.fixed= true
+    }
+  }
+  self.report.problems= append(self.report.problems,p)
+
+} // end add
+
+
+func (self *_Fsck) moveToQuarantine( rel string ) error {
+
+  dst:= self.abs ( path.Join ( self.quarantine, rel ) )
+  if err:= os.MkdirAll ( filepath.Dir ( dst ), 0755 ); err != nil {
+    return err
+  }
+  self.report.quarantine= self.abs ( self.quarantine )
+
+  return os.Rename ( self.abs ( rel ), dst )
+
+} // end moveToQuarantine
+
+
+func (self *_Fsck) relink( src string, dst string ) error {
+
+  if err:= os.MkdirAll ( filepath.Dir ( self.abs ( dst ) ), 0755 );
+  err != nil {
+    return err
+  }
+
+  return linkFile ( self.abs ( src ), self.abs ( dst ) )
+
+} // end relink
+
+
+// Indica si el contingut de 'rel' és el registrat en la base de dades.
+func (self *_Fsck) checkContent( rel string, f *_BackupFile ) bool {
+
+  fd,err:= os.Open ( self.abs ( rel ) )
+  if err != nil { return false }
+  defer fd.Close ()
+
+  return copyAndCheck ( io.Discard, fd, f ) == nil
+
+} // end checkContent
+
+
+func (self *_Fsck) checkFolders( folders [][2]string ) error {
+
+  for _,folder:= range folders {
+    rel:= path.Join ( _ROOT_ENTRIES, folder[0], folder[1] )
+    self.folders[rel]= true
+    _,err:= os.Stat ( self.abs ( rel ) )
+    if errors.Is ( err, fs.ErrNotExist ) {
+      self.add ( FSCK_MISSING_FOLDER, rel, "", func() error {
+        return os.MkdirAll ( self.abs ( rel ), 0755 )
+      })
+    } else if err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end checkFolders
+
+
+// Comprova el fitxer de 'files' i els seus enllaços en 'entries'. Tots
+// han de ser el mateix fitxer (mateix inode) amb permisos 0400.
+func (self *_Fsck) checkFile( f *_BackupFile ) error {
+
+  // Consulta estat
+  paths:= append([]string{f.Path},f.Links...)
+  infos:= make([]fs.FileInfo,len(paths))
+  ref,linked:= -1,true
+  for i,p:= range paths {
+    self.known[p]= true
+    info,err:= os.Stat ( self.abs ( p ) )
+    if errors.Is ( err, fs.ErrNotExist ) { continue }
+    if err != nil { return err }
+    infos[i]= info
+    if ref == -1 {
+      ref= i
+    } else if !os.SameFile ( infos[ref], info ) {
+      linked= false
+    }
+  }
+  if ref == -1 {
+    self.add ( FSCK_MISSING_FILE, f.Path, "", nil )
+    return nil
+  }
+
+  // Si no són el mateix fitxer es pren com a referència el primer que
+  // té el contingut registrat.
+  if !linked {
+    ref= -1
+    for i,info:= range infos {
+      if info != nil && self.checkContent ( paths[i], f ) {
+        ref= i
+        break
+      }
+    }
+    if ref == -1 {
+      self.add ( FSCK_NOT_LINKED, f.Path,
+        "cap còpia coincideix amb els hashes registrats", nil )
+      return nil
+    }
+  }
+
+  // Enllaços
+  for i,info:= range infos {
+    if i == ref { continue }
+    src,dst:= paths[ref],paths[i]
+    if info == nil {
+      self.add ( FSCK_MISSING_LINK, dst, "", func() error {
+        return self.relink ( src, dst )
+      })
+    } else if !os.SameFile ( infos[ref], info ) {
+      self.add ( FSCK_NOT_LINKED, dst, "", func() error {
+        if err:= self.moveToQuarantine ( dst ); err != nil { return err }
+        return self.relink ( src, dst )
+      })
+    }
+  }
+
+  // Permisos
+  if perm:= infos[ref].Mode ().Perm (); perm != 0400 {
+    self.add ( FSCK_PERMISSIONS, paths[ref], fmt.Sprintf ( "%04o", perm ),
+      func() error {
+        return os.Chmod ( self.abs ( paths[ref] ), 0400 )
+      })
+  }
+
+  return nil
+
+} // end checkFile
+
+
+func (self *_Fsck) addOrphan( rel string, is_dir bool ) {
+
+  typ:= FSCK_ORPHAN_FILE
+  if is_dir { typ= FSCK_ORPHAN_FOLDER }
+  self.add ( typ, rel, "", func() error {
+    return self.moveToQuarantine ( rel )
+  })
+
+} // end addOrphan
+
+
+// Llig un directori. Si no existeix torna una llista buida.
+func (self *_Fsck) readDir( rel string ) ([]fs.DirEntry,error) {
+
+  ret,err:= os.ReadDir ( self.abs ( rel ) )
+  if errors.Is ( err, fs.ErrNotExist ) { return nil,nil }
+
+  return ret,err
+
+} // end readDir
+
+
+// Busca fitxers en 'files' que no estan en la base de dades.
+func (self *_Fsck) scanFiles() error {
+
+  types,err:= self.readDir ( _ROOT_FILES )
+  if err != nil { return err }
+  for _,t:= range types {
+    trel:= path.Join ( _ROOT_FILES, t.Name () )
+    if !t.IsDir () {
+      self.addOrphan ( trel, false )
+      continue
+    }
+    files,err:= self.readDir ( trel )
+    if err != nil { return err }
+    for _,f:= range files {
+      if frel:= path.Join ( trel, f.Name () ); !self.known[frel] {
+        self.addOrphan ( frel, f.IsDir () )
+      }
+    }
+  }
+
+  return nil
+
+} // end scanFiles
+
+
+// Busca directoris d'entrades que no estan en la base de dades i
+// fitxers dins de les entrades que no estan registrats.
+func (self *_Fsck) scanEntries() error {
+
+  plats,err:= self.readDir ( _ROOT_ENTRIES )
+  if err != nil { return err }
+  for _,p:= range plats {
+    prel:= path.Join ( _ROOT_ENTRIES, p.Name () )
+    if !p.IsDir () {
+      self.addOrphan ( prel, false )
+      continue
+    }
+    entries,err:= self.readDir ( prel )
+    if err != nil { return err }
+    for _,e:= range entries {
+      erel:= path.Join ( prel, e.Name () )
+      if !e.IsDir () || !self.folders[erel] {
+        self.addOrphan ( erel, e.IsDir () )
+        continue
+      }
+      files,err:= self.readDir ( erel )
+      if err != nil { return err }
+      for _,f:= range files {
+        if frel:= path.Join ( erel, f.Name () ); !self.known[frel] {
+          self.addOrphan ( frel, f.IsDir () )
+        }
+      }
+    }
+  }
+
+  return nil
+
+} // end scanEntries
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+const (
+  FSCK_MISSING_FILE   = iota // Falten el fitxer i tots els enllaços
+  FSCK_MISSING_LINK          // Falta un dels enllaços
+  FSCK_NOT_LINKED            // Enllaços que no són el mateix fitxer
+  FSCK_PERMISSIONS           // Permisos diferents de 0400
+  FSCK_ORPHAN_FILE           // Fitxer que no està en la base de dades
+  FSCK_ORPHAN_FOLDER         // Directori sense entrada en la base de dades
+  FSCK_MISSING_FOLDER        // Falta el directori d'una entrada
+)
+
+
+func FsckProblemToText( typ int ) string {
+
+  switch typ {
+  case FSCK_MISSING_FILE:
+    return "Fitxer perdut"
+  case FSCK_MISSING_LINK:
+    return "Falta l'enllaç"
+  case FSCK_NOT_LINKED:
+    return "Enllaç trencat"
+  case FSCK_PERMISSIONS:
+    return "Permisos incorrectes"
+  case FSCK_ORPHAN_FILE:
+    return "Fitxer orfe"
+  case FSCK_ORPHAN_FOLDER:
+    return "Directori orfe"
+  case FSCK_MISSING_FOLDER:
+    return "Falta el directori"
+  default:
+    return "Desconegut"
+  }
+
+} // end FsckProblemToText
+
+
+type FsckProblem struct {
+  typ   int
+  path  string // Relatiu al directori de dades
+  desc  string
+  fixed bool
+  err   error // Error en la reparació
+}
+
+func (self *FsckProblem) GetType() int { return self.typ }
+func (self *FsckProblem) GetPath() string { return self.path }
+func (self *FsckProblem) GetDescription() string { return self.desc }
+func (self *FsckProblem) IsFixed() bool { return self.fixed }
+func (self *FsckProblem) GetError() error { return self.err }
+
+
+type FsckReport struct {
+  problems   []FsckProblem
+  quarantine string
+}
+
+func (self *FsckReport) GetNumProblems() int { return len(self.problems) }
+func (self *FsckReport) GetProblem( i int ) *FsckProblem {
+  return &self.problems[i]
+} // end GetProblem
+
+// Directori on s'han mogut els fitxers en quarantena. Buit si no se
+// n'ha mogut cap.
+func (self *FsckReport) GetQuarantineDir() string { return self.quarantine }
+
+
+// Comprova que els fitxers de la biblioteca són coherents amb la base
+// de dades: que cada fitxer registrat existeix en 'files' i en la seua
+// entrada com a enllaços al mateix fitxer i amb permisos 0400, que no
+// hi ha fitxers ni directoris d'entrades que no estiguen registrats i
+// que existeixen els directoris de totes les entrades. Si 'repair' és
+// cert torna a crear els enllaços, corregeix els permisos i mou a
+// quarantena el que no es pot reparar de manera segura.
+func (self *Model) Fsck(
+
+  repair    bool,
+  create_pb func() view.ProgressBar,
+
+) (*FsckReport,error) {
+
+  // Prepara
+  froot,err:= self.dirs.GetDataFileName ( _ROOT_FILES )
+  if err != nil { return nil,err }
+  chk:= _Fsck{
+    root       : filepath.Dir ( froot ),
+    repair     : repair,
+    quarantine : path.Join ( _ROOT_QUARANTINE,
+      time.Now ().Format ( "20060102-150405" ) ),
+    known      : make(map[string]bool),
+    folders    : make(map[string]bool),
+    report     : &FsckReport{problems : make([]FsckProblem,0)},
+  }
+  if repair && self.files.StopBackfill () {
+    defer self.files.StartBackfill ()
+  }
+
+  // Directoris de les entrades
+  folders,err:= loadEntryFolders ( self.db.conn )
+  if err != nil { return nil,err }
+  if err:= chk.checkFolders ( folders ); err != nil { return nil,err }
+
+  // Fitxers registrats
+  files,err:= loadBackupFiles ( self.db.conn )
+  if err != nil { return nil,err }
+  if err:= setBackupPaths ( files, self.dirs.GetStore () ); err != nil {
+    return nil,err
+  }
+  pb:= create_pb ()
+  for i:= range files {
+    pb.Set ( files[i].Path, float32(i)/float32(len(files)) )
+    if err:= chk.checkFile ( &files[i] ); err != nil {
+      pb.Close ()
+      return nil,err
+    }
+  }
+  pb.Close ()
+
+  // Fitxers i directoris no registrats
+  if err:= chk.scanFiles (); err != nil { return nil,err }
+  if err:= chk.scanEntries (); err != nil { return nil,err }
+
+  return chk.report,nil
+
+} // end Fsck
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  main.go - Detecció de capçaleres de copiadors i contenidors (iNES,
+ *            FDS, A78, LNX, SMC, etc.). Els bolcadors afegeixen aquestes
+ *            capçaleres a les ROMs, però els DATs i la resta de còpies
+ *            del mateix joc sovint no les tenen. El hash "real" és el
+ *            del contingut sense la capçalera.
+ */
+
+package header
+
+import (
+  "bytes"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _Rule struct {
+  name   string
+  offset int    // Posició de la signatura
+  magic  []byte // Signatura
+  size   int64  // Grandària de la capçalera
+  check  func(data []byte,size int64) bool // Comprovació alternativa
+}
+
+
+// Comprova la capçalera interna d'una ROM de SNES en 'off' (0x7FC0
+// LoROM, 0xFFC0 HiROM): el mode de mapa ha de ser 0x2X i el checksum
+// i el seu complement han de sumar 0xFFFF.
+func checkSNESHeader( data []byte, off int ) bool {
+
+  if len(data) < off+32 { return false }
+  if data[off+0x15]&0xE0 != 0x20 { return false }
+  comp:= uint16(data[off+0x1C]) | uint16(data[off+0x1D])<<8
+  sum:= uint16(data[off+0x1E]) | uint16(data[off+0x1F])<<8
+  
+  return comp^sum == 0xFFFF
+  
+} // end checkSNESHeader
+
+
+// Capçalera de copiadora de SNES (SMC/SWC/FIG). No té signatura: la
+// ROM té una grandària múltiple de 1024 i la capçalera afegeix 512
+// bytes que, llevat dels primers camps, són zeros. Com que això també
+// ho complixen altres fitxers amb farciment de zeros, després de la
+// capçalera ha d'haver una ROM de SNES vàlida.
+func checkSNESCopier( data []byte, size int64 ) bool {
+
+  if size <= 512 || size%1024 != 512 || len(data) < 512 {
+    return false
+  }
+  for _,b:= range data[16:512] {
+    if b != 0 { return false }
+  }
+
+  return checkSNESHeader ( data, 512+0x7FC0 ) ||
+    checkSNESHeader ( data, 512+0xFFC0 )
+
+} // end checkSNESCopier
+
+
+var _RULES= []_Rule{
+  {
+    name   : "NES",
+    offset : 0,
+    magic  : []byte("NES\x1a"),
+    size   : 16,
+  },
+  {
+    name   : "FDS",
+    offset : 0,
+    magic  : []byte("FDS\x1a"),
+    size   : 16,
+  },
+  {
+    name   : "A78",
+    offset : 1,
+    magic  : []byte("ATARI7800"),
+    size   : 128,
+  },
+  {
+    name   : "LNX",
+    offset : 0,
+    magic  : []byte("LYNX"),
+    size   : 64,
+  },
+  {
+    name   : "SMC",
+    size   : 512,
+    check  : checkSNESCopier,
+  },
+}
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Nombre de bytes de l'inici del fitxer necessaris per a la detecció
+// (fins a la capçalera interna HiROM d'una ROM de SNES amb capçalera
+// de copiadora).
+const DETECT_SIZE= 512+0x10000
+
+
+// Torna el nombre de bytes de capçalera que cal botar per a obtindre
+// el contingut real i el nom del format de la capçalera. 'data' són
+// els primers DETECT_SIZE bytes del fitxer (o menys si és més menut) i
+// 'size' la grandària total. Si no es detecta cap capçalera torna 0.
+func Detect( data []byte, size int64 ) (int64,string) {
+
+  for i:= range _RULES {
+    rule:= &_RULES[i]
+    if size <= rule.size { continue }
+    if rule.check != nil {
+      if rule.check ( data, size ) {
+        return rule.size,rule.name
+      }
+    } else if end:= rule.offset+len(rule.magic); end <= len(data) &&
+      bytes.Equal ( data[rule.offset:end], rule.magic ) {
+      return rule.size,rule.name
+    }
+  }
+
+  return 0,""
+
+} // end Detect
@@ -0,0 +1,308 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  import.go - Còpia dels fitxers que s'afegeixen a la biblioteca en
+ *              el directori de dades: enllaç dur, reflink, còpia o
+ *              moure.
+ */
+
+package model
+
+import (
+  "crypto/md5"
+  "crypto/sha1"
+  "errors"
+  "fmt"
+  "io"
+  "log"
+  "os"
+
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+var _IMPORT_NAMES= []string{"hardlink","reflink","copy","move"}
+
+
+// Escriptor que actualitza la barra de progrés entre 'start' i 'end'.
+type _ProgressWriter struct {
+  pb    view.ProgressBar
+  msg   string
+  size  int64
+  n     int64
+  start float32
+  end   float32
+}
+
+
+func (self *_ProgressWriter) Write( p []byte ) (int,error) {
+
+  self.n+= int64(len(p))
+  if self.size > 0 {
+    self.pb.Set ( self.msg, self.start+
+      (self.end-self.start)*float32(self.n)/float32(self.size) )
+  }
+
+  return len(p),nil
+  
+} // end Write
+
+
+// Comprova que 'fn' té els hashes calculats en importar el fitxer.
+func checkImportedFile(
+
+  fn          string,
+  compression string,
+  hashes      *_Hashes,
+  pb          *_ProgressWriter,
+
+) error {
+
+  fd,err:= openStoreFile ( fn, compression )
+  if err != nil { return err }
+  defer fd.Close ()
+  h_md5,h_sha1:= md5.New (),sha1.New ()
+  if _,err:= io.Copy ( io.MultiWriter ( h_md5, h_sha1, pb ), fd );
+  err != nil {
+    return err
+  }
+  if fmt.Sprintf ( "%x", h_md5.Sum ( nil ) ) != hashes.md5 ||
+    fmt.Sprintf ( "%x", h_sha1.Sum ( nil ) ) != hashes.sha1 {
+    return errors.New ( "la còpia no coincideix amb l'original" )
+  }
+
+  return nil
+  
+} // end checkImportedFile
+
+
+// Crea 'dst' amb el contingut de 'src', clonant-lo (reflink) o
+// copiant-lo (comprimit si 'compression' no és buit). La còpia es
+// comprova amb els hashes.
+func copyImportedFile(
+
+  src         string,
+  dst         string,
+  clone       bool,
+  compression string,
+  size        int64,
+  hashes      *_Hashes,
+  pb          view.ProgressBar,
+
+) (err error) {
+
+  // Obri
+  fsrc,err:= os.Open ( src )
+  if err != nil { return err }
+  defer fsrc.Close ()
+  fdst,err:= os.OpenFile ( dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600 )
+  if err != nil { return err }
+  defer func() {
+    if err != nil { os.Remove ( dst ) }
+  }()
+
+  // Copia
+  if clone {
+    err= reflinkFile ( fdst, fsrc )
+  } else {
+    var w io.WriteCloser= fdst
+    if compression != "" {
+      var codec *_Codec
+      if codec,err= getCodec ( compression ); err == nil {
+        w,err= codec.new_writer ( fdst )
+      }
+      if err != nil { fdst.Close (); return err }
+    }
+    _,err= io.Copy ( w, io.TeeReader ( fsrc, &_ProgressWriter{
+      pb    : pb,
+      msg   : "Copia el fitxer...",
+      size  : size,
+      start : 0.7,
+      end   : 0.8,
+    }))
+    if w != fdst {
+      if err2:= w.Close (); err == nil { err= err2 }
+    }
+    if err == nil {
+      err= fdst.Sync ()
+    }
+  }
+  if err2:= fdst.Close (); err == nil { err= err2 }
+  if err != nil { return err }
+
+  // Comprova
+  if err= checkImportedFile ( dst, compression, hashes, &_ProgressWriter{
+    pb    : pb,
+    msg   : "Comprova la còpia...",
+    size  : size,
+    start : 0.8,
+    end   : 0.9,
+  }); err != nil {
+    return err
+  }
+
+  return os.Chmod ( dst, 0400 )
+  
+} // end copyImportedFile
+
+
+// Desa 'src' en 'dst' (dins del directori de dades) segons 'mode'. Si
+// no es pot fer un enllaç dur (p.e. perquè estan en sistemes de
+// fitxers diferents) es prova amb reflink i, si tampoc es pot, es
+// copia. Amb IMPORT_MOVE l'original s'ha d'eliminar després. Si
+// s'ha de comprimir sempre es copia.
+func importFile(
+
+  src         string,
+  dst         string,
+  mode        int,
+  compression string,
+  size        int64,
+  hashes      *_Hashes,
+  pb          view.ProgressBar,
+
+) error {
+
+  // Comprimit
+  if compression != "" {
+    if err:= copyImportedFile ( src, dst, false, compression, size, hashes,
+      pb ); err != nil {
+      return fmt.Errorf ( "No s'ha pogut comprimir '%s': %s", src, err )
+    }
+    return nil
+  }
+
+  // Enllaç dur
+  if mode == IMPORT_HARDLINK || mode == IMPORT_MOVE {
+    err:= linkFile ( src, dst )
+    if err == nil { return nil }
+    log.Printf ( "%s. Es copia el fitxer", err )
+  }
+
+  // Reflink
+  if mode != IMPORT_COPY {
+    err:= copyImportedFile ( src, dst, true, "", size, hashes, pb )
+    if err == nil { return nil }
+    if mode == IMPORT_REFLINK {
+      log.Printf ( "No s'ha pogut clonar '%s' (%s). Es copia el fitxer",
+        src, err )
+    }
+  }
+
+  // Còpia
+  if err:= copyImportedFile ( src, dst, false, "", size, hashes, pb );
+  err != nil {
+    return fmt.Errorf ( "No s'ha pogut copiar '%s': %s", src, err )
+  }
+
+  return nil
+  
+} // end importFile
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Maneres de desar en el directori de dades els fitxers que
+// s'afegeixen.
+const (
+  IMPORT_HARDLINK = iota // Enllaç dur a l'original
+  IMPORT_REFLINK         // Còpia que comparteix blocs (btrfs, xfs)
+  IMPORT_COPY
+  IMPORT_MOVE            // Enllaç o còpia i s'elimina l'original
+)
+
+
+func ImportModeToText( mode int ) string {
+
+  switch mode {
+  case IMPORT_HARDLINK:
+    return "Enllaç dur"
+  case IMPORT_REFLINK:
+    return "Còpia reflink"
+  case IMPORT_COPY:
+    return "Còpia"
+  case IMPORT_MOVE:
+    return "Mou"
+  default:
+    return "Desconegut"
+  }
+  
+} // end ImportModeToText
+
+
+// Nom de la manera d'importar en la configuració i la línia de
+// comandaments.
+func GetImportModeName( mode int ) string { return _IMPORT_NAMES[mode] }
+
+
+func ParseImportMode( name string ) (int,error) {
+
+  for mode,n:= range _IMPORT_NAMES {
+    if n == name { return mode,nil }
+  }
+
+  return -1,fmt.Errorf ( "Manera d'importar desconeguda: '%s'", name )
+  
+} // end ParseImportMode
+
+
+func (self *Model) GetImportMode() int {
+
+  ret,err:= self.db.GetImportMode ()
+  if err != nil {
+    log.Printf ( "No s'ha pogut llegir la manera d'importar: %s", err )
+    return IMPORT_HARDLINK
+  }
+
+  return ret
+  
+} // end GetImportMode
+
+
+func (self *Model) GetImportModes() []string {
+
+  ret:= make([]string,len(_IMPORT_NAMES))
+  for mode:= range ret {
+    ret[mode]= ImportModeToText ( mode )
+  }
+
+  return ret
+  
+} // end GetImportModes
+
+
+func (self *Model) SetImportMode( mode int ) error {
+
+  if mode < 0 || mode >= len(_IMPORT_NAMES) {
+    return fmt.Errorf ( "Manera d'importar desconeguda: %d", mode )
+  }
+
+  return self.db.SetImportMode ( mode )
+  
+} // end SetImportMode
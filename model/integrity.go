@@ -0,0 +1,252 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  integrity.go - Torna a calcular els hashes dels fitxers per a
+ *                 detectar fitxers modificats o corruptes.
+ */
+
+package model
+
+import (
+  "crypto/md5"
+  "crypto/sha1"
+  "errors"
+  "fmt"
+  "io"
+  "io/fs"
+  "time"
+
+  "github.com/adriagipas/imgteka/model/file_type"
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Fitxer a comprovar.
+type _IntegrityFile struct {
+  id          int64
+  name        string
+  file_type   int
+  size        int64
+  md5         string
+  sha1        string
+  last_check  int64
+  compression string
+}
+
+
+func (self *Files) checkFileIntegrity( f *_IntegrityFile ) (int,error) {
+
+  // Obri
+  ft,err:= file_type.Get ( f.file_type )
+  if err != nil { return INTEGRITY_ERROR,err }
+  path,err:= self.dirs.GetFileNameStore ( ft.GetShortName (), f.name,
+    f.sha1, f.compression )
+  if err != nil { return INTEGRITY_ERROR,err }
+  fd,err:= openStoreFile ( path, f.compression )
+  if errors.Is ( err, fs.ErrNotExist ) {
+    return INTEGRITY_MISSING,nil
+  } else if err != nil && f.compression != "" {
+    return INTEGRITY_CORRUPT,err // Capçalera de la compressió
+  } else if err != nil {
+    return INTEGRITY_ERROR,err
+  }
+  defer fd.Close ()
+
+  // Calcula hashes. Si no es pot descomprimir està corrupte.
+  h_md5,h_sha1:= md5.New (),sha1.New ()
+  n,err:= io.Copy ( io.MultiWriter ( h_md5, h_sha1 ), fd )
+  if err != nil && f.compression != "" {
+    return INTEGRITY_CORRUPT,err
+  } else if err != nil {
+    return INTEGRITY_ERROR,err
+  }
+
+  // Compara. Si la grandària és la mateixa però el contingut no,
+  // segurament el fitxer s'ha corromput.
+  if fmt.Sprintf ( "%x", h_md5.Sum ( nil ) ) == f.md5 &&
+    fmt.Sprintf ( "%x", h_sha1.Sum ( nil ) ) == f.sha1 {
+    return INTEGRITY_OK,nil
+  } else if n == f.size {
+    return INTEGRITY_CORRUPT,nil
+  } else {
+    return INTEGRITY_MODIFIED,nil
+  }
+
+} // end checkFileIntegrity
+
+
+// Comprova els fitxers indicats. Als que estan bé se'ls actualitza la
+// data de l'última comprovació.
+func (self *Files) checkIntegrity(
+
+  ifiles    []_IntegrityFile,
+  create_pb func() view.ProgressBar,
+
+) (*IntegrityReport,error) {
+
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  ret:= IntegrityReport{
+    results : make([]IntegrityResult,0,len(ifiles)),
+  }
+  for i:= range ifiles {
+    f:= &ifiles[i]
+    pb.Set ( f.name, float32(i)/float32(len(ifiles)) )
+    r:= IntegrityResult{
+      id         : f.id,
+      name       : f.name,
+      last_check : f.last_check,
+    }
+    r.status,r.err= self.checkFileIntegrity ( f )
+    if r.status == INTEGRITY_OK {
+      now:= time.Now ().Unix ()
+      if err:= self.db.UpdateFileLastCheck ( f.id, now ); err != nil {
+        return nil,err
+      }
+      if file,ok:= self.v[f.id]; ok {
+        file.last_check= now
+      }
+      ret.num_ok++
+    }
+    ret.results= append(ret.results,r)
+  }
+
+  return &ret,nil
+
+} // end checkIntegrity
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+const (
+  INTEGRITY_OK       = iota
+  INTEGRITY_MODIFIED        // Grandària diferent
+  INTEGRITY_CORRUPT         // Mateixa grandària però contingut diferent
+  INTEGRITY_MISSING
+  INTEGRITY_ERROR           // No s'ha pogut llegir
+)
+
+
+func IntegrityStatusToText( status int ) string {
+
+  switch status {
+  case INTEGRITY_OK:
+    return "Correcte"
+  case INTEGRITY_MODIFIED:
+    return "Modificat"
+  case INTEGRITY_CORRUPT:
+    return "Corrupte"
+  case INTEGRITY_MISSING:
+    return "No trobat"
+  default:
+    return "Error"
+  }
+
+} // end IntegrityStatusToText
+
+
+type IntegrityResult struct {
+  id         int64
+  name       string
+  status     int
+  err        error
+  last_check int64 // Abans de la comprovació
+}
+
+func (self *IntegrityResult) GetID() int64 { return self.id }
+func (self *IntegrityResult) GetName() string { return self.name }
+func (self *IntegrityResult) GetStatus() int { return self.status }
+func (self *IntegrityResult) GetError() error { return self.err }
+func (self *IntegrityResult) GetLastCheck() int64 { return self.last_check }
+
+
+type IntegrityReport struct {
+  results []IntegrityResult
+  num_ok  int
+}
+
+func (self *IntegrityReport) GetNumFiles() int { return len(self.results) }
+func (self *IntegrityReport) GetNumOK() int { return self.num_ok }
+func (self *IntegrityReport) GetResult( i int ) *IntegrityResult {
+  return &self.results[i]
+} // end GetResult
+
+
+// Descripció dels fitxers que no estan bé.
+func (self *IntegrityReport) GetFailed() []string {
+
+  ret:= make([]string,0,len(self.results)-self.num_ok)
+  for _,r:= range self.results {
+    if r.status == INTEGRITY_OK { continue }
+    text:= fmt.Sprintf ( "%s: %s", r.name,
+      IntegrityStatusToText ( r.status ) )
+    if r.err != nil {
+      text+= fmt.Sprintf ( " (%s)", r.err )
+    }
+    ret= append(ret,text)
+  }
+
+  return ret
+
+} // end GetFailed
+
+
+// Comprova els fitxers que fa més de 'max_age' que no es comproven.
+func (self *Model) CheckIntegrity(
+
+  max_age   time.Duration,
+  create_pb func() view.ProgressBar,
+
+) (*IntegrityReport,error) {
+
+  ifiles,err:= self.db.GetFilesCheckedBefore (
+    time.Now ().Add ( -max_age ).Unix () )
+  if err != nil { return nil,err }
+
+  return self.files.checkIntegrity ( ifiles, create_pb )
+
+} // end CheckIntegrity
+
+
+func (self *Model) CheckPlatformIntegrity(
+
+  id        int,
+  create_pb func() view.ProgressBar,
+
+) (view.IntegrityReport,error) {
+
+  ifiles,err:= self.db.GetPlatformIntegrityFiles ( id )
+  if err != nil { return nil,err }
+  ret,err:= self.files.checkIntegrity ( ifiles, create_pb )
+  if err != nil { return nil,err }
+
+  return ret,nil
+
+} // end CheckPlatformIntegrity
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  library.go - Selecció de la biblioteca a obrir: la per defecte, un
+ *               perfil amb nom o un directori arrel.
+ */
+
+package model
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io/fs"
+  "os"
+  "path"
+  "path/filepath"
+  "regexp"
+  "sort"
+
+  "github.com/adrg/xdg"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Variables d'entorn.
+const _ROOT_ENV= "IMGTEKA_ROOT"
+const _PROFILE_ENV= "IMGTEKA_PROFILE"
+
+// Fitxer de configuració (relatiu a XDG_CONFIG_HOME).
+const _CONFIG_NAME= "config.json"
+
+var _PROFILE_RE= regexp.MustCompile ( `^[A-Za-z0-9][A-Za-z0-9_.-]*$` )
+
+
+type _Config struct {
+  Root     string            `json:"root"`
+  Profile  string            `json:"profile"`
+  Profiles map[string]string `json:"profiles"` // Perfils amb arrel pròpia
+  dir      string            // Directori del fitxer
+}
+
+
+// Llig el fitxer de configuració. Si no existeix torna una
+// configuració buida.
+func readConfig() (*_Config,error) {
+
+  ret:= _Config{}
+  fn,err:= xdg.SearchConfigFile ( path.Join ( _ROOT_NAME, _CONFIG_NAME ) )
+  if err != nil { return &ret,nil } // No existeix
+  data,err:= os.ReadFile ( fn )
+  if err != nil { return nil,err }
+  if err:= json.Unmarshal ( data, &ret ); err != nil {
+    return nil,fmt.Errorf ( "No s'ha pogut llegir '%s': %s", fn, err )
+  }
+  ret.dir= filepath.Dir ( fn )
+
+  return &ret,nil
+
+} // end readConfig
+
+
+// Un camí relatiu del fitxer de configuració es resol respecte al
+// directori del fitxer.
+func (self *_Config) getPath( p string ) string {
+
+  if p == "" || filepath.IsAbs ( p ) || self.dir == "" { return p }
+
+  return filepath.Join ( self.dir, p )
+
+} // end getPath
+
+
+// Comprova el directori arrel i el crea si no existeix.
+func checkRoot( root string ) (string,error) {
+
+  root,err:= filepath.Abs ( root )
+  if err != nil { return "",err }
+  info,err:= os.Stat ( root )
+  if errors.Is ( err, fs.ErrNotExist ) {
+    if err:= os.MkdirAll ( root, 0755 ); err != nil { return "",err }
+  } else if err != nil {
+    return "",err
+  } else if !info.IsDir () {
+    return "",fmt.Errorf ( "El directori arrel de la biblioteca '%s' no"+
+      " és un directori", root )
+  }
+
+  return root,nil
+
+} // end checkRoot
+
+
+func newRootLibrary( root string ) (*Library,error) {
+
+  root,err:= checkRoot ( root )
+  if err != nil { return nil,err }
+
+  return &Library{root : root},nil
+
+} // end newRootLibrary
+
+
+func newProfileLibrary( name string, conf *_Config ) (*Library,error) {
+
+  if name == DEFAULT_PROFILE { return &Library{},nil }
+  if !_PROFILE_RE.MatchString ( name ) {
+    return nil,fmt.Errorf ( "Nom de perfil no vàlid: '%s'", name )
+  }
+  ret:= Library{profile : name}
+  if root,ok:= conf.Profiles[name]; ok {
+    root,err:= checkRoot ( conf.getPath ( root ) )
+    if err != nil { return nil,err }
+    ret.root= root
+  }
+
+  return &ret,nil
+
+} // end newProfileLibrary
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Nom del perfil de la biblioteca per defecte.
+const DEFAULT_PROFILE= "default"
+
+
+// Biblioteca que s'obri. La per defecte està en els directoris XDG,
+// els perfils en un subdirectori i les biblioteques amb directori
+// arrel (indicat directament o en la configuració del perfil) ho
+// tenen tot dins de l'arrel.
+type Library struct {
+  root    string
+  profile string
+}
+
+
+// Tria la biblioteca. Per ordre de prioritat: 'root' o 'profile' (p.e.
+// de les opcions de la línia de comandaments), les variables
+// d'entorn IMGTEKA_ROOT o IMGTEKA_PROFILE o les claus "root" o
+// "profile" del fitxer de configuració imgteka/config.json. En la
+// clau "profiles" de la configuració es pot assignar un directori
+// arrel a un perfil.
+func ResolveLibrary( root string, profile string ) (*Library,error) {
+
+  conf,err:= readConfig ()
+  if err != nil { return nil,err }
+
+  // Opcions
+  if root != "" && profile != "" {
+    return nil,errors.New ( "No es pot indicar al mateix temps un"+
+      " directori arrel i un perfil" )
+  } else if root != "" {
+    return newRootLibrary ( root )
+  } else if profile != "" {
+    return newProfileLibrary ( profile, conf )
+  }
+
+  // Entorn
+  if root:= os.Getenv ( _ROOT_ENV ); root != "" {
+    return newRootLibrary ( root )
+  } else if profile:= os.Getenv ( _PROFILE_ENV ); profile != "" {
+    return newProfileLibrary ( profile, conf )
+  }
+
+  // Configuració
+  if conf.Root != "" {
+    return newRootLibrary ( conf.getPath ( conf.Root ) )
+  } else if conf.Profile != "" {
+    return newProfileLibrary ( conf.Profile, conf )
+  }
+
+  return &Library{},nil
+
+} // end ResolveLibrary
+
+
+// Torna tots els perfils coneguts: el per defecte, els del fitxer de
+// configuració i els que ja tenen directori.
+func GetProfiles() ([]string,error) {
+
+  conf,err:= readConfig ()
+  if err != nil { return nil,err }
+  names:= make(map[string]bool)
+  for name:= range conf.Profiles {
+    names[name]= true
+  }
+  dir:= filepath.Join ( xdg.DataHome, _ROOT_NAME, _ROOT_PROFILES )
+  if ents,err:= os.ReadDir ( dir ); err == nil {
+    for _,e:= range ents {
+      if e.IsDir () && _PROFILE_RE.MatchString ( e.Name () ) {
+        names[e.Name ()]= true
+      }
+    }
+  } else if !errors.Is ( err, fs.ErrNotExist ) {
+    return nil,err
+  }
+  delete(names,DEFAULT_PROFILE)
+  ret:= make([]string,0,len(names)+1)
+  for name:= range names {
+    ret= append(ret,name)
+  }
+  sort.Strings ( ret )
+
+  return append([]string{DEFAULT_PROFILE},ret...),nil
+
+} // end GetProfiles
+
+
+// Nom del perfil. Buit si s'ha obert indicant sols el directori
+// arrel.
+func (self *Library) GetProfile() string {
+
+  if self.profile == "" && self.root == "" { return DEFAULT_PROFILE }
+
+  return self.profile
+
+} // end GetProfile
+
+
+func (self *Library) GetRoot() string { return self.root }
+
+
+// Identificador únic de la biblioteca. Sols pot haver un procés
+// gràfic per biblioteca.
+func (self *Library) GetID() string {
+
+  if self.root != "" {
+    return "root:"+self.root
+  } else if self.profile != "" {
+    return "profile:"+self.profile
+  }
+
+  return ""
+
+} // end GetID
+
+
+// Nom per a mostrar.
+func (self *Library) GetName() string {
+
+  if self.profile != "" {
+    return self.profile
+  } else if self.root != "" {
+    return self.root
+  }
+
+  return DEFAULT_PROFILE
+
+} // end GetName
@@ -34,6 +34,7 @@ import (
 
 
 type Model struct {
+  lib     *Library
   dirs    *Dirs
   db      *Database
   plats   *Platforms
@@ -42,25 +43,33 @@ type Model struct {
   entries *Entries
   stats   *Stats
   cmds    *Commands
+  dats    *Dats
 }
 
 
-func New() (*Model,error) {
+// Obri la biblioteca indicada (vegeu ResolveLibrary).
+func New( lib *Library ) (*Model,error) {
 
   // Crea objectes
-  dirs:= NewDirs ()
+  dirs:= NewDirs ( lib )
   cmds,err:= NewCommands ( dirs )
   if err != nil { return nil,err }
   db,err:= NewDatabase ( dirs )
   if err != nil { return nil,err }
+  store,err:= db.GetStore ()
+  if err != nil { db.Close (); return nil,err }
+  dirs.SetStore ( store )
   plats:= NewPlatforms ( db )
   labels:= NewLabels ( db )
-  files:= NewFiles ( db, plats, dirs, cmds )
+  dats:= NewDats ( db )
+  files:= NewFiles ( db, plats, dirs, cmds, dats )
   entries:= NewEntries ( db, plats, labels, files, dirs )
+  cmds.setEntries ( entries )
   stats:= NewStats ( db )
   
   // Crea model
   ret:= Model{
+    lib     : lib,
     dirs    : dirs,
     db      : db,
     plats   : plats,
@@ -69,6 +78,7 @@ func New() (*Model,error) {
     entries : entries,
     stats   : stats,
     cmds    : cmds,
+    dats    : dats,
   }
   
   return &ret,nil
@@ -78,12 +88,41 @@ func New() (*Model,error) {
 
 func (self *Model) Close() {
   
+  self.files.StopBackfill ()
+  self.files.cleanUncompressed ()
+  self.cmds.Close () // Abans que la base de dades (vegeu prune)
   self.db.Close ()
-  self.cmds.Close ()
   
 } // end Close
 
 
+// Accés directe als gestors. Pensat per a la interfície de línia de
+// comandaments.
+func (self *Model) GetDats() *Dats { return self.dats }
+func (self *Model) GetEntries() *Entries { return self.entries }
+func (self *Model) GetFiles() *Files { return self.files }
+func (self *Model) GetLabels() *Labels { return self.labels }
+func (self *Model) GetPlatforms() *Platforms { return self.plats }
+
+
+func (self *Model) GetLibraryName() string {
+  return self.lib.GetName ()
+} // end GetLibraryName
+
+
+func (self *Model) GetProfiles() []string {
+
+  ret,err:= GetProfiles ()
+  if err != nil {
+    log.Printf ( "No s'han pogut llegir els perfils: %s", err )
+    return []string{DEFAULT_PROFILE}
+  }
+
+  return ret
+  
+} // end GetProfiles
+
+
 func (self *Model) RootEntries() []int64 {
   return self.entries.GetIDs ()
 } // end RootEntries
@@ -124,6 +163,40 @@ func (self *Model) GetStats() view.Stats {
 } // end GetStates
 
 
+func (self *Model) GetDatIDs() []int64 {
+  return self.dats.GetIDs ()
+} // end GetDatIDs
+
+
+func (self *Model) GetDat( id int64 ) view.Dat {
+  return self.dats.Get ( id )
+} // end GetDat
+
+
+func (self *Model) GetDatReport( platform_id int ) (view.DatReport,error) {
+
+  ret,err:= NewDatReport ( self.dats, self.files, self.plats, platform_id )
+  if err != nil { return nil,err }
+
+  return ret,nil
+  
+} // end GetDatReport
+
+
+func (self *Model) DetectFileType( file_name string ) ([]int,error) {
+
+  cands,err:= file_type.Detect ( file_name )
+  if err != nil { return nil,err }
+  ret:= make([]int,len(cands))
+  for i:= range cands {
+    ret[i]= cands[i].GetID ()
+  }
+
+  return ret,nil
+  
+} // end DetectFileType
+
+
 func (self *Model) GetFileTypeIDs() []int {
   return file_type.GetIDs ()
 } // end GetFileTypeIDs
@@ -140,14 +213,66 @@ func (self *Model) GetFileTypeName( id int ) string {
   
 } // end GetFileTypeName
 
-func (self *Model) GetFileTypeCommand( id int ) string {
-  return self.cmds.GetCommand ( id )
-} // end GetFileTypeCommand
+func (self *Model) GetFileTypeLaunchers( id int ) []view.Launcher {
+  return self.cmds.GetTypeLaunchers ( id )
+} // end GetFileTypeLaunchers
+
+
+func (self *Model) SetFileTypeLaunchers(
+  
+  id        int,
+  launchers []view.Launcher,
+  
+) error {
+  return self.cmds.SetTypeLaunchers ( id, launchers )
+} // end SetFileTypeLaunchers
+
+
+func (self *Model) GetPlatformLaunchers(
+  
+  platform_id int,
+  type_id     int,
+  
+) []view.Launcher {
+  return self.cmds.GetPlatformLaunchers ( platform_id, type_id )
+} // end GetPlatformLaunchers
+
+
+func (self *Model) SetPlatformLaunchers(
+  
+  platform_id int,
+  type_id     int,
+  launchers   []view.Launcher,
+  
+) error {
+  return self.cmds.SetPlatformLaunchers ( platform_id, type_id, launchers )
+} // end SetPlatformLaunchers
 
 
-func (self *Model) SetFileTypeCommand( id int, command string ) {
-  self.cmds.SetCommand ( id, command )
-} // end SetFileTypeCommand
+func (self *Model) GetEntryLaunchers(
+  
+  entry_id int64,
+  type_id  int,
+  
+) []view.Launcher {
+  return self.cmds.GetEntryLaunchers ( entry_id, type_id )
+} // end GetEntryLaunchers
+
+
+func (self *Model) SetEntryLaunchers(
+  
+  entry_id  int64,
+  type_id   int,
+  launchers []view.Launcher,
+  
+) error {
+  return self.cmds.SetEntryLaunchers ( entry_id, type_id, launchers )
+} // end SetEntryLaunchers
+
+
+func (self *Model) CheckFileTypeCommand( command string ) error {
+  return CheckCommand ( command )
+} // end CheckFileTypeCommand
 
 
 func (self *Model) AddPlatform(
@@ -169,8 +294,25 @@ func (self *Model) AddEntry( name string, platform_id int ) error {
 } // end AddEntry
 
 
+func (self *Model) ImportDat( file_name string ) error {
+  return self.dats.Import ( file_name )
+} // end ImportDat
+
+
+func (self *Model) RemoveDat( id int64 ) error {
+  return self.dats.Remove ( id )
+} // end RemoveDat
+
+
 func (self *Model) RemovePlatform( id int ) error {
-  return self.plats.Remove ( id )
+
+  if err:= self.plats.Remove ( id ); err != nil {
+    return err
+  }
+  self.dats.reset () // Pot haver DATs associats
+
+  return nil
+  
 } // end RemovePlatform
 
 
@@ -180,7 +322,12 @@ func (self *Model) RemoveLabel( id int ) error {
 
 
 func (self *Model) RemoveEntry( id int64 ) error {
-  return self.entries.Remove ( id )
+
+  if err:= self.entries.Remove ( id ); err != nil { return err }
+  self.cmds.removeEntry ( id )
+
+  return nil
+  
 } // end RemoveEntry
 
 
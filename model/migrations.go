@@ -0,0 +1,325 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  migrations.go - Versions de l'esquema de la base de dades. La
+ *                  versió es guarda en PRAGMA user_version i cada
+ *                  migració porta l'esquema d'una versió a la següent.
+ */
+
+package model
+
+import (
+  "context"
+  "database/sql"
+  "fmt"
+  "log"
+  "time"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+type _Migration struct {
+  desc string
+  run  func(tx *sql.Tx) error
+}
+
+
+// MOLT IMPORTANT!!! Sols es poden afegir migracions al final. La
+// versió de l'esquema és el nombre de migracions aplicades. Les
+// bases de dades creades abans de tindre versió (user_version 0) ja
+// poden tindre part de l'esquema, per això les migracions comproven
+// el que ja existeix.
+var _MIGRATIONS= []_Migration{
+  {"Esquema inicial",migrateInitialSchema},
+  {"CRC32 i SHA-256 dels fitxers",migrateFileHashes},
+  {"DATs de referència",migrateDats},
+  {"Hashes sense capçalera dels fitxers",migrateRealHashes},
+  {"Organització dels fitxers per SHA1",migrateFileStore},
+  {"Compressió dels fitxers",migrateFileCompression},
+}
+
+
+func addColumnIfNotExists (
+
+  tx     *sql.Tx,
+  table  string,
+  column string,
+  def    string,
+
+) error {
+
+  // Comprova si ja existeix
+  rows,err:= tx.Query ( "PRAGMA table_info("+table+");" )
+  if err != nil { return err }
+  defer rows.Close ()
+  for rows.Next () {
+    var cid,notnull,pk int
+    var name,ctype string
+    var dflt any
+    if err:= rows.Scan ( &cid, &name, &ctype, &notnull,
+      &dflt, &pk ); err != nil {
+      return err
+    }
+    if name == column { return nil }
+  }
+  if err:= rows.Err (); err != nil { return err }
+  rows.Close ()
+
+  // Afegeix
+  _,err= tx.Exec ( "ALTER TABLE "+table+" ADD COLUMN "+column+" "+def+";" )
+
+  return err
+
+} // end addColumnIfNotExists
+
+
+func execAll( tx *sql.Tx, stmts ...string ) error {
+
+  for _,stmt:= range stmts {
+    if _,err:= tx.Exec ( stmt ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end execAll
+
+
+func migrateInitialSchema( tx *sql.Tx ) error {
+  return execAll ( tx, _CREATE_PLATFORMS, _CREATE_ENTRIES, _CREATE_LABELS,
+    _CREATE_ENTRY_LABEL_PAIRS, _CREATE_FILES )
+} // end migrateInitialSchema
+
+
+func migrateFileHashes( tx *sql.Tx ) error {
+
+  if err:= addColumnIfNotExists ( tx, "FILES", "crc32",
+    "TEXT NOT NULL DEFAULT ''" ); err != nil {
+    return err
+  }
+
+  return addColumnIfNotExists ( tx, "FILES", "sha256",
+    "TEXT NOT NULL DEFAULT ''" )
+
+} // end migrateFileHashes
+
+
+func migrateDats( tx *sql.Tx ) error {
+
+  if err:= execAll ( tx, _CREATE_DATS, _CREATE_DAT_GAMES,
+    _CREATE_DAT_ROMS ); err != nil {
+    return err
+  }
+
+  // Columnes que no tenien les primeres versions de les taules
+  for _,c:= range [][3]string{
+    {"DATS","header","TEXT NOT NULL DEFAULT ''"},
+    {"DATS","platform_id","INTEGER NOT NULL DEFAULT -1"},
+    {"DAT_GAMES","clone_of","TEXT NOT NULL DEFAULT ''"},
+    {"DAT_GAMES","rom_of","TEXT NOT NULL DEFAULT ''"},
+    {"DAT_GAMES","resource","INTEGER NOT NULL DEFAULT 0"},
+    {"DAT_ROMS","merge","TEXT NOT NULL DEFAULT ''"},
+  } {
+    if err:= addColumnIfNotExists ( tx, c[0], c[1], c[2] ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end migrateDats
+
+
+func migrateRealHashes( tx *sql.Tx ) error {
+
+  for _,col:= range []string{"real_md5","real_sha1","real_crc32"} {
+    if err:= addColumnIfNotExists ( tx, "FILES", col,
+      "TEXT NOT NULL DEFAULT ''" ); err != nil {
+      return err
+    }
+  }
+
+  return execAll ( tx, `
+CREATE INDEX IF NOT EXISTS FILES_MD5 ON FILES (md5);
+CREATE INDEX IF NOT EXISTS FILES_SHA1 ON FILES (sha1);
+CREATE INDEX IF NOT EXISTS FILES_CRC32 ON FILES (crc32);
+CREATE INDEX IF NOT EXISTS FILES_REAL_MD5 ON FILES (real_md5);
+CREATE INDEX IF NOT EXISTS FILES_REAL_SHA1 ON FILES (real_sha1);
+CREATE INDEX IF NOT EXISTS FILES_REAL_CRC32 ON FILES (real_crc32);
+` )
+
+} // end migrateRealHashes
+
+
+// Torna a crear FILES sense UNIQUE (type,name). SQLite no permet
+// eliminar una restricció, per això es copia la taula. Requereix les
+// claus foranes desactivades (vore applyMigration).
+func migrateFileStore( tx *sql.Tx ) error {
+
+  return execAll ( tx, _CREATE_SETTINGS, _CREATE_FILES_STORE, `
+INSERT INTO FILES_STORE (id,name,entry_id,type,size,md5,sha1,extra_json,
+                         last_check,crc32,sha256,real_md5,real_sha1,
+                         real_crc32)
+SELECT id,name,entry_id,type,size,md5,sha1,extra_json,last_check,crc32,
+       sha256,real_md5,real_sha1,real_crc32
+FROM FILES;
+`, "DROP TABLE FILES;", "ALTER TABLE FILES_STORE RENAME TO FILES;", `
+CREATE UNIQUE INDEX FILES_TYPE_NAME ON FILES (type,name);
+CREATE INDEX FILES_MD5 ON FILES (md5);
+CREATE INDEX FILES_SHA1 ON FILES (sha1);
+CREATE INDEX FILES_CRC32 ON FILES (crc32);
+CREATE INDEX FILES_REAL_MD5 ON FILES (real_md5);
+CREATE INDEX FILES_REAL_SHA1 ON FILES (real_sha1);
+CREATE INDEX FILES_REAL_CRC32 ON FILES (real_crc32);
+` )
+
+} // end migrateFileStore
+
+
+func migrateFileCompression( tx *sql.Tx ) error {
+  return addColumnIfNotExists ( tx, "FILES", "compression",
+    "TEXT NOT NULL DEFAULT ''" )
+} // end migrateFileCompression
+
+
+func getSchemaVersion( db *sql.DB ) (int,error) {
+
+  var ret int
+  err:= db.QueryRow ( "PRAGMA user_version;" ).Scan ( &ret )
+
+  return ret,err
+
+} // end getSchemaVersion
+
+
+// Indica si la base de dades acaba de crear-se (no té cap taula).
+func isEmptyDatabase( db *sql.DB ) (bool,error) {
+
+  var n int
+  err:= db.QueryRow ( "SELECT COUNT(*) FROM sqlite_master;" ).Scan ( &n )
+
+  return n == 0,err
+
+} // end isEmptyDatabase
+
+
+// Fa una còpia consistent de la base de dades al costat de
+// l'original.
+func backupDatabase( db *sql.DB, db_fn string, version int ) error {
+
+  bak_fn:= fmt.Sprintf ( "%s.v%d-%s.bak", db_fn, version,
+    time.Now ().Format ( "20060102-150405" ) )
+  if _,err:= db.Exec ( "VACUUM INTO ?;", bak_fn ); err != nil {
+    return fmt.Errorf ( "No s'ha pogut fer una còpia de seguretat de la"+
+      " base de dades en '%s': %s", bak_fn, err )
+  }
+  log.Printf ( "Còpia de seguretat de la base de dades (versió %d): %s",
+    version, bak_fn )
+
+  return nil
+
+} // end backupDatabase
+
+
+func applyMigration( db *sql.DB, version int ) error {
+
+  // Algunes migracions tornen a crear taules (DROP TABLE), cosa que
+  // amb les claus foranes actives esborraria o trencaria les files
+  // que hi fan referència. PRAGMA foreign_keys no té efecte dins
+  // d'una transacció, per això es desactiva abans de començar-la i en
+  // la mateixa connexió.
+  ctx:= context.Background ()
+  conn,err:= db.Conn ( ctx )
+  if err != nil { return err }
+  defer conn.Close ()
+  var fkeys int
+  if err:= conn.QueryRowContext ( ctx,
+    "PRAGMA foreign_keys;" ).Scan ( &fkeys ); err != nil {
+    return err
+  }
+  if _,err:= conn.ExecContext ( ctx, "PRAGMA foreign_keys = OFF;" );
+  err != nil {
+    return err
+  }
+  defer conn.ExecContext ( ctx,
+    fmt.Sprintf ( "PRAGMA foreign_keys = %d;", fkeys ) )
+  
+  m:= &_MIGRATIONS[version]
+  tx,err:= conn.BeginTx ( ctx, nil )
+  if err != nil { return err }
+  if err:= m.run ( tx ); err != nil {
+    tx.Rollback ()
+    return fmt.Errorf ( "No s'ha pogut actualitzar la base de dades a la"+
+      " versió %d (%s): %s", version+1, m.desc, err )
+  }
+
+  // PRAGMA no admet paràmetres
+  if _,err:= tx.Exec ( fmt.Sprintf ( "PRAGMA user_version = %d;",
+    version+1 ) ); err != nil {
+    tx.Rollback ()
+    return err
+  }
+
+  return tx.Commit ()
+
+} // end applyMigration
+
+
+// Porta la base de dades a l'última versió de l'esquema. Abans
+// d'actualitzar una base de dades existent en fa una còpia de
+// seguretat. Una base de dades d'una versió més nova no s'obri.
+func migrateDatabase( db *sql.DB, db_fn string ) error {
+
+  // Comprova versió
+  version,err:= getSchemaVersion ( db )
+  if err != nil { return err }
+  last:= len(_MIGRATIONS)
+  if version > last {
+    return fmt.Errorf ( "La base de dades '%s' és d'una versió més nova"+
+      " (%d) que la suportada per aquesta versió del programa (%d)",
+      db_fn, version, last )
+  } else if version == last {
+    return nil
+  }
+
+  // Còpia de seguretat
+  empty,err:= isEmptyDatabase ( db )
+  if err != nil { return err }
+  if !empty {
+    if err:= backupDatabase ( db, db_fn, version ); err != nil {
+      return err
+    }
+  }
+
+  // Actualitza
+  for ; version < last; version++ {
+    if err:= applyMigration ( db, version ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+
+} // end migrateDatabase
@@ -40,9 +40,16 @@ const (
   _LABEL_WAIT_VAL = 2
   _PLATF_WAIT_SEP = 3
   _PLATF_WAIT_VAL = 4
+  _HASH_WAIT_SEP  = 5
+  _HASH_WAIT_VAL  = 6
 )
 
 
+func isHashChar( c rune ) bool {
+  return (c>='0' && c<='9') || (c>='a' && c<='z') || (c>='A' && c<='Z')
+} // end isHashChar
+
+
 func addEntry( q *QueryOr, token string, typ int ) {
 
   // Elimina cometes
@@ -76,6 +83,7 @@ const (
   QUERY_TYPE_NAME_ENTRY = 0
   QUERY_TYPE_LABEL      = 1
   QUERY_TYPE_PLATFORM   = 2
+  QUERY_TYPE_HASH       = 3
 )
 
 
@@ -127,6 +135,8 @@ func NewQuery( query_text string ) *Query {
           state= _LABEL_WAIT_SEP
         } else if val == "p" {
           state= _PLATF_WAIT_SEP
+        } else if val == "h" {
+          state= _HASH_WAIT_SEP
         } else {
           addEntry( current_oq, val, QUERY_TYPE_NAME_ENTRY )
         }
@@ -154,6 +164,24 @@ func NewQuery( query_text string ) *Query {
       case _PLATF_WAIT_VAL: // PLATF WAIT VAL
         addEntry( current_oq, val, QUERY_TYPE_PLATFORM )
         state= _WAIT_TOKEN
+
+      case _HASH_WAIT_SEP: // HASH WAIT SEP
+        if val == ":" {
+          state= _HASH_WAIT_VAL
+        } else {
+          addEntry( current_oq, "h", QUERY_TYPE_NAME_ENTRY )
+          state= _WAIT_TOKEN
+        }
+
+      case _HASH_WAIT_VAL: // HASH WAIT VAL
+        // L'escàner separa els hashes en diversos tokens (p.e. "3b2"
+        // són un enter i un identificador). Els ajunta.
+        for isHashChar ( s.Peek () ) {
+          s.Scan ()
+          val+= s.TokenText ()
+        }
+        addEntry( current_oq, val, QUERY_TYPE_HASH )
+        state= _WAIT_TOKEN
         
       }
       
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  reflink_linux.go - Clona fitxers amb FICLONE (btrfs, xfs, ...).
+ */
+
+package model
+
+import (
+  "os"
+  "syscall"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+const _FICLONE= 0x40049409
+
+
+func reflinkFile( dst *os.File, src *os.File ) error {
+
+  _,_,errno:= syscall.Syscall ( syscall.SYS_IOCTL, dst.Fd (), _FICLONE,
+    src.Fd () )
+  if errno != 0 { return errno }
+
+  return nil
+  
+} // end reflinkFile
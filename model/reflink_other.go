@@ -0,0 +1,41 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  reflink_other.go - Clonar fitxers sols està implementat en Linux.
+ */
+
+//go:build !linux
+
+package model
+
+import (
+  "errors"
+  "os"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func reflinkFile( dst *os.File, src *os.File ) error {
+  return errors.New ( "no està suportat en aquest sistema" )
+} // end reflinkFile
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  store.go - Canvia l'organització del directori 'files' de la
+ *             biblioteca (per tipus i nom) a l'organització per
+ *             SHA1 del contingut.
+ */
+
+package model
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "log"
+  "os"
+  "path/filepath"
+
+  "github.com/adriagipas/imgteka/view"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Fitxer a migrar. Els noms són absoluts.
+type _StoreFile struct {
+  old_name string
+  new_name string
+  link     string // Enllaç de l'entrada
+}
+
+
+// Crea el fitxer nou de 'files' com a enllaç a l'antic, després de
+// comprovar que el contingut és el registrat. Torna cert si ja
+// existia perquè un altre fitxer té el mateix contingut.
+func createStoreFile(
+
+  sf      *_StoreFile,
+  f       *_BackupFile,
+  created map[string]bool,
+
+) (bool,error) {
+
+  fd,err:= os.Open ( sf.old_name )
+  if err != nil { return false,err }
+  err= copyAndCheck ( io.Discard, fd, f )
+  fd.Close ()
+  if err != nil { return false,err }
+  if created[sf.new_name] { return true,nil }
+  if _,err:= os.Lstat ( sf.new_name ); err == nil {
+    return false,fmt.Errorf ( "El fitxer '%s' ja existeix", sf.new_name )
+  }
+  if err:= linkFile ( sf.old_name, sf.new_name ); err != nil {
+    return false,err
+  }
+  created[sf.new_name]= true
+
+  return false,nil
+
+} // end createStoreFile
+
+
+// Substitueix l'enllaç de l'entrada per un enllaç al fitxer nou. Si
+// ja és el mateix fitxer (el cas normal, perquè el fitxer nou és un
+// enllaç a l'antic) no fa res.
+func relinkStoreFile( sf *_StoreFile ) error {
+
+  ninfo,err:= os.Stat ( sf.new_name )
+  if err != nil { return err }
+  if linfo,err:= os.Stat ( sf.link ); err == nil &&
+    os.SameFile ( ninfo, linfo ) {
+    return nil
+  }
+  tmp:= sf.link+".store"
+  if err:= linkFile ( sf.new_name, tmp ); err != nil { return err }
+  if err:= os.Rename ( tmp, sf.link ); err != nil {
+    os.Remove ( tmp )
+    return err
+  }
+
+  return nil
+
+} // end relinkStoreFile
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+// Passa a desar els fitxers pel SHA1 del contingut. Primer es crea
+// el fitxer nou de cada fitxer (comprovant el contingut) i, quan la
+// base de dades ja indica la nova organització, es tornen a crear
+// els enllaços de les entrades i s'eliminen els fitxers antics. Els
+// fitxers idèntics passen a compartir el mateix fitxer. Torna el
+// nombre de fitxers migrats i quants d'ells eren còpies d'un altre.
+func (self *Model) MigrateStore(
+
+  create_pb func() view.ProgressBar,
+
+) (int,int,error) {
+
+  // Prepara
+  if self.dirs.GetStore () == STORE_SHA1 {
+    return 0,0,errors.New ( "Els fitxers de la biblioteca ja es desen"+
+      " per SHA1" )
+  }
+  if self.files.StopBackfill () {
+    defer self.files.StartBackfill ()
+  }
+  files,err:= loadBackupFiles ( self.db.conn )
+  if err != nil { return 0,0,err }
+  if err:= setBackupPaths ( files, STORE_NAME ); err != nil {
+    return 0,0,err
+  }
+  sfiles:= make([]_StoreFile,len(files))
+  for i:= range files {
+    f,sf:= &files[i],&sfiles[i]
+    if sf.old_name,err= self.dirs.GetDataFileName ( f.Path ); err != nil {
+      return 0,0,err
+    }
+    if sf.new_name,err= self.dirs.GetDataFileName (
+      storeRelName ( STORE_SHA1, "", f.name, f.SHA1,
+        f.Compression ) ); err != nil {
+      return 0,0,err
+    }
+    if sf.link,err= self.dirs.GetDataFileName ( f.Links[0] ); err != nil {
+      return 0,0,err
+    }
+  }
+  pb:= create_pb ()
+  defer pb.Close ()
+
+  // Crea els fitxers nous. Si falla es desfà tot.
+  created:= make(map[string]bool)
+  undo:= func() {
+    for name:= range created {
+      os.Remove ( name )
+    }
+  }
+  ndups:= 0
+  for i:= range files {
+    pb.Set ( files[i].Path, 0.9*float32(i)/float32(len(files)) )
+    dup,err:= createStoreFile ( &sfiles[i], &files[i], created )
+    if err != nil {
+      undo ()
+      return 0,0,fmt.Errorf ( "No s'ha pogut migrar '%s': %s",
+        files[i].Path, err )
+    }
+    if dup { ndups++ }
+  }
+  if err:= self.db.SetStore ( STORE_SHA1 ); err != nil {
+    undo ()
+    return 0,0,err
+  }
+  self.dirs.SetStore ( STORE_SHA1 )
+  self.files.reset ()
+
+  // Enllaços de les entrades i fitxers antics. A partir d'ací els
+  // errors no es desfan, sols s'avisa (fsck els pot reparar).
+  nerrs:= 0
+  for i:= range sfiles {
+    sf:= &sfiles[i]
+    pb.Set ( files[i].Path, 0.9+0.1*float32(i)/float32(len(files)) )
+    if err:= relinkStoreFile ( sf ); err != nil {
+      log.Printf ( "No s'ha pogut tornar a crear l'enllaç '%s': %s",
+        sf.link, err )
+      nerrs++
+    }
+    if err:= os.Remove ( sf.old_name ); err != nil {
+      log.Printf ( "No s'ha pogut eliminar '%s': %s", sf.old_name, err )
+      nerrs++
+    }
+  }
+  froot,err:= self.dirs.GetDataFileName ( _ROOT_FILES )
+  if err != nil { return len(files),ndups,err }
+  if ents,err:= os.ReadDir ( froot ); err == nil {
+    for _,e:= range ents {
+      if e.IsDir () {
+        os.Remove ( filepath.Join ( froot, e.Name () ) ) // Sols si està buit
+      }
+    }
+  }
+  if nerrs > 0 {
+    return len(files),ndups,fmt.Errorf ( "La migració ha acabat amb %d"+
+      " errors, executeu fsck -repair", nerrs )
+  }
+
+  return len(files),ndups,nil
+
+} // end MigrateStore
@@ -23,9 +23,11 @@
 package view
 
 import (
-  
   "fyne.io/fyne/v2"
   "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/layout"
+  "fyne.io/fyne/v2/theme"
   "fyne.io/fyne/v2/widget"
 )
 
@@ -34,6 +36,130 @@ import (
 /* PART PRIVADA */
 /****************/
 
+const _LAUNCHERS_HELP= "Els arguments se separen com en la shell." +
+  " Variables: {file}, {dir}, {entry}, {platform}, {name} i" +
+  " {files_of_entry}. Sense variables el fitxer s'afegeix al final."
+
+
+// Fila de l'editor de llançadors.
+type _LauncherRow struct {
+  name    *widget.Entry
+  command *widget.Entry
+}
+
+
+// Editor de la llista de llançadors de cada tipus de fitxer. 'get' i
+// 'set' accedeixen als llançadors de l'àmbit que s'està editant.
+func newLaunchersEditor(
+
+  model    DataModel,
+  tids     []int,
+  get      func(type_id int) []Launcher,
+  set      func(type_id int,launchers []Launcher) error,
+  hint     string,
+  main_win fyne.Window,
+  
+) fyne.CanvasObject {
+
+  // Files
+  var rows []*_LauncherRow
+  rows_box:= container.NewVBox ()
+  var add_row func(l Launcher)
+  add_row= func(l Launcher) {
+    row:= &_LauncherRow{
+      name    : widget.NewEntry (),
+      command : widget.NewEntry (),
+    }
+    row.name.SetText ( l.Name )
+    row.name.SetPlaceHolder ( "Nom" )
+    row.command.SetText ( l.Command )
+    row.command.SetPlaceHolder ( "programa [arguments] {file}" )
+    row.command.Validator= model.CheckFileTypeCommand
+    var box *fyne.Container
+    but_del:= widget.NewButtonWithIcon ( "", theme.DeleteIcon (), func(){
+      for i,r:= range rows {
+        if r == row {
+          rows= append(rows[:i],rows[i+1:]...)
+          break
+        }
+      }
+      rows_box.Remove ( box )
+    })
+    name_size:= fyne.NewSize ( 150, row.name.MinSize ().Height )
+    box= container.NewBorder ( nil, nil,
+      container.NewGridWrap ( name_size, row.name ), but_del, row.command )
+    rows= append(rows,row)
+    rows_box.Add ( box )
+  }
+
+  // Tipus
+  names:= make([]string,len(tids))
+  for i,tid:= range tids {
+    names[i]= model.GetFileTypeName ( tid )
+  }
+  type_sel:= widget.NewSelect ( names, func(string){} )
+  load:= func() {
+    rows= rows[:0]
+    rows_box.RemoveAll ()
+    if type_sel.SelectedIndex () < 0 { return }
+    for _,l:= range get ( tids[type_sel.SelectedIndex ()] ) {
+      add_row ( l )
+    }
+  }
+  type_sel.OnChanged= func(string) { load () }
+  
+  // Botonera
+  but_add:= widget.NewButtonWithIcon ( "Afegeix", theme.ContentAddIcon (),
+    func(){
+      if type_sel.SelectedIndex () >= 0 {
+        add_row ( Launcher{} )
+      }
+    })
+  but_ok:= widget.NewButton ( "Aplica", func() {
+    if type_sel.SelectedIndex () < 0 { return }
+    ls:= make([]Launcher,0,len(rows))
+    for _,r:= range rows {
+      if r.name.Text == "" && r.command.Text == "" { continue }
+      ls= append(ls,Launcher{r.name.Text,r.command.Text})
+    }
+    if err:= set ( tids[type_sel.SelectedIndex ()], ls ); err != nil {
+      dialog.ShowError ( err, main_win )
+    } else {
+      load ()
+    }
+  })
+  but_box:= container.NewHBox ( but_add, layout.NewSpacer (), but_ok )
+
+  // Crea contingut
+  top:= container.NewVBox (
+    container.NewBorder ( nil, nil, widget.NewLabel ( "Tipus:" ), nil,
+      type_sel ),
+  )
+  if hint != "" {
+    top.Add ( widget.NewLabel ( hint ) )
+  }
+  if len(tids) > 0 {
+    type_sel.SetSelectedIndex ( 0 )
+  }
+  
+  return container.NewBorder ( top, but_box, nil, nil,
+    container.NewVScroll ( rows_box ) )
+  
+} // end newLaunchersEditor
+
+
+func newLaunchersHelp() fyne.CanvasObject {
+
+  ret:= widget.NewLabel ( _LAUNCHERS_HELP )
+  ret.Wrapping= fyne.TextWrapWord
+
+  return ret
+  
+} // end newLaunchersHelp
+
+
+
+
 /****************/
 /* PART PÚBLICA */
 /****************/
@@ -46,23 +172,85 @@ func NewCommandsManager(
 ) fyne.CanvasObject {
 
   tids:= model.GetFileTypeIDs ()
-  entries:= make([]*widget.Entry,len(tids))
-  form:= widget.NewForm ()
-  for i,tid:= range model.GetFileTypeIDs () {
-    text:= model.GetFileTypeName ( tid )
-    entry:= widget.NewEntry ()
-    entry.Text= model.GetFileTypeCommand ( tids[i] )
-    form.Append ( text, entry )
-    entries[i]= entry
+
+  // Editor de l'àmbit seleccionat
+  editor:= container.NewMax ()
+  plat_ids:= model.GetPlatformIDs ()
+  scopes:= []string{"Tots"}
+  for _,id:= range plat_ids {
+    scopes= append(scopes,model.GetPlatform ( id ).GetName ())
   }
-  form.SubmitText= "Aplica"
-  form.OnSubmit= func() {
-    for i:= 0; i < len(tids); i++ {
-      model.SetFileTypeCommand ( tids[i], entries[i].Text )
+  scope_sel:= widget.NewSelect ( scopes, func(string){} )
+  scope_sel.OnChanged= func(string) {
+    var content fyne.CanvasObject
+    if i:= scope_sel.SelectedIndex (); i <= 0 {
+      content= newLaunchersEditor ( model, tids,
+        model.GetFileTypeLaunchers, model.SetFileTypeLaunchers, "",
+        main_win )
+    } else {
+      plat_id:= plat_ids[i-1]
+      content= newLaunchersEditor ( model, tids,
+        func(tid int) []Launcher {
+          return model.GetPlatformLaunchers ( plat_id, tid )
+        },
+        func(tid int,ls []Launcher) error {
+          return model.SetPlatformLaunchers ( plat_id, tid, ls )
+        },
+        "Si la plataforma no en té s'usen els llançadors del tipus.",
+        main_win )
     }
+    editor.RemoveAll ()
+    editor.Add ( content )
   }
-  form.Refresh ()
+  scope_sel.SetSelectedIndex ( 0 )
+
+  // Crea contingut
+  top:= container.NewVBox (
+    newLaunchersHelp (),
+    container.NewBorder ( nil, nil, widget.NewLabel ( "Plataforma:" ), nil,
+      scope_sel ),
+  )
   
-  return container.NewVScroll ( form )
+  return container.NewBorder ( top, nil, nil, nil, editor )
   
 } // end NewCommandsManager
+
+
+// Llançadors propis de l'entrada per als tipus dels seus fitxers.
+func NewEditEntryLaunchers (
+  
+  e        Entry,
+  model    DataModel,
+  main_win fyne.Window,
+  
+) fyne.CanvasObject {
+
+  // Tipus dels fitxers de l'entrada
+  used:= make(map[int]bool)
+  for _,id:= range e.GetFileIDs () {
+    used[model.GetFile ( id ).GetTypeID ()]= true
+  }
+  var tids []int
+  for _,tid:= range model.GetFileTypeIDs () {
+    if used[tid] { tids= append(tids,tid) }
+  }
+  if len(tids) == 0 {
+    return widget.NewLabel ( "L'entrada no té fitxers" )
+  }
+
+  // Editor
+  id:= e.GetID ()
+  editor:= newLaunchersEditor ( model, tids,
+    func(tid int) []Launcher {
+      return model.GetEntryLaunchers ( id, tid )
+    },
+    func(tid int,ls []Launcher) error {
+      return model.SetEntryLaunchers ( id, tid, ls )
+    },
+    "Si l'entrada no en té s'usen els llançadors de la plataforma o,"+
+      " si tampoc en té, els del tipus.",
+    main_win )
+  
+  return container.NewBorder ( newLaunchersHelp (), nil, nil, nil, editor )
+  
+} // end NewEditEntryLaunchers
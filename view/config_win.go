@@ -39,10 +39,11 @@ import (
 
 func RunConfigWin (
 
-  model    DataModel,
-  list     *List,
-  dv       *DetailsViewer,
-  main_win fyne.Window,
+  model      DataModel,
+  list       *List,
+  dv         *DetailsViewer,
+  status_bar *StatusBar,
+  main_win   fyne.Window,
 
 ) {
 
@@ -64,7 +65,26 @@ func RunConfigWin (
     "Comandaments",
     container.NewPadded ( NewCommandsManager ( model, main_win ) ),
   )
-  tabs:= container.NewAppTabs ( plats_tab, labels_tab, commands_tab )
+  dats_tab:= container.NewTabItem (
+    "DATs",
+    container.NewPadded ( NewDatsManager ( model, dv, main_win ) ),
+  )
+  report_tab:= container.NewTabItem (
+    "Informe DAT",
+    container.NewPadded ( NewDatReportManager ( model, list, status_bar,
+      main_win ) ),
+  )
+  dups_tab:= container.NewTabItem (
+    "Duplicats",
+    container.NewPadded ( NewDuplicatesManager ( model, list, dv, status_bar,
+      main_win ) ),
+  )
+  import_tab:= container.NewTabItem (
+    "Importació",
+    container.NewPadded ( NewImportManager ( model, main_win ) ),
+  )
+  tabs:= container.NewAppTabs ( plats_tab, labels_tab, commands_tab,
+    import_tab, dats_tab, report_tab, dups_tab )
   
   // --> Botonera
   but_close:= widget.NewButtonWithIcon ( "Tanca", theme.CancelIcon (), func(){
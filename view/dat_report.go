@@ -0,0 +1,208 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  dat_report.go - Pestanya amb l'informe dels jocs que falten d'una
+ *                  plataforma respecte als seus DATs.
+ */
+
+package view
+
+import (
+  "fmt"
+  "strings"
+
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/theme"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func showExportDatReport (
+
+  report   DatReport,
+  main_win fyne.Window,
+
+) {
+
+  d:= dialog.NewFileSave ( func(w fyne.URIWriteCloser,err error){
+    if err != nil {
+      dialog.ShowError ( err, main_win )
+      return
+    } else if w == nil {
+      return
+    }
+    defer w.Close ()
+    if strings.ToLower ( w.URI ().Extension () ) == ".csv" {
+      err= report.WriteCSV ( w )
+    } else {
+      err= report.WriteText ( w )
+    }
+    if err != nil {
+      dialog.ShowError ( err, main_win )
+    }
+  }, main_win )
+  d.SetFileName ( "informe.csv" )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.8,csize.Height*0.8} )
+  d.Show ()
+
+} // end showExportDatReport
+
+
+// Crea una entrada buida per a cada joc que falta i que encara no en
+// té.
+func createWantedEntries (
+
+  model       DataModel,
+  report      DatReport,
+  platform_id int,
+
+) (int,error) {
+
+  n:= 0
+  for _,name:= range report.GetMissing () {
+    if report.HasEntry ( name ) { continue }
+    if err:= model.AddEntry ( name, platform_id ); err != nil {
+      return n,err
+    }
+    n++
+  }
+
+  return n,nil
+
+} // end createWantedEntries
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func NewDatReportManager (
+
+  model      DataModel,
+  list_win   *List,
+  status_bar *StatusBar,
+  main_win   fyne.Window,
+
+) fyne.CanvasObject {
+
+  var report DatReport= nil
+  var platform_id int= -1
+  missing:= []string{}
+
+  // Llista jocs que falten
+  info:= widget.NewLabel ( "Selecciona una plataforma" )
+  list:= widget.NewList (
+    func() int {
+      return len(missing)
+    },
+    func() fyne.CanvasObject {
+      return widget.NewLabel ( "Template Game Name" )
+    },
+    func( id widget.ListItemID, w fyne.CanvasObject ) {
+      w.(*widget.Label).SetText ( missing[id] )
+    },
+  )
+
+  // Botons
+  but_wanted:= widget.NewButtonWithIcon ( "Crea entrades desitjades",
+    theme.ContentAddIcon (), func(){} )
+  but_export:= widget.NewButtonWithIcon ( "Exporta",
+    theme.DocumentSaveIcon (), func(){} )
+  but_wanted.Disable ()
+  but_export.Disable ()
+
+  // Actualitza informe
+  update:= func() {
+    var err error
+    report,err= model.GetDatReport ( platform_id )
+    if err != nil {
+      report= nil
+      missing= []string{}
+      info.SetText ( err.Error () )
+      but_wanted.Disable ()
+      but_export.Disable ()
+    } else {
+      missing= report.GetMissing ()
+      info.SetText ( fmt.Sprintf ( "Tenim %d de %d jocs, en falten %d",
+        report.GetNumHave (), report.GetNumGames (), report.GetNumMiss () ) )
+      but_wanted.Enable ()
+      but_export.Enable ()
+    }
+    list.Refresh ()
+  }
+
+  // Selector plataforma
+  pids:= model.GetPlatformIDs ()
+  options:= make([]string,len(pids))
+  plat_text2id:= make(map[string]int)
+  for i,id:= range pids {
+    plat:= model.GetPlatform ( id )
+    text:= fmt.Sprintf ( "%s - %s", plat.GetShortName (), plat.GetName () )
+    options[i]= text
+    plat_text2id[text]= id
+  }
+  plat_sel:= widget.NewSelect ( options, func(text string){
+    platform_id= plat_text2id[text]
+    update ()
+  })
+  plat_sel.PlaceHolder= "Selecciona una plataforma"
+
+  // Accions
+  but_wanted.OnTapped= func() {
+    if report == nil { return }
+    dialog.ShowConfirm ( "Crea entrades desitjades",
+      "Es crearà una entrada buida per a cada joc que falta. Vol continuar?",
+      func(ok bool) {
+        if !ok { return }
+        n,err:= createWantedEntries ( model, report, platform_id )
+        if err != nil {
+          dialog.ShowError ( err, main_win )
+        }
+        if n > 0 {
+          list_win.Update ()
+          status_bar.Update ()
+        }
+        update ()
+      }, main_win )
+  }
+  but_export.OnTapped= func() {
+    if report == nil { return }
+    showExportDatReport ( report, main_win )
+  }
+
+  // Crea contingut
+  top:= container.NewVBox ( plat_sel, info )
+  but_box:= container.NewHBox ( but_wanted, but_export )
+  ret:= container.NewBorder ( top, container.NewPadded ( but_box ),
+    nil, nil, list )
+
+  return ret
+
+} // end NewDatReportManager
@@ -26,6 +26,8 @@ package view
 import (
   "image"
   "image/color"
+  "io"
+  "time"
 )
 
 
@@ -48,6 +50,14 @@ type StringPair interface {
 }
 
 
+// Comandament amb nom per a obrir un tipus de fitxer. Si el nom està
+// buit es fa servir el del programa.
+type Launcher struct {
+  Name    string
+  Command string
+}
+
+
 type File interface {
 
   // Torna la imatge que representa el fitxer, o nil si no en té (o no
@@ -60,17 +70,55 @@ type File interface {
   // Torna l'identificador del tipus
   GetTypeID() int
 
+  // Torna l'estat de verificació respecte als DATs importats i el nom
+  // canònic del fitxer (cadena buida si no apareix en cap DAT).
+  GetDatStatus() (status string,canonical_name string)
+
   // Torna metadades associades a aquest fitxer
   GetMetadata() []StringPair
 
-  // "Executa" el fitxer.
+  // Indica si es pot explorar el contingut del fitxer (ZIP, TAR,
+  // imatges de disc).
+  IsContainer() bool
+
+  // Torna els fitxers que conté. Sols si IsContainer.
+  GetMembers() ([]ArchiveMember,error)
+
+  // Indica si és un disquet FAT12 en el qual es poden escriure
+  // fitxers (vore Entry.AddFilesToFloppy).
+  IsFloppy() bool
+  
+  // Obri per a llegir un dels fitxers que conté.
+  OpenMember(member string) (io.ReadCloser,error)
+
+  // Extrau els fitxers indicats en el directori 'dir' mantenint la
+  // ruta que tenen dins del fitxer.
+  ExtractMembers(members []string,dir string,
+    create_pb func() ProgressBar) error
+  
+  // Torna els noms dels llançadors que es poden usar per a obrir el
+  // fitxer.
+  GetLaunchers() []string
+
+  // Torna el nom de l'últim llançador usat en l'entrada del fitxer
+  // (cadena buida si no n'hi ha).
+  GetLastLauncher() string
+  
+  // "Executa" el fitxer amb l'últim llançador usat en l'entrada o,
+  // si no n'hi ha, amb el primer.
   Run() error
+
+  // "Executa" el fitxer amb el llançador indicat.
+  RunLauncher(name string) error
   
 }
 
 
 type Entry interface {
 
+  // Torna l'identificador de l'entrada
+  GetID() int64
+
   // Torna el nom que es mostrarà en la interfície
   GetName() string
 
@@ -101,12 +149,35 @@ type Entry interface {
   // name -> Nom amb el que volem registrar el fitxer
   // file_type -> Identificador tipus fitxer
   // create_pb -> Funció que crea i mostra una barra de progrés
+  // Torna la descripció dels fitxers idèntics que ja estaven en la
+  // biblioteca (buit si no n'hi ha).
   AddFile(path string,name string,file_type int,
+    create_pb func() ProgressBar) ([]string,error)
+
+  // Afegeix els fitxers 'members' continguts en el fitxer ZIP o TAR
+  // 'path'. El tipus de cada fitxer es detecta automàticament. Si
+  // 'keep' és cert també afegeix el fitxer original. Torna el nombre
+  // de fitxers afegits.
+  AddFilesFromArchive(path string,members []string,keep bool,
+    create_pb func() ProgressBar) (int,error)
+  
+  // Afegeix un disquet FAT12 buit amb el format indicat (vore
+  // DataModel.GetFloppyFormats).
+  CreateFloppy(name string,format string,create_pb func() ProgressBar) error
+
+  // Afegeix, amb el nom 'name', una còpia del disquet 'file_id' amb
+  // els fitxers locals 'paths' escrits en el directori 'dir' del
+  // disquet. El disquet original no es modifica.
+  AddFilesToFloppy(file_id int64,name string,paths []string,dir string,
     create_pb func() ProgressBar) error
   
   // Afegeix una nova etiqueta
   AddLabel(id int) error
 
+  // Torna a calcular els hashes dels fitxers de l'entrada i els
+  // compara amb els registrats
+  CheckIntegrity(create_pb func() ProgressBar) (IntegrityReport,error)
+
   // Elimina el fitxer de l'entrada
   RemoveFile(id int64) error
   
@@ -126,6 +197,20 @@ type Entry interface {
 }
 
 
+type ArchiveMember interface {
+
+  // Nom (amb la ruta dins del fitxer d'emmagatzemament)
+  GetName() string
+
+  // Grandària sense comprimir
+  GetSize() int64
+
+  // Data de modificació (zero si no es coneix)
+  GetDate() time.Time
+  
+}
+
+
 type Stats interface {
 
   // Torna el nombre d'entrades
@@ -175,8 +260,109 @@ type Label interface {
 }
 
 
+type Dat interface {
+
+  // Torna el nom
+  GetName() string
+
+  // Torna la versió
+  GetVersion() string
+
+  // Torna el nombre de jocs
+  GetNumGames() int64
+
+  // Torna el nombre de ROMs
+  GetNumRoms() int64
+
+  // Torna l'identificador de la plataforma associada (-1 si no en té)
+  GetPlatformID() int
+
+  // Associa el DAT a una plataforma (-1 per a cap)
+  SetPlatform(platform_id int) error
+  
+}
+
+
+type DatReport interface {
+
+  // Torna el nombre de jocs del DAT
+  GetNumGames() int
+
+  // Torna el nombre de jocs que tenim
+  GetNumHave() int
+
+  // Torna el nombre de jocs que falten
+  GetNumMiss() int
+
+  // Torna el nom dels jocs que falten
+  GetMissing() []string
+
+  // Indica si ja hi ha una entrada amb el nom del joc
+  HasEntry(name string) bool
+
+  // Escriu l'informe en format CSV
+  WriteCSV(w io.Writer) error
+
+  // Escriu l'informe en format text
+  WriteText(w io.Writer) error
+  
+}
+
+
+type DuplicatesReport interface {
+
+  // Torna el nombre de grups de fitxers repetits
+  GetNumGroups() int
+
+  // Torna el hash que comparteixen els fitxers del grup
+  GetHash(group int) string
+
+  // Torna el nombre de fitxers del grup
+  GetNumFiles(group int) int
+
+  // Torna l'identificador d'un fitxer del grup
+  GetFileID(group int,i int) int64
+
+  // Torna la descripció (nom i entrada) d'un fitxer del grup
+  GetFileText(group int,i int) string
+  
+}
+
+
+type IntegrityReport interface {
+
+  // Torna el nombre de fitxers comprovats
+  GetNumFiles() int
+
+  // Torna el nombre de fitxers que coincideixen amb els hashes
+  // registrats
+  GetNumOK() int
+
+  // Torna la descripció dels fitxers modificats, corruptes o que no
+  // s'han pogut llegir
+  GetFailed() []string
+  
+}
+
+
 type DataModel interface {
 
+  // Torna el nom de la biblioteca oberta (perfil o directori arrel)
+  GetLibraryName() string
+
+  // Torna els noms dels perfils de biblioteca disponibles
+  GetProfiles() []string
+
+  // Torna la manera de desar els fitxers que s'afegeixen (índex de
+  // GetImportModes)
+  GetImportMode() int
+
+  // Torna el nom de les maneres de desar els fitxers que s'afegeixen
+  GetImportModes() []string
+
+  // Canvia la manera de desar els fitxers que s'afegeixen
+  SetImportMode(mode int) error
+  
   // Torna la llista dels identificadors (long) de tots els objectes del
   // model.
   RootEntries() []int64
@@ -202,19 +388,74 @@ type DataModel interface {
   // Obté estadístiques
   GetStats() Stats
 
+  // Torna els identificadors dels DATs importats
+  GetDatIDs() []int64
+
+  // Torna el DAT
+  GetDat(id int64) Dat
+
+  // Crea l'informe dels jocs que tenim i falten en la plataforma
+  // d'acord amb els DATs associats
+  GetDatReport(platform_id int) (DatReport,error)
+  
+  // Torna a calcular els hashes dels fitxers de la plataforma i els
+  // compara amb els registrats
+  CheckPlatformIntegrity(id int,create_pb func() ProgressBar) (
+    IntegrityReport,error)
+  
+  // Agrupa els fitxers repetits de tota la biblioteca
+  GetDuplicates() (DuplicatesReport,error)
+
+  // Elimina una còpia d'un fitxer repetit
+  RemoveDuplicate(file_id int64) error
+
+  // Conserva el fitxer indicat i elimina la resta de còpies. Les
+  // entrades que es queden buides es fusionen amb la del fitxer
+  // conservat.
+  MergeDuplicates(keep_id int64) error
+  
+  // Torna els fitxers que conté un fitxer ZIP o TAR. Falla si no és
+  // un fitxer d'emmagatzemament.
+  GetArchiveMembers(file_name string) ([]ArchiveMember,error)
+  
+  // Torna els formats dels disquets que es poden crear.
+  GetFloppyFormats() []string
+  
+  // Torna els tipus de fitxer que poden correspondre al fitxer
+  // indicat, del més probable al menys.
+  DetectFileType(file_name string) ([]int,error)
+  
   // Obté els identificadors dels tipus de fitxer
   GetFileTypeIDs() []int
 
   // Obté el nom d'un tipus
   GetFileTypeName(id int) string
 
-  // Obté el comandament per un tipus de fitxer. Si no en té,
-  // aleshores cadena buida.
-  GetFileTypeCommand(id int) string
+  // Obté els llançadors d'un tipus de fitxer.
+  GetFileTypeLaunchers(id int) []Launcher
+
+  // Fixa els llançadors d'un tipus de fitxer. Una llista buida els
+  // elimina. Torna error si alguna plantilla no és vàlida.
+  SetFileTypeLaunchers(id int,launchers []Launcher) error
+
+  // Obté els llançadors que substitueixen els del tipus en una
+  // plataforma.
+  GetPlatformLaunchers(platform_id int,type_id int) []Launcher
 
-  // Fixa comandament per a un tipus de fitxer. Cadena buida elimina
-  // el comandament.
-  SetFileTypeCommand(id int,command string)
+  // Fixa els llançadors d'un tipus en una plataforma. Una llista
+  // buida fa que s'usen els del tipus.
+  SetPlatformLaunchers(platform_id int,type_id int,launchers []Launcher) error
+
+  // Obté els llançadors que substitueixen els de la plataforma en
+  // una entrada.
+  GetEntryLaunchers(entry_id int64,type_id int) []Launcher
+
+  // Fixa els llançadors d'un tipus en una entrada. Una llista buida
+  // fa que s'usen els de la plataforma.
+  SetEntryLaunchers(entry_id int64,type_id int,launchers []Launcher) error
+
+  // Comprova la plantilla d'un comandament sense desar-la.
+  CheckFileTypeCommand(command string) error
   
   // Afegeix una nova plataforma
   AddPlatform(short_name string,name string,c color.Color) error
@@ -225,6 +466,12 @@ type DataModel interface {
   // Afegeix una nova entrada
   AddEntry(name string,platform_id int) error
 
+  // Importa un fitxer DAT
+  ImportDat(file_name string) error
+
+  // Elimina un DAT
+  RemoveDat(id int64) error
+  
   // Elimina una plataforma
   RemovePlatform(id int) error
 
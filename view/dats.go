@@ -0,0 +1,231 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  dats.go - Pestanya per a gestionar els fitxers DAT de referència.
+ */
+
+package view
+
+import (
+  "fmt"
+
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/theme"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+func showImportDat (
+
+  model    DataModel,
+  main_win fyne.Window,
+  list     *widget.List,
+  dv       *DetailsViewer,
+
+) {
+
+  d:= dialog.NewFileOpen ( func(r fyne.URIReadCloser,err error){
+    if err != nil {
+      dialog.ShowError ( err, main_win )
+    } else if r != nil {
+      r.Close ()
+      if err:= model.ImportDat ( r.URI ().Path () ); err != nil {
+        dialog.ShowError ( err, main_win )
+      } else {
+        list.Refresh ()
+        dv.Update ()
+      }
+    }
+  }, main_win )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.8,csize.Height*0.8} )
+  d.Show ()
+
+} // end showImportDat
+
+
+func showEditDat (
+
+  d        Dat,
+  model    DataModel,
+  main_win fyne.Window,
+  list     *widget.List,
+
+) {
+
+  // Selector de plataforma
+  const NONE= "Cap"
+  pids:= model.GetPlatformIDs ()
+  options:= make([]string,1,len(pids)+1)
+  options[0]= NONE
+  plat_text2id:= make(map[string]int)
+  plat_text2id[NONE]= -1
+  plat_sel:= widget.NewSelect ( nil, func(string){} )
+  for _,id:= range pids {
+    plat:= model.GetPlatform ( id )
+    text:= fmt.Sprintf ( "%s - %s", plat.GetShortName (), plat.GetName () )
+    options= append(options,text)
+    plat_text2id[text]= id
+    if id == d.GetPlatformID () {
+      plat_sel.Selected= text
+    }
+  }
+  plat_sel.Options= options
+  if plat_sel.Selected == "" {
+    plat_sel.Selected= NONE
+  }
+
+  // Dialeg
+  items:= []*widget.FormItem{
+    widget.NewFormItem ( "Plataforma", plat_sel ),
+  }
+  dialog.ShowForm ( "Edita DAT", "Aplica", "Cancel·la", items,
+    func(b bool){
+      if !b { return }
+      if err:= d.SetPlatform ( plat_text2id[plat_sel.Selected] );
+      err != nil {
+        dialog.ShowError ( err, main_win )
+      } else {
+        list.Refresh ()
+      }
+    }, main_win )
+
+} // end showEditDat
+
+
+func createDatItemTemplate () fyne.CanvasObject {
+
+  // Text
+  name:= widget.NewLabel ( "Template DAT Name" )
+
+  // Botons
+  but_edit:= widget.NewButtonWithIcon ( "", theme.DocumentCreateIcon (),
+    func(){} )
+  but_del:= widget.NewButtonWithIcon ( "", theme.DeleteIcon (), func(){} )
+  but_box:= container.NewHBox ( but_edit, but_del )
+
+  return container.NewBorder ( nil, nil, nil, but_box, name )
+
+} // end createDatItemTemplate
+
+
+func updateDatItem (
+
+  co       fyne.CanvasObject,
+  model    DataModel,
+  dv       *DetailsViewer,
+  id       int,
+  list     *widget.List,
+  main_win fyne.Window,
+
+) {
+
+  // Prepara
+  dats:= model.GetDatIDs ()
+  d:= model.GetDat ( dats[id] )
+  name:= co.(*fyne.Container).Objects[0].(*widget.Label)
+  but_box:= co.(*fyne.Container).Objects[1].(*fyne.Container)
+
+  // Nom
+  text:= fmt.Sprintf ( "%s (%s) - %d jocs, %d ROMs", d.GetName (),
+    d.GetVersion (), d.GetNumGames (), d.GetNumRoms () )
+  if d.GetPlatformID () != -1 {
+    plat:= model.GetPlatform ( d.GetPlatformID () )
+    text= plat.GetShortName ()+": "+text
+  }
+  name.SetText ( text )
+
+  // Edita
+  but_edit:= but_box.Objects[0].(*widget.Button)
+  but_edit.OnTapped= func() {
+    showEditDat ( d, model, main_win, list )
+  }
+
+  // Esborra
+  but_del:= but_box.Objects[1].(*widget.Button)
+  but_del.OnTapped= func() {
+    dialog.ShowConfirm ( "Esborra DAT",
+      "Està segur que vol esborrar el DAT?",
+      func(ok bool) {
+        if ok {
+          if err:= model.RemoveDat ( dats[id] ); err != nil {
+            dialog.ShowError ( err, main_win )
+          } else {
+            list.Refresh ()
+            dv.Update ()
+          }
+        }
+      }, main_win )
+  }
+
+} // end updateDatItem
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func NewDatsManager (
+
+  model    DataModel,
+  dv       *DetailsViewer,
+  main_win fyne.Window,
+
+) fyne.CanvasObject {
+
+  // Llista DATs
+  list:= widget.NewList (
+    func() int {return -1},
+    func() fyne.CanvasObject {return nil},
+    func(id widget.ListItemID,w fyne.CanvasObject){},
+  )
+  list.Length= func() int {
+    return len(model.GetDatIDs ())
+  }
+  list.CreateItem= func() fyne.CanvasObject {
+    return createDatItemTemplate ()
+  }
+  list.UpdateItem= func( id widget.ListItemID, w fyne.CanvasObject ) {
+    updateDatItem ( w, model, dv, id, list, main_win )
+  }
+
+  // Botonera
+  but_new:= widget.NewButtonWithIcon ( "Importa DAT",
+    theme.ContentAddIcon (), func(){
+      showImportDat ( model, main_win, list, dv )
+    })
+  but_box:= container.NewHBox ( but_new )
+  but_box= container.NewPadded ( but_box )
+
+  // Crea contingut
+  ret:= container.NewBorder ( but_box, nil, nil, nil, list )
+
+  return ret
+
+} // end NewDatsManager
@@ -65,6 +65,37 @@ func (self *DetailsViewer) newLabel ( id int ) fyne.CanvasObject {
 } // end newLabel
 
 
+// Mostra sota la barra d'eines el menú per a triar amb quin
+// llançador s'obri el fitxer. L'últim usat en l'entrada apareix
+// marcat.
+func (self *DetailsViewer) showLaunchersMenu(
+
+  f         File,
+  launchers []string,
+  toolbar   *widget.Toolbar,
+  
+) {
+
+  last:= f.GetLastLauncher ()
+  items:= make([]*fyne.MenuItem,len(launchers))
+  for i,name:= range launchers {
+    items[i]= fyne.NewMenuItem ( name, func() {
+      if err:= f.RunLauncher ( name ); err != nil {
+        dialog.ShowError ( err, self.win )
+      }
+    })
+    items[i].Checked= name == last
+  }
+  menu:= widget.NewPopUpMenu ( fyne.NewMenu ( "", items... ),
+    self.win.Canvas () )
+  pos:= fyne.CurrentApp ().Driver ().AbsolutePositionForObject ( toolbar )
+  pos= pos.Add ( fyne.NewPos (
+    toolbar.Size ().Width-menu.MinSize ().Width, toolbar.Size ().Height ) )
+  menu.ShowAtPosition ( pos )
+  
+} // end showLaunchersMenu
+
+
 
 
 /****************/
@@ -228,6 +259,17 @@ func (self *DetailsViewer) ViewFile ( f_id int64 ) {
 `,
       md[i].GetKey (), md[i].GetValue () )
   }
+  if len(self.model.GetDatIDs ()) > 0 {
+    status,dat_name:= f.GetDatStatus ()
+    text_tmp+= fmt.Sprintf ( `
+- **Estat DAT:** %s
+`, status )
+    if dat_name != "" {
+      text_tmp+= fmt.Sprintf ( `
+- **Nom DAT:** %s
+`, dat_name )
+    }
+  }
   text:= widget.NewRichTextFromMarkdown ( text_tmp )
   
   // Crea card
@@ -248,14 +290,28 @@ func (self *DetailsViewer) ViewFile ( f_id int64 ) {
   // Crea toolbar
   // NOTA!! En el futur el RUN el podem ficar sols si el tipus de
   // fitxer es pot executar.
-  toolbar:= widget.NewToolbar (
-    widget.NewToolbarSpacer (),
-    widget.NewToolbarAction ( theme.MediaPlayIcon (), func() {
-      if err:= f.Run (); err != nil {
-        dialog.ShowError ( err, self.win )
-      }
-    }),
-  )
+  items:= []widget.ToolbarItem{widget.NewToolbarSpacer ()}
+  if f.IsContainer () {
+    items= append(items,widget.NewToolbarAction ( theme.FolderOpenIcon (),
+      func() {
+        RunFileBrowserWin ( f, self.win )
+      }))
+  }
+  var toolbar *widget.Toolbar
+  if launchers:= f.GetLaunchers (); len(launchers) > 1 {
+    items= append(items,widget.NewToolbarAction ( theme.MediaPlayIcon (),
+      func() {
+        self.showLaunchersMenu ( f, launchers, toolbar )
+      }))
+  } else {
+    items= append(items,widget.NewToolbarAction ( theme.MediaPlayIcon (),
+      func() {
+        if err:= f.Run (); err != nil {
+          dialog.ShowError ( err, self.win )
+        }
+      }))
+  }
+  toolbar= widget.NewToolbar ( items... )
   
   // Afegeix
   tmp:= container.NewVBox ( container.NewHScroll ( card ), toolbar )
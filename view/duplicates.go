@@ -0,0 +1,172 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  duplicates.go - Pestanya amb els fitxers repetits de la biblioteca.
+ */
+
+package view
+
+import (
+  "fmt"
+
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/theme"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Fila de la llista: la capçalera d'un grup (file == -1) o un fitxer.
+type _DuplicateRow struct {
+  group int
+  file  int
+}
+
+
+func createDuplicateItemTemplate () fyne.CanvasObject {
+
+  // Text
+  text:= widget.NewLabel ( "Template File Name - [ABC] Entry" )
+
+  // Botons
+  but_keep:= widget.NewButtonWithIcon ( "Conserva", theme.ConfirmIcon (),
+    func(){} )
+  but_del:= widget.NewButtonWithIcon ( "", theme.DeleteIcon (), func(){} )
+  but_box:= container.NewHBox ( but_keep, but_del )
+
+  return container.NewBorder ( nil, nil, nil, but_box, text )
+  
+} // end createDuplicateItemTemplate
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func NewDuplicatesManager (
+
+  model      DataModel,
+  list_win   *List,
+  dv         *DetailsViewer,
+  status_bar *StatusBar,
+  main_win   fyne.Window,
+
+) fyne.CanvasObject {
+
+  var report DuplicatesReport= nil
+  rows:= []_DuplicateRow{}
+
+  // Llista
+  info:= widget.NewLabel ( "" )
+  list:= widget.NewList (
+    func() int {
+      return len(rows)
+    },
+    func() fyne.CanvasObject {
+      return createDuplicateItemTemplate ()
+    },
+    func( id widget.ListItemID, w fyne.CanvasObject ) {},
+  )
+
+  // Actualitza informe
+  update:= func() {
+    var err error
+    rows= rows[:0]
+    report,err= model.GetDuplicates ()
+    if err != nil {
+      report= nil
+      info.SetText ( err.Error () )
+    } else {
+      for g:= 0; g < report.GetNumGroups (); g++ {
+        rows= append(rows,_DuplicateRow{g,-1})
+        for i:= 0; i < report.GetNumFiles ( g ); i++ {
+          rows= append(rows,_DuplicateRow{g,i})
+        }
+      }
+      info.SetText ( fmt.Sprintf ( "%d grups de fitxers repetits",
+        report.GetNumGroups () ) )
+    }
+    list.Refresh ()
+  }
+
+  // Després de modificar la biblioteca
+  run:= func( title string, msg string, action func() error ) {
+    dialog.ShowConfirm ( title, msg, func(ok bool) {
+      if !ok { return }
+      if err:= action (); err != nil {
+        dialog.ShowError ( err, main_win )
+      }
+      list_win.Update ()
+      dv.Update ()
+      status_bar.Update ()
+      update ()
+    }, main_win )
+  }
+
+  // Files
+  list.UpdateItem= func( id widget.ListItemID, w fyne.CanvasObject ) {
+    row:= rows[id]
+    text:= w.(*fyne.Container).Objects[0].(*widget.Label)
+    but_box:= w.(*fyne.Container).Objects[1].(*fyne.Container)
+    if row.file == -1 {
+      text.TextStyle= fyne.TextStyle{Bold : true}
+      text.SetText ( "sha1: "+report.GetHash ( row.group ) )
+      but_box.Hide ()
+      return
+    }
+    file_id:= report.GetFileID ( row.group, row.file )
+    text.TextStyle= fyne.TextStyle{}
+    text.SetText ( report.GetFileText ( row.group, row.file ) )
+    but_box.Show ()
+    but_keep:= but_box.Objects[0].(*widget.Button)
+    but_keep.OnTapped= func() {
+      run ( "Conserva fitxer",
+        "S'eliminaran la resta de còpies i les entrades que es queden"+
+          " buides es fusionaran amb la d'aquest fitxer. Vol continuar?",
+        func() error { return model.MergeDuplicates ( file_id ) } )
+    }
+    but_del:= but_box.Objects[1].(*widget.Button)
+    but_del.OnTapped= func() {
+      run ( "Elimina fitxer", "Està segur que vol eliminar aquesta còpia?",
+        func() error { return model.RemoveDuplicate ( file_id ) } )
+    }
+  }
+
+  // Botons
+  but_update:= widget.NewButtonWithIcon ( "Actualitza",
+    theme.ViewRefreshIcon (), update )
+
+  // Crea contingut
+  update ()
+  top:= container.NewBorder ( nil, nil, nil, but_update, info )
+  ret:= container.NewBorder ( container.NewPadded ( top ), nil,
+    nil, nil, list )
+
+  return ret
+
+} // end NewDuplicatesManager
@@ -72,7 +72,12 @@ func RunEditEntryWin (
     container.NewPadded ( NewEditEntryCover (
       e, model, dv, main_win ) ),
   )
-  tabs:= container.NewAppTabs ( name_tab, labels_tab, files_tab, cover_tab )
+  launchers_tab:= container.NewTabItem (
+    "Comandaments",
+    container.NewPadded ( NewEditEntryLaunchers ( e, model, main_win ) ),
+  )
+  tabs:= container.NewAppTabs ( name_tab, labels_tab, files_tab, cover_tab,
+    launchers_tab )
   
   // --> Botonera
   but_close:= widget.NewButtonWithIcon ( "Tanca", theme.CancelIcon (), func(){
@@ -24,6 +24,8 @@ package view
 
 import (
   "fmt"
+  "path/filepath"
+  "strings"
   
   "fyne.io/fyne/v2"
   "fyne.io/fyne/v2/container"
@@ -133,6 +135,176 @@ func (self *_AddFileProgressBar) Set( msg string, f float32 ) {
 } // end Set
 
 
+func showNewFloppy(
+
+  e         Entry,
+  model     DataModel,
+  main_win  fyne.Window,
+  list      *widget.List,
+  list_win  *List,
+  dv        *DetailsViewer,
+  statusbar *StatusBar,
+) {
+
+  // Nom
+  name:= widget.NewEntry ()
+  name.Text= "disquet.img"
+  name.Validator= validation.NewRegexp ( `^.+$`,
+    "el nom ha de contindre almenys un caràcter" )
+
+  // Format
+  formats:= model.GetFloppyFormats ()
+  format:= widget.NewSelect ( formats, func(string){} )
+  format.SetSelectedIndex ( len(formats)-1 )
+
+  // Dialeg
+  items:= []*widget.FormItem{
+    widget.NewFormItem ( "Nom", name ),
+    widget.NewFormItem ( "Format", format ),
+  }
+  d:= dialog.NewForm ( "Disquet nou", "Crea", "Cancel·la", items,
+    func(b bool){
+      if !b { return }
+      if err:= e.CreateFloppy ( name.Text, format.Selected,
+        func() ProgressBar{
+          return newAddFileProgressBar ( main_win )
+        }); err != nil {
+        dialog.ShowError ( err, main_win )
+      } else {
+        list.Refresh ()
+        list_win.Refresh ()
+        dv.Update ()
+        statusbar.Update ()
+      }
+    }, main_win )
+  win_size:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{win_size.Width*0.4,win_size.Height*0.4} )
+  d.Show ()
+  
+} // end showNewFloppy
+
+
+// Escriu un fitxer local en una còpia del disquet.
+func showAddFileToFloppy(
+
+  e         Entry,
+  f         File,
+  file_id   int64,
+  main_win  fyne.Window,
+  list      *widget.List,
+  list_win  *List,
+  dv        *DetailsViewer,
+  statusbar *StatusBar,
+) {
+
+  d:= dialog.NewFileOpen ( func(r fyne.URIReadCloser,err error){
+    if err != nil {
+      dialog.ShowError ( err, main_win )
+      return
+    } else if r == nil {
+      return
+    }
+    r.Close ()
+    path:= r.URI ().Path ()
+
+    // Directori dins del disquet
+    dir:= widget.NewEntry ()
+    dir.SetPlaceHolder ( "Arrel" )
+
+    // Nom del nou disquet
+    name:= widget.NewEntry ()
+    ext:= filepath.Ext ( f.GetName () )
+    name.Text= strings.TrimSuffix ( f.GetName (), ext )+"_mod"+ext
+    name.Validator= validation.NewRegexp ( `^.+$`,
+      "el nom ha de contindre almenys un caràcter" )
+
+    // Dialeg
+    items:= []*widget.FormItem{
+      widget.NewFormItem ( "Fitxer", widget.NewLabel ( r.URI ().Name () ) ),
+      widget.NewFormItem ( "Directori", dir ),
+      widget.NewFormItem ( "Nom del nou disquet", name ),
+    }
+    d2:= dialog.NewForm ( "Escriu en el disquet", "Escriu", "Cancel·la", items,
+      func(b bool){
+        if !b { return }
+        if err:= e.AddFilesToFloppy ( file_id, name.Text, []string{path},
+          dir.Text, func() ProgressBar{
+            return newAddFileProgressBar ( main_win )
+          }); err != nil {
+          dialog.ShowError ( err, main_win )
+        } else {
+          list.Refresh ()
+          list_win.Refresh ()
+          dv.Update ()
+          statusbar.Update ()
+        }
+      }, main_win )
+    win_size:= main_win.Content ().Size ()
+    d2.Resize ( fyne.Size{win_size.Width*0.4,win_size.Height*0.4} )
+    d2.Show ()
+    
+  }, main_win )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.8,csize.Height*0.8} )
+  d.Show ()
+  
+} // end showAddFileToFloppy
+
+
+// Permet triar quins fitxers d'un fitxer ZIP o TAR s'afegeixen.
+func showAddArchiveFiles(
+
+  e         Entry,
+  path      string,
+  members   []ArchiveMember,
+  main_win  fyne.Window,
+  list      *widget.List,
+  list_win  *List,
+  dv        *DetailsViewer,
+  statusbar *StatusBar,
+) {
+
+  // Fitxers
+  options:= make([]string,len(members))
+  option2name:= make(map[string]string)
+  for i,m:= range members {
+    options[i]= fmt.Sprintf ( "%s (%d B)", m.GetName (), m.GetSize () )
+    option2name[options[i]]= m.GetName ()
+  }
+  checks:= widget.NewCheckGroup ( options, func([]string){} )
+  keep:= widget.NewCheck ( "Afegeix també el fitxer original", func(bool){} )
+
+  // Dialeg
+  content:= container.NewBorder ( nil, keep, nil, nil,
+    container.NewVScroll ( checks ) )
+  d:= dialog.NewCustomConfirm ( "Afegeix fitxers continguts", "Afegeix",
+    "Cancel·la", content, func(b bool){
+      if !b { return }
+      sel:= make([]string,len(checks.Selected))
+      for i,opt:= range checks.Selected {
+        sel[i]= option2name[opt]
+      }
+      n,err:= e.AddFilesFromArchive ( path, sel, keep.Checked,
+        func() ProgressBar{
+          return newAddFileProgressBar ( main_win )
+        })
+      if err != nil {
+        dialog.ShowError ( err, main_win )
+      }
+      if n > 0 {
+        list.Refresh ()
+        list_win.Refresh ()
+        dv.Update ()
+        statusbar.Update ()
+      }
+    }, main_win )
+  win_size:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{win_size.Width*0.6,win_size.Height*0.6} )
+  d.Show ()
+  
+} // end showAddArchiveFiles
+
+
 func showAddFileEntry(
   
   e         Entry,
@@ -152,6 +324,14 @@ func showAddFileEntry(
       
       // Obté nom i path
       uri:= r.URI ()
+
+      // Els fitxers ZIP i TAR permeten triar quins fitxers s'afegeixen
+      if members,err:= model.GetArchiveMembers ( uri.Path () );
+      err == nil && len(members) > 0 {
+        showAddArchiveFiles ( e, uri.Path (), members, main_win, list,
+          list_win, dv, statusbar )
+        return
+      }
       
       // Nom
       name:= widget.NewEntry ()
@@ -170,6 +350,10 @@ func showAddFileEntry(
       }
       type_sel:= widget.NewSelect ( options, func(string){} )
       type_sel.SetSelectedIndex ( 0 )
+      if cands,err:= model.DetectFileType ( uri.Path () );
+      err == nil && len(cands) > 0 {
+        type_sel.SetSelected ( model.GetFileTypeName ( cands[0] ) )
+      }
 
       // Dialeg
       items:= []*widget.FormItem{
@@ -179,16 +363,22 @@ func showAddFileEntry(
       d2:= dialog.NewForm ( "Afegeix fitxer", "Afegeix", "Cancel·la", items,
         func(b bool){
           if !b { return }
-          if err:= e.AddFile ( uri.Path (), name.Text,
+          dups,err:= e.AddFile ( uri.Path (), name.Text,
             type_text2id[type_sel.Selected], func() ProgressBar{
               return newAddFileProgressBar ( main_win )
-          }); err != nil {
+            })
+          if err != nil {
             dialog.ShowError ( err, main_win )
           } else {
             list.Refresh ()
             list_win.Refresh ()
             dv.Update ()
             statusbar.Update ()
+            if len(dups) > 0 {
+              dialog.ShowInformation ( "Fitxer duplicat",
+                "El fitxer ja estava en la biblioteca:\n"+
+                  strings.Join ( dups, "\n" ), main_win )
+            }
           }
         }, main_win )
       win_size:= main_win.Content ().Size ()
@@ -210,6 +400,10 @@ func createFileEntryItemTemplate () fyne.CanvasObject {
   name:= widget.NewLabel ( "Template Label Name" )
   
   // Botons
+  but_floppy:= widget.NewButtonWithIcon ( "", theme.UploadIcon (),
+    func(){
+      fmt.Println ( "Escriu en el disquet!" )
+    })
   but_edit:= widget.NewButtonWithIcon ( "", theme.DocumentCreateIcon (),
     func(){
       fmt.Println ( "Edita!" )
@@ -218,7 +412,7 @@ func createFileEntryItemTemplate () fyne.CanvasObject {
     func(){
       fmt.Println ( "Esborra!" )
     })
-  but_box:= container.NewHBox ( but_edit, but_del )
+  but_box:= container.NewHBox ( but_floppy, but_edit, but_del )
   
   return container.NewBorder ( nil, nil, nil, but_box, name )
   
@@ -249,7 +443,7 @@ func updateFileEntryItem (
   label.SetText ( f.GetName () )
   
   // Esborra
-  but_del:= but_box.Objects[1].(*widget.Button)
+  but_del:= but_box.Objects[2].(*widget.Button)
   but_del.OnTapped= func() {
     dialog.ShowConfirm ( "Eliminar fitxer",
       "Està segur que vol eliminar aquest fitxer?",
@@ -268,10 +462,22 @@ func updateFileEntryItem (
   }
 
   // Edita
-  but_edit:= but_box.Objects[0].(*widget.Button)
+  but_edit:= but_box.Objects[1].(*widget.Button)
   but_edit.OnTapped= func() {
     showEditFile ( e, f, files[id], main_win, list_win, list )
   }
+
+  // Escriu en el disquet
+  but_floppy:= but_box.Objects[0].(*widget.Button)
+  if f.IsFloppy () {
+    but_floppy.Show ()
+    but_floppy.OnTapped= func() {
+      showAddFileToFloppy ( e, f, files[id], main_win, list, list_win,
+        dv, statusbar )
+    }
+  } else {
+    but_floppy.Hide ()
+  }
   
 } // end updateFileEntryItem
 
@@ -315,7 +521,15 @@ func NewEditEntryFiles (
     theme.ContentAddIcon (), func(){
       showAddFileEntry ( e, model, main_win, list, list_win, dv, statusbar )
     })
-  but_box:= container.NewHBox ( but_new )
+  but_floppy:= widget.NewButtonWithIcon ( "Disquet Nou",
+    theme.ContentAddIcon (), func(){
+      showNewFloppy ( e, model, main_win, list, list_win, dv, statusbar )
+    })
+  but_check:= widget.NewButtonWithIcon ( "Verifica Fitxers",
+    theme.ConfirmIcon (), func(){
+      runCheckIntegrity ( e.CheckIntegrity, main_win )
+    })
+  but_box:= container.NewHBox ( but_new, but_floppy, but_check )
   but_box= container.NewPadded ( but_box )
   
   // Crea contingut
@@ -0,0 +1,301 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  file_browser.go - Explorador del contingut dels fitxers que
+ *                    contenen altres fitxers (ZIP, TAR, imatges de
+ *                    disc).
+ */
+
+package view
+
+import (
+  "bytes"
+  "fmt"
+  "image"
+  _ "image/jpeg"
+  _ "image/png"
+  "io"
+  "path"
+  "sort"
+  "strings"
+  "unicode/utf8"
+  
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/canvas"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/theme"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Màxim de bytes que es llegeixen per a previsualitzar un fitxer.
+const _FILE_BROWSER_PREVIEW_MAX = 4*1024*1024
+
+
+// Arbre construït a partir dels noms dels fitxers. Els directoris
+// s'identifiquen per la seua ruta acabada en '/' i l'arrel és "".
+type _FileBrowserTree struct {
+  children map[string][]string
+  members  map[string]ArchiveMember
+}
+
+
+func newFileBrowserTree( members []ArchiveMember ) *_FileBrowserTree {
+
+  ret:= _FileBrowserTree{
+    children : make(map[string][]string),
+    members  : make(map[string]ArchiveMember),
+  }
+  seen:= make(map[string]bool)
+  add:= func(parent,id string) {
+    if !seen[id] {
+      seen[id]= true
+      ret.children[parent]= append(ret.children[parent],id)
+    }
+  }
+  for _,m:= range members {
+    parent:= ""
+    toks:= strings.Split ( m.GetName (), "/" )
+    for _,tok:= range toks[:len(toks)-1] {
+      if tok == "" { continue }
+      dir:= parent+tok+"/"
+      add ( parent, dir )
+      parent= dir
+    }
+    // Un fitxer acabat en '/' (p.e. en un TAR fet a mà) es confondria
+    // amb el directori i penjaria d'ell mateix.
+    if m.GetName () == "" || strings.HasSuffix ( m.GetName (), "/" ) {
+      continue
+    }
+    ret.members[m.GetName ()]= m
+    add ( parent, m.GetName () )
+  }
+
+  // Directoris primer i per nom
+  for _,v:= range ret.children {
+    sort.Slice ( v, func(i,j int) bool {
+      di,dj:= strings.HasSuffix ( v[i], "/" ),strings.HasSuffix ( v[j], "/" )
+      if di != dj { return di }
+      return strings.ToLower ( v[i] ) < strings.ToLower ( v[j] )
+    })
+  }
+  
+  return &ret
+  
+} // end newFileBrowserTree
+
+
+func (self *_FileBrowserTree) isDir( id string ) bool {
+  return id == "" || strings.HasSuffix ( id, "/" )
+} // end isDir
+
+
+func (self *_FileBrowserTree) baseName( id string ) string {
+  return path.Base ( strings.TrimSuffix ( id, "/" ) )
+} // end baseName
+
+
+// Fitxers que penjen de 'id' (ell mateix si és un fitxer).
+func (self *_FileBrowserTree) getFiles( id string ) []string {
+
+  if !self.isDir ( id ) { return []string{id} }
+  ret:= make([]string,0)
+  for _,c:= range self.children[id] {
+    ret= append(ret,self.getFiles ( c )...)
+  }
+
+  return ret
+  
+} // end getFiles
+
+
+func (self *_FileBrowserTree) getInfo( id string ) string {
+
+  if self.isDir ( id ) {
+    return fmt.Sprintf ( "%d fitxers", len(self.getFiles ( id )) )
+  }
+  m:= self.members[id]
+  ret:= fmt.Sprintf ( "%d B", m.GetSize () )
+  if date:= m.GetDate (); !date.IsZero () {
+    ret+= "   "+date.Format ( "2006-01-02 15:04" )
+  }
+
+  return ret
+  
+} // end getInfo
+
+
+// Torna un widget amb el contingut del fitxer si és una imatge o
+// text.
+func newFileBrowserPreview( f File, member string ) fyne.CanvasObject {
+
+  // Llig
+  r,err:= f.OpenMember ( member )
+  if err != nil {
+    return widget.NewLabel ( err.Error () )
+  }
+  defer r.Close ()
+  data,err:= io.ReadAll ( io.LimitReader ( r, _FILE_BROWSER_PREVIEW_MAX ) )
+  if err != nil {
+    return widget.NewLabel ( err.Error () )
+  }
+
+  // Imatge
+  if img,_,err:= image.Decode ( bytes.NewReader ( data ) ); err == nil {
+    img_w:= canvas.NewImageFromImage ( img )
+    img_w.FillMode= canvas.ImageFillContain
+    return img_w
+  }
+
+  // Text
+  if utf8.Valid ( data ) && bytes.IndexByte ( data, 0 ) == -1 {
+    text:= widget.NewLabel ( string(data) )
+    text.TextStyle= fyne.TextStyle{Monospace:true}
+    return container.NewScroll ( text )
+  }
+  
+  return widget.NewLabel ( "No es pot previsualitzar aquest fitxer" )
+  
+} // end newFileBrowserPreview
+
+
+func showExtractMembers(
+
+  f        File,
+  members  []string,
+  main_win fyne.Window,
+  
+) {
+
+  d:= dialog.NewFolderOpen ( func(uri fyne.ListableURI,err error){
+    if err != nil {
+      dialog.ShowError ( err, main_win )
+      return
+    } else if uri == nil {
+      return
+    }
+    if err:= f.ExtractMembers ( members, uri.Path (), func() ProgressBar{
+      return newAddFileProgressBar ( main_win )
+    }); err != nil {
+      dialog.ShowError ( err, main_win )
+    } else {
+      dialog.ShowInformation ( "Extrau", fmt.Sprintf (
+        "S'han extret %d fitxers en '%s'", len(members), uri.Path () ),
+        main_win )
+    }
+  }, main_win )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.8,csize.Height*0.8} )
+  d.Show ()
+  
+} // end showExtractMembers
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func RunFileBrowserWin (
+
+  f        File,
+  main_win fyne.Window,
+
+) {
+
+  // Llig contingut
+  members,err:= f.GetMembers ()
+  if err != nil {
+    dialog.ShowError ( err, main_win )
+    return
+  }
+  tree:= newFileBrowserTree ( members )
+  
+  // Crea PopUP amb una caixa buida
+  pop_box:= container.NewMax ()
+  pop:= widget.NewModalPopUp ( pop_box, main_win.Canvas () )
+
+  // Previsualització
+  preview:= container.NewMax ()
+  
+  // Arbre
+  selected:= ""
+  but_extract:= widget.NewButtonWithIcon ( "Extrau", theme.DownloadIcon (),
+    func(){
+      showExtractMembers ( f, tree.getFiles ( selected ), main_win )
+    })
+  tree_w:= widget.NewTree (
+    func(id widget.TreeNodeID) []widget.TreeNodeID {
+      return tree.children[id]
+    },
+    func(id widget.TreeNodeID) bool {
+      return tree.isDir ( id )
+    },
+    func(branch bool) fyne.CanvasObject {
+      return container.NewBorder ( nil, nil, nil,
+        widget.NewLabel ( "Template Info" ), widget.NewLabel ( "Template" ) )
+    },
+    func(id widget.TreeNodeID, branch bool, co fyne.CanvasObject) {
+      c:= co.(*fyne.Container)
+      c.Objects[0].(*widget.Label).SetText ( tree.baseName ( id ) )
+      c.Objects[1].(*widget.Label).SetText ( tree.getInfo ( id ) )
+    },
+  )
+  tree_w.OnSelected= func(id widget.TreeNodeID) {
+    selected= id
+    but_extract.SetText ( fmt.Sprintf ( "Extrau (%d)",
+      len(tree.getFiles ( id )) ) )
+    preview.RemoveAll ()
+    if !tree.isDir ( id ) {
+      preview.Add ( newFileBrowserPreview ( f, id ) )
+    }
+    preview.Refresh ()
+  }
+  tree_w.OnUnselected= func(id widget.TreeNodeID) {
+    selected= ""
+    but_extract.SetText ( "Extrau" )
+  }
+
+  // Botonera
+  but_close:= widget.NewButtonWithIcon ( "Tanca", theme.CancelIcon (), func(){
+    pop.Hide ()
+  })
+  but_box:= container.NewBorder ( widget.NewSeparator (), nil, nil,
+    container.NewHBox ( but_extract, but_close ),
+    widget.NewLabel ( fmt.Sprintf ( "%s: %d fitxers", f.GetName (),
+      len(members) ) ) )
+
+  // Mostra
+  split:= container.NewHSplit ( tree_w, preview )
+  split.Offset= 0.6
+  content:= container.NewBorder ( nil, but_box, nil, nil, split )
+  pop_box.Add ( content )
+  csize:= main_win.Content ().Size ()
+  pop.Resize ( fyne.Size{csize.Width*0.8,csize.Height*0.8} )
+  pop.Show ()
+  
+} // end RunFileBrowserWin
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2023 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  import.go - Pestanya per a triar com es desen els fitxers que
+ *              s'afegeixen.
+ */
+
+package view
+
+import (
+  
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func NewImportManager(
+  
+  model    DataModel,
+  main_win fyne.Window,
+  
+) fyne.CanvasObject {
+
+  modes:= model.GetImportModes ()
+  radio:= widget.NewRadioGroup ( modes, nil )
+  radio.Selected= modes[model.GetImportMode ()]
+  radio.Required= true
+  radio.OnChanged= func(sel string) {
+    for mode,text:= range modes {
+      if text == sel {
+        if err:= model.SetImportMode ( mode ); err != nil {
+          dialog.ShowError ( err, main_win )
+        }
+        return
+      }
+    }
+  }
+  help:= widget.NewLabel ( "Si no es pot fer un enllaç dur (p.e. el fitxer"+
+    " està en altra partició) o un reflink, el fitxer es copia i\n"+
+    "es comprova la còpia. Amb \"Mou\" s'elimina el fitxer original." )
+  
+  return container.NewVScroll ( container.NewVBox ( radio, help ) )
+  
+} // end NewImportManager
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  integrity.go - Comprovació de la integritat dels fitxers d'una
+ *                 entrada o d'una plataforma.
+ */
+
+package view
+
+import (
+  "fmt"
+  "strings"
+
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/container"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Executa la comprovació mostrant una barra de progrés i després el
+// resultat.
+func runCheckIntegrity (
+
+  check    func(create_pb func() ProgressBar) (IntegrityReport,error),
+  main_win fyne.Window,
+
+) {
+
+  // Comprova
+  report,err:= check ( func() ProgressBar {
+    return newAddFileProgressBar ( main_win )
+  })
+  if err != nil {
+    dialog.ShowError ( err, main_win )
+    return
+  }
+
+  // Mostra resultat
+  failed:= report.GetFailed ()
+  if len(failed) == 0 {
+    dialog.ShowInformation ( "Verificació",
+      fmt.Sprintf ( "S'han comprovat %d fitxers i tots són correctes",
+        report.GetNumFiles () ), main_win )
+    return
+  }
+  text:= widget.NewLabel ( strings.Join ( failed, "\n" ) )
+  content:= container.NewBorder (
+    widget.NewLabel ( fmt.Sprintf ( "S'han comprovat %d fitxers, %d amb"+
+      " problemes:", report.GetNumFiles (), len(failed) ) ),
+    nil, nil, nil, container.NewVScroll ( text ) )
+  d:= dialog.NewCustom ( "Verificació", "Tanca", content, main_win )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.6,csize.Height*0.6} )
+  d.Show ()
+
+} // end runCheckIntegrity
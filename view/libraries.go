@@ -0,0 +1,87 @@
+/*
+ * Copyright 2025 Adrià Giménez Pastor.
+ *
+ * This file is part of adriagipas/imgteka.
+ *
+ * adriagipas/imgteka is free software: you can redistribute it and/or
+ * modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * adriagipas/imgteka is distributed in the hope that it will be
+ * useful, but WITHOUT ANY WARRANTY; without even the implied warranty
+ * of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with adriagipas/imgteka.  If not, see <https://www.gnu.org/licenses/>.
+ */
+/*
+ *  libraries.go - Diàleg per a obrir altra biblioteca (perfil).
+ */
+
+package view
+
+import (
+  "os"
+  "os/exec"
+
+  "fyne.io/fyne/v2"
+  "fyne.io/fyne/v2/data/validation"
+  "fyne.io/fyne/v2/dialog"
+  "fyne.io/fyne/v2/widget"
+)
+
+
+
+
+/****************/
+/* PART PRIVADA */
+/****************/
+
+// Obri el perfil en un procés nou. Si el perfil ja està obert, el
+// procés nou sols mostra la finestra de l'existent.
+func openProfile( name string ) error {
+
+  exe,err:= os.Executable ()
+  if err != nil { return err }
+  cmd:= exec.Command ( exe, "-profile", name )
+  if err:= cmd.Start (); err != nil { return err }
+  go cmd.Wait ()
+
+  return nil
+  
+} // end openProfile
+
+
+
+
+/****************/
+/* PART PÚBLICA */
+/****************/
+
+func ShowLibrariesDialog( model DataModel, main_win fyne.Window ) {
+
+  // Perfil
+  profile:= widget.NewSelectEntry ( model.GetProfiles () )
+  profile.Validator= validation.NewRegexp ( `^[A-Za-z0-9][A-Za-z0-9_.-]*$`,
+    "el nom sols pot contindre lletres, números, '_', '.' i '-'" )
+  
+  // Dialeg
+  current:= widget.NewLabel ( model.GetLibraryName () )
+  items:= []*widget.FormItem{
+    widget.NewFormItem ( "Actual", current ),
+    widget.NewFormItem ( "Perfil", profile ),
+  }
+  d:= dialog.NewForm ( "Obri biblioteca", "Obri", "Cancel·la", items,
+    func(b bool){
+      if !b { return }
+      if err:= openProfile ( profile.Text ); err != nil {
+        dialog.ShowError ( err, main_win )
+      }
+    }, main_win )
+  csize:= main_win.Content ().Size ()
+  d.Resize ( fyne.Size{csize.Width*0.4,d.MinSize ().Height} )
+  d.Show ()
+  
+} // end ShowLibrariesDialog
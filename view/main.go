@@ -40,7 +40,11 @@ func Run ( model DataModel ) error {
     fmt.Println ( "Adéu!!!!" )
   })
   a.Settings ().SetTheme ( &ImgtekaTheme{} ) 
-  win:= a.NewWindow ( "imgteka" )
+  title:= "imgteka"
+  if name:= model.GetLibraryName (); name != "default" { // Per defecte
+    title+= " - "+name
+  }
+  win:= a.NewWindow ( title )
   win.SetIcon ( resourceIcon256x256Png )
   win.CenterOnScreen ()
 
@@ -163,6 +163,10 @@ func createPlatformItemTemplate () fyne.CanvasObject {
   box:= container.NewHBox ( label, name )
 
   // Botons
+  but_check:= widget.NewButtonWithIcon ( "", theme.ConfirmIcon (),
+    func(){
+      fmt.Println ( "Verifica!" )
+    })
   but_edit:= widget.NewButtonWithIcon ( "", theme.DocumentCreateIcon (),
     func(){
       fmt.Println ( "Edita!" )
@@ -171,7 +175,7 @@ func createPlatformItemTemplate () fyne.CanvasObject {
     func(){
       fmt.Println ( "Esborra!" )
     })
-  but_box:= container.NewHBox ( but_edit, but_del )
+  but_box:= container.NewHBox ( but_check, but_edit, but_del )
 
   return container.NewBorder ( nil, nil, nil, but_box, box )
   
@@ -205,7 +209,7 @@ func updatePlatformItem (
   box.Objects[1].(*widget.Label).SetText ( text )
 
   // Esborra
-  but_del:= but_box.Objects[1].(*widget.Button)
+  but_del:= but_box.Objects[2].(*widget.Button)
   if plat.GetNumEntries () > 0 {
     but_del.Disable ()
     but_del.OnTapped= func() {}
@@ -227,10 +231,19 @@ func updatePlatformItem (
   }
   
   // Edita
-  but_edit:= but_box.Objects[0].(*widget.Button)
+  but_edit:= but_box.Objects[1].(*widget.Button)
   but_edit.OnTapped= func() {
     showEditPlatform ( plat, main_win, list_win, dv, list )
   }
+
+  // Verifica
+  but_check:= but_box.Objects[0].(*widget.Button)
+  but_check.OnTapped= func() {
+    runCheckIntegrity ( func(create_pb func() ProgressBar) (
+      IntegrityReport,error) {
+      return model.CheckPlatformIntegrity ( plats[id], create_pb )
+    }, main_win )
+  }
   
 } // end updatePlatformItem
 
@@ -78,11 +78,18 @@ func NewToolbar (
   // Botó configuració
   conf_but:= widget.NewButtonWithIcon ( "", theme.SettingsIcon (),
     func(){
-      RunConfigWin ( model, list, dv, main_win )
+      RunConfigWin ( model, list, dv, status_bar, main_win )
+    })
+  
+  // Botó biblioteques
+  lib_but:= widget.NewButtonWithIcon ( "", theme.StorageIcon (),
+    func(){
+      ShowLibrariesDialog ( model, main_win )
     })
   
   // Afegeix
-  box:= container.NewBorder ( nil, nil, add_but, conf_but, search_bar )
+  box:= container.NewBorder ( nil, nil, add_but,
+    container.NewHBox ( lib_but, conf_but ), search_bar )
   ret.root.Add ( box )
   ret.root.Add ( widget.NewSeparator () )
   